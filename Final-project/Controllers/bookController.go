@@ -5,50 +5,50 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
-	inmemoryStores "finalProject/InmemoryStores"
+	interfaces "finalProject/Interfaces"
 	"finalProject/StructureData"
-	postgresStores "finalProject/postgresStores"
+	"finalProject/utils"
 )
 
-func InitializeBookFile() {
-    pgStore := postgresStores.GetPostgresBookStoreInstance()
-    memStore := inmemoryStores.GetBookStoreInstance()
-
-    pgBooks := pgStore.GetAllBooks()
-    
-    if len(memStore.GetAllBooks()) == 0 {
-        for _, book := range pgBooks {
-            _, err := memStore.CreateBook(book)
-            if err != nil {
-                log.Printf("Error loading book %d into memory: %v", book.ID, err.Message)
-            }
-        }
-        log.Printf("Loaded %d books from PostgreSQL into memory", len(pgBooks))
-    }
+// BookHandler serves the /books routes. Books referenced by orders cannot be
+// changed or deleted.
+type BookHandler struct {
+	Books   interfaces.BookStore
+	Authors interfaces.AuthorStore
+	Orders  interfaces.OrderStore
+	Log     *log.Logger
 }
 
-func GetAllBooks(w http.ResponseWriter, r *http.Request) {
-	store := postgresStores.GetPostgresBookStoreInstance() // use Postgres store
-	books := store.GetAllBooks()
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(books)
+func (h *BookHandler) GetAllBooks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	store := h.Books
+	opts, ok := listOptions(w, r, StructureData.BookSortFields)
+	if !ok {
+		return
+	}
+	books, meta, errResp := store.ListBooks(ctx, opts)
+	if errResp != nil {
+		utils.WriteError(w, r, errResp)
+		return
+	}
+	writeList(w, r, books, meta, opts.Fields)
 }
 
-func GetBookByID(w http.ResponseWriter, r *http.Request) {
-	store := postgresStores.GetPostgresBookStoreInstance() // use Postgres store
+func (h *BookHandler) GetBookByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	store := h.Books
 	idStr := r.URL.Path[len("/books/"):]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid book ID"})
+		utils.WriteError(w, r, StructureData.FieldValidationError("id", "Invalid book ID"))
 		return
 	}
-	book, errResp := store.GetBook(id)
+	book, errResp := store.GetBook(ctx, id)
 	if errResp != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResp)
+		utils.WriteError(w, r, errResp)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -66,35 +66,34 @@ type BookInput struct {
 	// You can omit review_stats since those are computed later.
 }
 
-func CreateBook(w http.ResponseWriter, r *http.Request) {
-	bookStore := inmemoryStores.GetBookStoreInstance()
-	pgBookStore := postgresStores.GetPostgresBookStoreInstance()
-	pgAuthorStore := postgresStores.GetPostgresAuthorStoreInstance()
+func (h *BookHandler) CreateBook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bookStore := h.Books
+	authorStore := h.Authors
 
 	var input BookInput
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid input"})
+		utils.WriteError(w, r, StructureData.ValidationError("Invalid input"))
 		return
 	}
 
 	// Validate stock.
 	if input.Stock < 1 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Stock must be ≥1"})
+		utils.WriteError(w, r, StructureData.FieldValidationError("stock", "Stock must be ≥1"))
 		return
 	}
 
-	// Look up the author in PostgreSQL using the provided author_id.
+	// Look up the author using the provided author_id.
 	if input.AuthorID == 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Author ID is required"})
+		utils.WriteError(w, r, StructureData.FieldValidationError("author_id", "Author ID is required"))
 		return
 	}
-	author, errResp := pgAuthorStore.GetAuthor(input.AuthorID)
+	author, errResp := authorStore.GetAuthor(ctx, input.AuthorID)
 	if errResp != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Author not found"})
+		if errResp.Kind() == StructureData.CodeNotFound {
+			errResp = StructureData.FieldValidationError("author_id", "Author %d not found", input.AuthorID)
+		}
+		utils.WriteError(w, r, errResp)
 		return
 	}
 
@@ -109,22 +108,10 @@ func CreateBook(w http.ResponseWriter, r *http.Request) {
 		CreatedAt:   time.Now(),
 	}
 
-	// Create the book in PostgreSQL.
-	createdPgBook, pgErr := pgBookStore.CreateBook(book)
-	if pgErr != nil {
-		log.Printf("Error creating book in PostgreSQL: %v", pgErr.Message)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(pgErr)
-		return
-	}
-
-	// Create the book in the in-memory store.
-	createdBook, errResp := bookStore.CreateBook(createdPgBook)
+	createdBook, errResp := bookStore.CreateBook(ctx, book)
 	if errResp != nil {
-		// Roll back PostgreSQL creation if needed.
-		pgBookStore.DeleteBook(createdPgBook.ID)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errResp)
+		h.Log.Printf("Error creating book: %v", errResp.Message)
+		utils.WriteError(w, r, errResp)
 		return
 	}
 
@@ -132,49 +119,41 @@ func CreateBook(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(createdBook)
 }
 
-func UpdateBook(w http.ResponseWriter, r *http.Request) {
-    // Get instances of the in-memory and PostgreSQL BookStores,
-    // and the in-memory OrderStore.
-    bookStore := inmemoryStores.GetBookStoreInstance()
-    pgBookStore := postgresStores.GetPostgresBookStoreInstance()
-    orderStore := inmemoryStores.GetOrderStoreInstance()
+func (h *BookHandler) UpdateBook(w http.ResponseWriter, r *http.Request) {
+    ctx := r.Context()
+    bookStore := h.Books
+    orderStore := h.Orders
 
     // Extract book ID from URL.
     idStr := r.URL.Path[len("/books/"):]
     id, err := strconv.Atoi(idStr)
     if err != nil {
-        w.WriteHeader(http.StatusBadRequest)
-        json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid book ID"})
+        utils.WriteError(w, r, StructureData.FieldValidationError("id", "Invalid book ID"))
         return
     }
 
     // Check if the book is referenced by any orders.
-    orders := orderStore.GetAllOrders()
+    orders := orderStore.GetAllOrders(ctx)
     for _, order := range orders {
         for _, item := range order.Items {
             if item.Book.ID == id {
-                w.WriteHeader(http.StatusConflict)
-                json.NewEncoder(w).Encode(StructureData.ErrorResponse{
-                    Message: "Cannot update book that exists in existing orders",
-                })
+                utils.WriteError(w, r, StructureData.ConflictError("Cannot update book that exists in existing orders"))
                 return
             }
         }
     }
 
-    // Retrieve the existing book from the in-memory store to preserve its author.
-    existingBook, errResp := bookStore.GetBook(id)
+    // Retrieve the existing book to preserve its author.
+    existingBook, errResp := bookStore.GetBook(ctx, id)
     if errResp != nil {
-        w.WriteHeader(http.StatusNotFound)
-        json.NewEncoder(w).Encode(errResp)
+        utils.WriteError(w, r, errResp)
         return
     }
 
     // Decode the updated book data from the request body.
     var updatedBook StructureData.Book
     if err := json.NewDecoder(r.Body).Decode(&updatedBook); err != nil {
-        w.WriteHeader(http.StatusBadRequest)
-        json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid input"})
+        utils.WriteError(w, r, StructureData.ValidationError("Invalid input"))
         return
     }
 
@@ -183,25 +162,13 @@ func UpdateBook(w http.ResponseWriter, r *http.Request) {
 
     // Validate stock.
     if updatedBook.Stock < 1 {
-        w.WriteHeader(http.StatusBadRequest)
-        json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Stock must be ≥1"})
+        utils.WriteError(w, r, StructureData.FieldValidationError("stock", "Stock must be ≥1"))
         return
     }
 
-    // Update the book in PostgreSQL first.
-    pgUpdatedBook, pgErr := pgBookStore.UpdateBook(id, updatedBook)
-    if pgErr != nil {
-        w.WriteHeader(http.StatusInternalServerError)
-        json.NewEncoder(w).Encode(pgErr)
-        return
-    }
-
-    // Then update the in-memory store to synchronize.
-    finalBook, errResp := bookStore.UpdateBook(id, pgUpdatedBook)
+    finalBook, errResp := bookStore.UpdateBook(ctx, id, updatedBook)
     if errResp != nil {
-        // If in-memory update fails, you might consider rolling back the PG update.
-        w.WriteHeader(http.StatusInternalServerError)
-        json.NewEncoder(w).Encode(errResp)
+        utils.WriteError(w, r, errResp)
         return
     }
 
@@ -210,65 +177,96 @@ func UpdateBook(w http.ResponseWriter, r *http.Request) {
 }
 
 
-func DeleteBook(w http.ResponseWriter, r *http.Request) {
-	// Get the necessary store instances.
-	bookStore := inmemoryStores.GetBookStoreInstance()
-	orderStore := inmemoryStores.GetOrderStoreInstance()
-	pgBookStore := postgresStores.GetPostgresBookStoreInstance()
+func (h *BookHandler) DeleteBook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	bookStore := h.Books
+	orderStore := h.Orders
 
 	// Extract the book ID from the URL.
 	idStr := r.URL.Path[len("/books/"):]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid book ID"})
+		utils.WriteError(w, r, StructureData.FieldValidationError("id", "Invalid book ID"))
 		return
 	}
 
 	// Check if the book is referenced in any orders.
-	orders := orderStore.GetAllOrders()
+	orders := orderStore.GetAllOrders(ctx)
 	for _, order := range orders {
 		for _, item := range order.Items {
 			if item.Book.ID == id {
-				w.WriteHeader(http.StatusConflict)
-				json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Book linked to orders"})
+				utils.WriteError(w, r, StructureData.ConflictError("Book linked to orders"))
 				return
 			}
 		}
 	}
 
-	// Delete the book from the in-memory store.
-	errResp := bookStore.DeleteBook(id)
+	errResp := bookStore.DeleteBook(ctx, id)
 	if errResp != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResp)
+		utils.WriteError(w, r, errResp)
 		return
 	}
 
-	// Delete the book from PostgreSQL.
-	pgErr := pgBookStore.DeleteBook(id)
-	if pgErr != nil {
-		log.Printf("Error deleting book from PostgreSQL: %v", pgErr.Message)
-	}
-
 	w.WriteHeader(http.StatusNoContent)
 }
 
 
-func SearchBooks(w http.ResponseWriter, r *http.Request) {
-    pgStore := postgresStores.GetPostgresBookStoreInstance() // Use PostgreSQL
+func (h *BookHandler) SearchBooks(w http.ResponseWriter, r *http.Request) {
+    ctx := r.Context()
+    store := h.Books
     var criteria StructureData.BookSearchCriteria
     if err := json.NewDecoder(r.Body).Decode(&criteria); err != nil {
-        w.WriteHeader(http.StatusBadRequest)
-        json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid criteria"})
+        utils.WriteError(w, r, StructureData.ValidationError("Invalid criteria"))
         return
     }
-    searchResults, errResp := pgStore.SearchBooks(criteria) // Query PostgreSQL
+    if !parseSearchFilters(w, r,
+        searchFilter{"", criteria.Filter, StructureData.BookFilterFields},
+        searchFilter{"author_criteria.", criteria.AuthorCriteria.Filter, StructureData.AuthorFilterFields}) {
+        return
+    }
+    for _, facet := range criteria.Facets {
+        if !utils.ContainsString(StructureData.BookFacetNames, facet) {
+            utils.WriteError(w, r, StructureData.FieldValidationError("facets",
+                "Unknown facet %q; facets are %s", facet, strings.Join(StructureData.BookFacetNames, ", ")))
+            return
+        }
+    }
+    searchResults, errResp := store.SearchBooks(ctx, criteria)
+    if errResp != nil {
+        utils.WriteError(w, r, errResp)
+        return
+    }
+    if len(criteria.Facets) == 0 {
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(searchResults)
+        return
+    }
+
+    // With facets the results are wrapped together with the counts.
+    facets, errResp := store.SearchBookFacets(ctx, criteria)
     if errResp != nil {
-        w.WriteHeader(http.StatusInternalServerError)
-        json.NewEncoder(w).Encode(errResp)
+        utils.WriteError(w, r, errResp)
         return
     }
     w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(searchResults)
+    json.NewEncoder(w).Encode(StructureData.BookSearchResponse{Data: searchResults, Facets: facets})
+}
+
+// TextSearchBooks serves GET /books/search?q=, ranking books by how well their
+// title, author, genres and author bio match the query words.
+func (h *BookHandler) TextSearchBooks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	store := h.Books
+	query, errResp := utils.ParseTextQuery(r.URL.Query())
+	if errResp != nil {
+		utils.WriteError(w, r, errResp)
+		return
+	}
+	hits, meta, errResp := store.TextSearchBooks(ctx, query)
+	if errResp != nil {
+		utils.WriteError(w, r, errResp)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(StructureData.ListResponse{Data: hits, Meta: meta})
 }
\ No newline at end of file
@@ -2,152 +2,124 @@ package Controllers
 
 import (
 	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 	"time"
 
-	inmemoryStores "finalProject/InmemoryStores"
+	interfaces "finalProject/Interfaces"
 	"finalProject/StructureData"
 	"finalProject/auth"
-	postgresStores "finalProject/postgresStores"
+	"finalProject/utils"
 )
 
-func InitializeCustomerFile() {
-	pgStore := postgresStores.GetPostgresCustomerStoreInstance()
-	memStore := inmemoryStores.GetCustomerStoreInstance()
-
-	pgCustomers := pgStore.GetAllCustomers()
-
-	// Only initialize if memory store is empty
-	if len(memStore.GetAllCustomers()) == 0 {
-		for _, customer := range pgCustomers {
-			_, err := memStore.CreateCustomer(customer)
-			if err != nil {
-				log.Printf("Error loading customer %d into memory: %v", customer.ID, err.Message)
-			}
-		}
-		log.Printf("Loaded %d customers from PostgreSQL into memory", len(pgCustomers))
-	}
+// CustomerHandler serves the /customers routes. New customers are signed in
+// straight away with a token pair from Issuer, recorded in Tokens.
+type CustomerHandler struct {
+	Customers interfaces.CustomerStore
+	Orders    interfaces.OrderStore
+	Issuer    *auth.Issuer
+	Tokens    interfaces.TokenStore
+	Log       *log.Logger
 }
-func GetAllCustomers(w http.ResponseWriter, r *http.Request) {
 
-    pgStore := postgresStores.GetPostgresCustomerStoreInstance()
+func (h *CustomerHandler) GetAllCustomers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
-    // Fetch latest customers from PostgreSQL
-    pgCustomers := pgStore.GetAllCustomers()
+    store := h.Customers
 
-    
+    opts, ok := listOptions(w, r, StructureData.CustomerSortFields)
+    if !ok {
+        return
+    }
 
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(pgCustomers)
+    customers, meta, errResp := store.ListCustomers(ctx, opts)
+    if errResp != nil {
+        utils.WriteError(w, r, errResp)
+        return
+    }
+
+    writeList(w, r, customers, meta, opts.Fields)
 }
 
-func GetCustomerByID(w http.ResponseWriter, r *http.Request) {
-    memStore := inmemoryStores.GetCustomerStoreInstance()
-    pgStore := postgresStores.GetPostgresCustomerStoreInstance()
+func (h *CustomerHandler) GetCustomerByID(w http.ResponseWriter, r *http.Request) {
+    ctx := r.Context()
+    store := h.Customers
 
     idStr := r.URL.Path[len("/customers/"):]
     id, err := strconv.Atoi(idStr)
     if err != nil {
-        w.WriteHeader(http.StatusBadRequest)
-        json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid customer ID"})
-        return
-    }
-
-    // Check in-memory store first
-    customer, errResp := memStore.GetCustomer(id)
-    if errResp == nil {
-        w.Header().Set("Content-Type", "application/json")
-        json.NewEncoder(w).Encode(customer)
+        utils.WriteError(w, r, StructureData.FieldValidationError("id", "Invalid customer ID"))
         return
     }
 
-    // Fallback to PostgreSQL if not found in-memory
-    pgCustomer, pgErr := pgStore.GetCustomer(id)
-    if pgErr != nil {
-        w.WriteHeader(http.StatusNotFound)
-        json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Customer not found"})
+    customer, errResp := store.GetCustomer(ctx, id)
+    if errResp != nil {
+        utils.WriteError(w, r, errResp)
         return
     }
-
-    // Add to in-memory store for future requests
-    memStore.CreateCustomer(pgCustomer)
     w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(pgCustomer)
+    json.NewEncoder(w).Encode(customer)
 }
 
-func DeleteCustomer(w http.ResponseWriter, r *http.Request) {
-	store := inmemoryStores.GetCustomerStoreInstance()
-	orderStore := inmemoryStores.GetOrderStoreInstance()
-	pgStore := postgresStores.GetPostgresCustomerStoreInstance()
+func (h *CustomerHandler) DeleteCustomer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	store := h.Customers
+	orderStore := h.Orders
 
 	idStr := r.URL.Path[len("/customers/"):]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid customer ID"})
+		utils.WriteError(w, r, StructureData.FieldValidationError("id", "Invalid customer ID"))
 		return
 	}
 
-	orders := orderStore.GetAllOrders()
+	orders := orderStore.GetAllOrders(ctx)
 	for _, order := range orders {
 		if order.Customer.ID == id {
-			w.WriteHeader(http.StatusConflict)
-			json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Customer linked to orders"})
+			utils.WriteError(w, r, StructureData.ConflictError("Customer linked to orders"))
 			return
 		}
 	}
 
-	errResp := store.DeleteCustomer(id)
+	errResp := store.DeleteCustomer(ctx, id)
 	if errResp != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResp)
+		utils.WriteError(w, r, errResp)
 		return
 	}
 
-	pgErr := pgStore.DeleteCustomer(id)
-	if pgErr != nil {
-		log.Printf("Error deleting customer: %v", pgErr.Message)
-	}
-
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Customer deleted"})
 }
 
-func CreateCustomer(w http.ResponseWriter, r *http.Request) {
-	store := inmemoryStores.GetCustomerStoreInstance()
-	pgStore := postgresStores.GetPostgresCustomerStoreInstance()
+func (h *CustomerHandler) CreateCustomer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	store := h.Customers
 
 	var customer StructureData.Customer
 	if err := json.NewDecoder(r.Body).Decode(&customer); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid input"})
+		utils.WriteError(w, r, StructureData.ValidationError("Invalid input"))
 		return
 	}
 
 	// Ensure required fields are provided
 	if customer.Name == "" || customer.Email == "" || customer.Password == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Name, Email, and Password are required"})
+		utils.WriteError(w, r, StructureData.ValidationError("Name, Email, and Password are required"))
 		return
 	}
 
 	// Hash the password before storing
 	err := customer.HashPassword(customer.Password)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error hashing password"})
+		utils.WriteError(w, r, StructureData.InternalError("Error hashing password"))
 		return
 	}
 
 	// Check if the email already exists
-	existingCustomers := pgStore.GetAllCustomers()
+	existingCustomers := store.GetAllCustomers(ctx)
 	for _, existingCustomer := range existingCustomers {
 		if existingCustomer.Email == customer.Email {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Email already exists"})
+			utils.WriteError(w, r, StructureData.ConflictError("Email already exists"))
 			return
 		}
 	}
@@ -155,42 +127,35 @@ func CreateCustomer(w http.ResponseWriter, r *http.Request) {
 	// Set the CreatedAt field
 	customer.CreatedAt = time.Now()
 
-	// Save to PostgreSQL
-	createdPgCustomer, pgErr := pgStore.CreateCustomer(customer)
-	if pgErr != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error creating customer in PostgreSQL"})
-		return
-	}
+	// Self-registered accounts are always regular users; admins are provisioned in the database.
+	customer.Role = StructureData.RoleUser
 
-	// Save to in-memory store
-	_, errResp := store.CreateCustomer(createdPgCustomer)
+	createdCustomer, errResp := store.CreateCustomer(ctx, customer)
 	if errResp != nil {
-		// Roll back PostgreSQL insert if needed
-		pgStore.DeleteCustomer(createdPgCustomer.ID)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errResp)
+		h.Log.Printf("Error creating customer: %v", errResp.Message)
+		utils.WriteError(w, r, StructureData.InternalError("Error creating customer"))
 		return
 	}
 
-	// Generate JWT token for the newly created user
-	token, jwtErr := auth.GenerateJWT(createdPgCustomer.ID, createdPgCustomer.Email, createdPgCustomer.Username)
+	// Generate the token pair for the newly created user
+	pair, jwtErr := issueTokenPair(ctx, h.Issuer, h.Tokens, createdCustomer)
 	if jwtErr != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error generating JWT token"})
+		utils.WriteError(w, r, StructureData.InternalError("Error generating JWT token"))
 		return
 	}
 
-	// Return the customer info + token
+	// Return the customer info + tokens
 	response := map[string]interface{}{
-		"message": "Customer created successfully",
-		"token":   token,
+		"message":       "Customer created successfully",
+		"token":         pair.AccessToken,
+		"refresh_token": pair.RefreshToken,
 		"customer": map[string]interface{}{
-			"id":         createdPgCustomer.ID,
-			"name":       createdPgCustomer.Name,
-			"email":      createdPgCustomer.Email,
-			"username":   createdPgCustomer.Username,
-			"created_at": createdPgCustomer.CreatedAt,
+			"id":         createdCustomer.ID,
+			"name":       createdCustomer.Name,
+			"email":      createdCustomer.Email,
+			"username":   createdCustomer.Username,
+			"role":       createdCustomer.Role,
+			"created_at": createdCustomer.CreatedAt,
 		},
 	}
 
@@ -199,89 +164,59 @@ func CreateCustomer(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-func UpdateCustomer(w http.ResponseWriter, r *http.Request) {
-	// Retrieve store instances.
-	pgStore := postgresStores.GetPostgresCustomerStoreInstance()
-	memStore := inmemoryStores.GetCustomerStoreInstance()
+func (h *CustomerHandler) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	store := h.Customers
 
 	// Extract customer ID from the URL.
 	idStr := r.URL.Path[len("/customers/"):]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid customer ID"})
+		utils.WriteError(w, r, StructureData.FieldValidationError("id", "Invalid customer ID"))
 		return
 	}
 
 	// Decode the incoming customer update.
 	var customer StructureData.Customer
 	if err := json.NewDecoder(r.Body).Decode(&customer); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid input"})
+		utils.WriteError(w, r, StructureData.ValidationError("Invalid input"))
 		return
 	}
 
 	// Validate required fields.
 	if customer.Name == "" || customer.Email == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Name and Email required"})
+		utils.WriteError(w, r, StructureData.ValidationError("Name and Email required"))
 		return
 	}
 
-	// First, update the customer in PostgreSQL.
-	updatedPgCustomer, pgErr := pgStore.UpdateCustomer(id, customer)
-	if pgErr != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: fmt.Sprintf("Error updating customer in PostgreSQL: %v", pgErr.Message)})
-		return
-	}
-
-	// Then, update the customer in the in-memory store.
-	updatedMemCustomer, memErrResp := memStore.UpdateCustomer(id, updatedPgCustomer)
-	if memErrResp != nil {
-		// Optionally: you could attempt to revert the PG update here.
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Error updating in-memory customer; data may be inconsistent"})
+	updatedCustomer, errResp := store.UpdateCustomer(ctx, id, customer)
+	if errResp != nil {
+		utils.WriteError(w, r, errResp)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(updatedMemCustomer)
+	json.NewEncoder(w).Encode(updatedCustomer)
 }
-func SearchCustomers(w http.ResponseWriter, r *http.Request) {
-    pgStore := postgresStores.GetPostgresCustomerStoreInstance()
-    memStore := inmemoryStores.GetCustomerStoreInstance()
+func (h *CustomerHandler) SearchCustomers(w http.ResponseWriter, r *http.Request) {
+    ctx := r.Context()
+    store := h.Customers
 
     var criteria StructureData.CustomerSearchCriteria
     if err := json.NewDecoder(r.Body).Decode(&criteria); err != nil {
-        w.WriteHeader(http.StatusBadRequest)
-        json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid criteria"})
+        utils.WriteError(w, r, StructureData.ValidationError("Invalid criteria"))
         return
     }
-
-    // Search in PostgreSQL for accurate results
-    pgResults, pgErr := pgStore.SearchCustomers(criteria)
-    if pgErr != nil {
-        w.WriteHeader(http.StatusInternalServerError)
-        json.NewEncoder(w).Encode(pgErr)
+    if !parseSearchFilters(w, r, searchFilter{"", criteria.Filter, StructureData.CustomerFilterFields}) {
         return
     }
 
-    // Update in-memory store with the found customers
-    for _, customer := range pgResults {
-        existing, err := memStore.GetCustomer(customer.ID)
-        if err != nil {
-            memStore.CreateCustomer(customer)
-        } else if !customersEqual(existing, customer) {
-            memStore.UpdateCustomer(customer.ID, customer)
-        }
+    customers, errResp := store.SearchCustomers(ctx, criteria)
+    if errResp != nil {
+        utils.WriteError(w, r, errResp)
+        return
     }
 
     w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(pgResults)
-}
-
-// Helper function to check customer equality
-func customersEqual(a, b StructureData.Customer) bool {
-    return a.Name == b.Name && a.Email == b.Email && a.Username == b.Username
+    json.NewEncoder(w).Encode(customers)
 }
@@ -0,0 +1,97 @@
+package Controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	cachedStores "finalProject/cachedStores"
+)
+
+// HealthHandler serves the /health routes. Each of its fields is optional: without
+// Ping the database is reported unavailable, and without CacheStats no caches are
+// listed.
+type HealthHandler struct {
+	// Ping checks that the database can be reached.
+	Ping func(ctx context.Context) error
+	// PoolStats returns the statistics of the database connection pool.
+	PoolStats func() (sql.DBStats, bool)
+	// CacheStats returns the statistics of the caches in front of the stores.
+	CacheStats func() map[string]cachedStores.Stats
+}
+
+// DatabaseHealth reports database reachability and connection pool statistics.
+type DatabaseHealth struct {
+	Status             string `json:"status"`
+	Error              string `json:"error,omitempty"`
+	MaxOpenConnections int    `json:"max_open_connections"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"wait_count"`
+	WaitDuration       string `json:"wait_duration"`
+	MaxIdleClosed      int64  `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64  `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64  `json:"max_lifetime_closed"`
+}
+
+// GetDatabaseHealth pings the database and returns the statistics of its pool.
+// It responds 503 when the database cannot be reached.
+func (h *HealthHandler) GetDatabaseHealth(w http.ResponseWriter, r *http.Request) {
+	health := DatabaseHealth{Status: "ok"}
+	status := http.StatusOK
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+	if err := h.ping(ctx); err != nil {
+		health.Status = "unavailable"
+		health.Error = err.Error()
+		status = http.StatusServiceUnavailable
+	}
+
+	if stats, ok := h.poolStats(); ok {
+		health.MaxOpenConnections = stats.MaxOpenConnections
+		health.OpenConnections = stats.OpenConnections
+		health.InUse = stats.InUse
+		health.Idle = stats.Idle
+		health.WaitCount = stats.WaitCount
+		health.WaitDuration = stats.WaitDuration.String()
+		health.MaxIdleClosed = stats.MaxIdleClosed
+		health.MaxIdleTimeClosed = stats.MaxIdleTimeClosed
+		health.MaxLifetimeClosed = stats.MaxLifetimeClosed
+	}
+
+	response, _ := json.Marshal(health)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// ping checks the database, failing when there is none.
+func (h *HealthHandler) ping(ctx context.Context) error {
+	if h.Ping == nil {
+		return errors.New("no database configured")
+	}
+	return h.Ping(ctx)
+}
+
+// poolStats returns the statistics of the database pool, if there is one.
+func (h *HealthHandler) poolStats() (sql.DBStats, bool) {
+	if h.PoolStats == nil {
+		return sql.DBStats{}, false
+	}
+	return h.PoolStats()
+}
+
+// GetCacheStats returns the statistics of the cache in front of each store.
+func (h *HealthHandler) GetCacheStats(w http.ResponseWriter, r *http.Request) {
+	stats := map[string]cachedStores.Stats{}
+	if h.CacheStats != nil {
+		stats = h.CacheStats()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
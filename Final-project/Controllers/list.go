@@ -0,0 +1,31 @@
+package Controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// listOptions parses the paging, sorting and field selection parameters of a list
+// request, writing a validation problem when they are invalid.
+func listOptions(w http.ResponseWriter, r *http.Request, sortable []string) (StructureData.ListOptions, bool) {
+	opts, errResp := utils.ParseListOptions(r.URL.Query(), sortable)
+	if errResp != nil {
+		utils.WriteError(w, r, errResp)
+		return StructureData.ListOptions{}, false
+	}
+	return opts, true
+}
+
+// writeList writes one page of a list endpoint, reduced to the requested fields.
+func writeList(w http.ResponseWriter, r *http.Request, items interface{}, meta StructureData.ListMeta, fields []string) {
+	selected, errResp := utils.SelectFields(items, fields)
+	if errResp != nil {
+		utils.WriteError(w, r, errResp)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(StructureData.ListResponse{Data: selected, Meta: meta})
+}
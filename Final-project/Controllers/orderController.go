@@ -3,476 +3,344 @@ package Controllers
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
-	"log"
 	"net/http"
-	"sort"
 	"strconv"
 	"time"
 
-	inmemoryStores "finalProject/InmemoryStores"
+	interfaces "finalProject/Interfaces"
 	"finalProject/StructureData"
-	postgresStores "finalProject/postgresStores"
+	"finalProject/auth"
+	"finalProject/utils"
 )
 
-func InitializeOrderFile() {
-	pgStore := postgresStores.GetPostgresOrderStoreInstance()
-	memStore := inmemoryStores.GetOrderStoreInstance()
-
-	// Load PostgreSQL orders into memory
-	pgOrders := pgStore.GetAllOrders()
-
-	// Only initialize if memory store is empty
-	if len(memStore.GetAllOrders()) == 0 {
-		for _, order := range pgOrders {
-			_, err := memStore.CreateOrder(order)
-			if err != nil {
-				log.Printf("Error loading order %d into memory: %v", order.ID, err.Message)
-			}
-		}
-		log.Printf("Loaded %d orders from PostgreSQL into memory", len(pgOrders))
-	}
+// OrderHandler serves the /orders routes. Orders are read from Orders; every change
+// to an order goes through Processor, which moves the stock its items hold.
+type OrderHandler struct {
+	Orders    interfaces.OrderStore
+	Processor interfaces.OrderProcessor
+	Customers interfaces.CustomerStore
 }
 
-func GetAllOrders(w http.ResponseWriter, r *http.Request) {
-	store := inmemoryStores.GetOrderStoreInstance()
-	orders := store.GetAllOrders()
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(orders)
+func (h *OrderHandler) GetAllOrders(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	store := h.Orders
+	opts, ok := listOptions(w, r, StructureData.OrderSortFields)
+	if !ok {
+		return
+	}
+	orders, meta, errResp := store.ListOrders(ctx, opts)
+	if errResp != nil {
+		utils.WriteError(w, r, errResp)
+		return
+	}
+	writeList(w, r, orders, meta, opts.Fields)
 }
 
-func GetOrderByID(w http.ResponseWriter, r *http.Request) {
-	store := inmemoryStores.GetOrderStoreInstance()
+func (h *OrderHandler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	store := h.Orders
 	idStr := r.URL.Path[len("/orders/"):]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid order ID"})
+		utils.WriteError(w, r, StructureData.FieldValidationError("id", "Invalid order ID"))
 		return
 	}
-	order, errResp := store.GetOrder(id)
+	order, errResp := store.GetOrder(ctx, id)
 	if errResp != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResp)
+		utils.WriteError(w, r, errResp)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(order)
 }
 
-
-func CreateOrder(w http.ResponseWriter, r *http.Request) {
-	orderStore := inmemoryStores.GetOrderStoreInstance()
-	customerStore := inmemoryStores.GetCustomerStoreInstance()
-	bookStore := inmemoryStores.GetBookStoreInstance()
-	pgStore := postgresStores.GetPostgresOrderStoreInstance()
-	pgBookStore := postgresStores.GetPostgresBookStoreInstance()
-
-	var order StructureData.Order
-	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid input"})
-		return
+// requestCustomerID resolves the customer a request acts for from the token in its
+// context. Admins may act for the customer given in requested; everyone else always
+// acts for themselves.
+func requestCustomerID(r *http.Request, requested int) (int, bool) {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if !ok {
+		return 0, false
 	}
+	if claims.Role == StructureData.RoleAdmin && requested != 0 {
+		return requested, true
+	}
+	return claims.ID, true
+}
 
-	if order.Status == "" {
-		order.Status = StructureData.OrderStatusPending
+// customerError is the error for an order whose customer could not be read: a
+// validation error on customer.id if the customer does not exist, and errResp
+// otherwise.
+func customerError(errResp *StructureData.ErrorResponse, customerID int) *StructureData.ErrorResponse {
+	if errResp.Kind() == StructureData.CodeNotFound {
+		return StructureData.FieldValidationError("customer.id", "Customer %d does not exist", customerID)
 	}
+	return errResp
+}
 
-	// Validate customer exists in memory.
-	customer, errResp := customerStore.GetCustomer(order.Customer.ID)
+// OrderOwnerID returns the ID of the customer who placed the order with the given ID.
+func (h *OrderHandler) OrderOwnerID(ctx context.Context, id int) (int, bool) {
+	order, errResp := h.Orders.GetOrder(ctx, id)
 	if errResp != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Customer does not exist"})
+		return 0, false
+	}
+	return order.Customer.ID, true
+}
+
+// OrderInput is the request body for creating or updating an order. By default an
+// order is refused if any of its items cannot be supplied; AllowPartial places it
+// with the remaining items instead.
+type OrderInput struct {
+	StructureData.Order
+	AllowPartial bool `json:"allow_partial"`
+}
+
+func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	customerStore := h.Customers
+	processor := h.Processor
+
+	var input OrderInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		utils.WriteError(w, r, StructureData.ValidationError("Invalid input"))
 		return
 	}
-	order.Customer = customer
+	order := input.Order
+	order.RejectedItems = nil
+	// Every order starts out pending and moves on through its transitions.
+	order.Status = StructureData.OrderStatusPending
 
-	validItems := []StructureData.OrderItem{}
-	for _, item := range order.Items {
-		// Attempt to fetch the book from the in-memory store.
-		book, bookErr := bookStore.GetBook(item.Book.ID)
-		log.Println(book)
-		// If not found in memory, try PostgreSQL.
-		if bookErr != nil {
-			pgBook, pgErrResp := pgBookStore.GetBook(item.Book.ID)
-			log.Println(pgBook)
-			if pgErrResp != nil {
-				// If not found in PostgreSQL either, skip this item.
-				continue
-			}
-			// Add the book from PostgreSQL into the in-memory store.
-			_, memErr := bookStore.CreateBook(pgBook)
-			if memErr != nil {
-				continue
-			}
-			book = pgBook
-		}
-		// Check if the requested quantity is available.
-		if item.Quantity > book.Stock || book.Stock == 0 {
-			continue
-		}
-		// Deduct stock.
-		book.Stock -= item.Quantity
-
-		// Update the book in both stores.
-		_, updateErr := bookStore.UpdateBook(book.ID, book)
-		if updateErr != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Failed to update book stock in memory"})
-			return
-		}
-		if _, pgUpdateErr := pgBookStore.UpdateBook(book.ID, book); pgUpdateErr != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Failed to update book stock in PostgreSQL"})
-			return
-		}
-		item.Book = book
-		validItems = append(validItems, item)
-	}
-	if len(validItems) == 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "No valid books available"})
+	// The ordering customer comes from the token, not from the request body.
+	customerID, ok := requestCustomerID(r, order.Customer.ID)
+	if !ok {
+		utils.WriteError(w, r, StructureData.UnauthorizedError("Authentication required"))
 		return
 	}
-	order.Items = validItems
-	order.CreatedAt = time.Now()
 
-	createdPgOrder, errResp := pgStore.CreateOrder(order)
+	// Validate customer exists.
+	customer, errResp := customerStore.GetCustomer(ctx, customerID)
 	if errResp != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errResp)
+		utils.WriteError(w, r, customerError(errResp, customerID))
 		return
 	}
+	order.Customer = customer
 
-	createdOrder, errResp := orderStore.CreateOrder(createdPgOrder)
+	order.CreatedAt = time.Now()
+
+	// Stock is reserved and the order stored in one step.
+	placedOrder, _, errResp := processor.PlaceOrder(ctx, order, input.AllowPartial)
 	if errResp != nil {
-		pgStore.DeleteOrder(createdPgOrder.ID)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errResp)
+		utils.WriteError(w, r, errResp)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(createdOrder)
+	json.NewEncoder(w).Encode(placedOrder)
 }
 
-
-func UpdateOrder(w http.ResponseWriter, r *http.Request) {
-	orderStore := inmemoryStores.GetOrderStoreInstance()
-	customerStore := inmemoryStores.GetCustomerStoreInstance()
-	bookStore := inmemoryStores.GetBookStoreInstance()
-	pgStore := postgresStores.GetPostgresOrderStoreInstance()
-	pgBookStore := postgresStores.GetPostgresBookStoreInstance()
+func (h *OrderHandler) UpdateOrder(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	orderStore := h.Orders
+	customerStore := h.Customers
+	processor := h.Processor
 
 	idStr := r.URL.Path[len("/orders/"):]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid order ID"})
+		utils.WriteError(w, r, StructureData.FieldValidationError("id", "Invalid order ID"))
 		return
 	}
 
-	existingOrder, errResp := orderStore.GetOrder(id)
+	existingOrder, errResp := orderStore.GetOrder(ctx, id)
 	if errResp != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResp)
+		utils.WriteError(w, r, errResp)
+		return
+	}
+
+	if existingOrder.Status != StructureData.OrderStatusPending {
+		utils.WriteError(w, r, StructureData.ConflictError("Only pending orders can be updated"))
 		return
 	}
 
-	if existingOrder.Status == StructureData.OrderStatusSuccess {
-		w.WriteHeader(http.StatusForbidden)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Cannot update successful orders"})
+	var input OrderInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		utils.WriteError(w, r, StructureData.ValidationError("Invalid input"))
 		return
 	}
+	updatedOrder := input.Order
+	updatedOrder.RejectedItems = nil
 
-	var updatedOrder StructureData.Order
-	if err := json.NewDecoder(r.Body).Decode(&updatedOrder); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid input"})
+	// An admin who names no customer keeps the order's current one.
+	requested := updatedOrder.Customer.ID
+	if requested == 0 {
+		requested = existingOrder.Customer.ID
+	}
+	customerID, ok := requestCustomerID(r, requested)
+	if !ok {
+		utils.WriteError(w, r, StructureData.UnauthorizedError("Authentication required"))
 		return
 	}
 
-	customer, errResp := customerStore.GetCustomer(updatedOrder.Customer.ID)
+	customer, errResp := customerStore.GetCustomer(ctx, customerID)
 	if errResp != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Customer does not exist"})
+		utils.WriteError(w, r, customerError(errResp, customerID))
 		return
 	}
 	updatedOrder.Customer = customer
 
-	// Revert stock for existing order items.
-	for _, item := range existingOrder.Items {
-		book, bookErr := bookStore.GetBook(item.Book.ID)
-		if bookErr == nil {
-			book.Stock += item.Quantity
-			// Update in-memory.
-			_, _ = bookStore.UpdateBook(book.ID, book)
-			// Also update in PostgreSQL.
-			_, _ = pgBookStore.UpdateBook(book.ID, book)
-		}
-	}
-
-	validItems := []StructureData.OrderItem{}
-	for _, item := range updatedOrder.Items {
-		book, bookErr := bookStore.GetBook(item.Book.ID)
-		if bookErr != nil {
-			continue
-		}
-		if item.Quantity > book.Stock || book.Stock == 0 {
-			continue
-		}
-		book.Stock -= item.Quantity
-		// Update in-memory.
-		_, updateErr := bookStore.UpdateBook(book.ID, book)
-		if updateErr != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Failed to update book stock in memory"})
-			return
-		}
-		// Update in PostgreSQL.
-		if _, pgUpdateErr := pgBookStore.UpdateBook(book.ID, book); pgUpdateErr != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Failed to update book stock in PostgreSQL"})
-			return
-		}
-		item.Book = book
-		validItems = append(validItems, item)
-	}
-	if len(validItems) == 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "No valid books available"})
+	updatedOrder.CreatedAt = existingOrder.CreatedAt
+	// Status only changes through POST /orders/:id/transitions.
+	updatedOrder.Status = existingOrder.Status
+
+	// Returning the old items to stock, reserving the new ones and rewriting the
+	// order happen in one step.
+	replacedOrder, _, errResp := processor.ReplaceOrder(ctx, id, updatedOrder, input.AllowPartial)
+	if errResp != nil {
+		utils.WriteError(w, r, errResp)
 		return
 	}
-	updatedOrder.Items = validItems
-	updatedOrder.CreatedAt = existingOrder.CreatedAt
 
-	updatedOrder, errResp = orderStore.UpdateOrder(id, updatedOrder)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(replacedOrder)
+}
+
+func (h *OrderHandler) DeleteOrder(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	orderStore := h.Orders
+	processor := h.Processor
+
+	idStr := r.URL.Path[len("/orders/"):]
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.WriteError(w, r, StructureData.FieldValidationError("id", "Invalid order ID"))
+		return
+	}
+
+	order, errResp := orderStore.GetOrder(ctx, id)
 	if errResp != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResp)
+		utils.WriteError(w, r, errResp)
 		return
 	}
 
-	_, pgErr := pgStore.UpdateOrder(id, updatedOrder)
-	if pgErr != nil {
-		log.Printf("Error updating order in PostgreSQL: %v", pgErr.Message)
+	if order.Status != StructureData.OrderStatusPending {
+		utils.WriteError(w, r, StructureData.ConflictError("Only pending orders can be deleted"))
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(updatedOrder)
+	if _, errResp := processor.RemoveOrder(ctx, id); errResp != nil {
+		utils.WriteError(w, r, errResp)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func DeleteOrder(w http.ResponseWriter, r *http.Request) {
-	orderStore := inmemoryStores.GetOrderStoreInstance()
-	bookStore := inmemoryStores.GetBookStoreInstance()
-	pgStore := postgresStores.GetPostgresOrderStoreInstance()
-	pgBookStore := postgresStores.GetPostgresBookStoreInstance()
+// TransitionOrder moves an order to the status named in the request body. Customers
+// may only cancel their own orders; every other transition is made by an admin.
+// Cancelling an order returns its items to stock.
+func (h *OrderHandler) TransitionOrder(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	orderStore := h.Orders
+	processor := h.Processor
 
 	idStr := r.URL.Path[len("/orders/"):]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid order ID"})
+		utils.WriteError(w, r, StructureData.FieldValidationError("id", "Invalid order ID"))
 		return
 	}
 
-	order, errResp := orderStore.GetOrder(id)
-	if errResp != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResp)
+	var transition StructureData.OrderTransition
+	if err := json.NewDecoder(r.Body).Decode(&transition); err != nil {
+		utils.WriteError(w, r, StructureData.ValidationError("Invalid input"))
+		return
+	}
+	if !StructureData.IsOrderStatus(transition.Status) || transition.Status == StructureData.OrderStatusSuccess {
+		utils.WriteError(w, r, StructureData.FieldValidationError("status", "Unknown order status %q", transition.Status))
 		return
 	}
 
-	if order.Status == StructureData.OrderStatusSuccess {
-		w.WriteHeader(http.StatusForbidden)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Cannot delete successful orders"})
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		utils.WriteError(w, r, StructureData.UnauthorizedError("Authentication required"))
+		return
+	}
+	if claims.Role != StructureData.RoleAdmin && transition.Status != StructureData.OrderStatusCancelled {
+		utils.WriteError(w, r, StructureData.ForbiddenError("Only admins can mark orders as %s", transition.Status))
 		return
 	}
 
-	for _, item := range order.Items {
-		book, bookErr := bookStore.GetBook(item.Book.ID)
-		if bookErr != nil {
-			continue
-		}
-		book.Stock += item.Quantity
-		_, _ = bookStore.UpdateBook(book.ID, book)
-		_, _ = pgBookStore.UpdateBook(book.ID, book)
+	order, errResp := orderStore.GetOrder(ctx, id)
+	if errResp != nil {
+		utils.WriteError(w, r, errResp)
+		return
+	}
+	if !StructureData.CanTransitionOrder(order.Status, transition.Status) {
+		utils.WriteError(w, r, StructureData.ConflictError("Cannot move order from %s to %s", order.Status, transition.Status))
+		return
 	}
 
-	errResp = orderStore.DeleteOrder(id)
+	// The status check above may run against a cached copy; the processor checks
+	// again with the order locked.
+	change, _, errResp := processor.TransitionOrder(ctx, id, transition.Status, claims.ID, transition.Note)
 	if errResp != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(errResp)
+		utils.WriteError(w, r, errResp)
 		return
 	}
 
-	pgErr := pgStore.DeleteOrder(id)
-	if pgErr != nil {
-		log.Printf("Error deleting order from PostgreSQL: %v", pgErr.Message)
+	order.Status = change.ToStatus
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// GetOrderHistory returns the status changes an order has gone through.
+func (h *OrderHandler) GetOrderHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	processor := h.Processor
+
+	idStr := r.URL.Path[len("/orders/"):]
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.WriteError(w, r, StructureData.FieldValidationError("id", "Invalid order ID"))
+		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	if _, errResp := h.Orders.GetOrder(ctx, id); errResp != nil {
+		utils.WriteError(w, r, errResp)
+		return
+	}
+
+	history, errResp := processor.GetOrderHistory(ctx, id)
+	if errResp != nil {
+		utils.WriteError(w, r, errResp)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
 }
 
-func SearchOrders(w http.ResponseWriter, r *http.Request) {
-    pgStore := postgresStores.GetPostgresOrderStoreInstance() // Use PostgreSQL
+func (h *OrderHandler) SearchOrders(w http.ResponseWriter, r *http.Request) {
+    ctx := r.Context()
+    store := h.Orders
     var criteria StructureData.OrderSearchCriteria
     if err := json.NewDecoder(r.Body).Decode(&criteria); err != nil {
-        w.WriteHeader(http.StatusBadRequest)
-        json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid search criteria"})
+        utils.WriteError(w, r, StructureData.ValidationError("Invalid search criteria"))
+        return
+    }
+    bookCriteria := &criteria.ItemCriteria.BookCriteria
+    if !parseSearchFilters(w, r,
+        searchFilter{"", criteria.Filter, StructureData.OrderFilterFields},
+        searchFilter{"item_criteria.book_criteria.", bookCriteria.Filter, StructureData.BookFilterFields},
+        searchFilter{"item_criteria.book_criteria.author_criteria.", bookCriteria.AuthorCriteria.Filter, StructureData.AuthorFilterFields}) {
         return
     }
-    searchResults, errResp := pgStore.SearchOrders(criteria) // Query PostgreSQL
+    searchResults, errResp := store.SearchOrders(ctx, criteria)
     if errResp != nil {
-        w.WriteHeader(http.StatusInternalServerError)
-        json.NewEncoder(w).Encode(errResp)
+        utils.WriteError(w, r, errResp)
         return
     }
     w.Header().Set("Content-Type", "application/json")
     json.NewEncoder(w).Encode(searchResults)
 }
-
-func GenerateSalesReport(ctx context.Context) {
-	// Use the PostgreSQL order and sales report stores.
-	orderStore := postgresStores.GetPostgresOrderStoreInstance()
-	reportStore := postgresStores.GetPostgresSalesReportStoreInstance()
-
-	// Use local time for the report window.
-	nowLocal := time.Now() // local time
-	endTime := nowLocal
-	startTime := endTime.Add(-24 * time.Hour)
-
-	var report StructureData.SalesReport
-	report.Timestamp = endTime
-	// Initialize a map to accumulate revenue and quantity per book.
-	bookRevenueMap := make(map[int]*StructureData.TopSellingBook)
-
-	orders := orderStore.GetAllOrders()
-	
-	log.Printf("[Report] Local Time Range: %s to %s",
-		startTime.Format(time.RFC3339),
-		endTime.Format(time.RFC3339),
-	)
-
-	for _, order := range orders {
-		// Convert order.CreatedAt to local time.
-		orderTimeLocal := order.CreatedAt.Local().Add(-1 * time.Hour)
-
-		log.Printf("[Order %d] Created (Local): %s | Status: %s | Total: $%.2f",
-			order.ID,
-			orderTimeLocal.Format(time.RFC3339),
-			order.Status,
-			order.TotalPrice,
-		)
-
-		// Check if order is within the local time window.
-		if orderTimeLocal.Before(startTime) || orderTimeLocal.After(endTime) {
-			log.Printf("[Excluded] Order %d - Reason: %s",
-				order.ID,
-				getExclusionReason(orderTimeLocal, startTime, endTime),
-			)
-			continue
-		}
-
-		log.Printf("[Included] Order %d - Within time window", order.ID)
-		report.TotalOrders++
-		report.TotalRevenue += order.TotalPrice
-
-		// Count order status if needed.
-		switch order.Status {
-		case StructureData.OrderStatusSuccess:
-			report.SuccessfulOrders++
-		case StructureData.OrderStatusPending:
-			report.PendingOrders++
-		}
-		log.Println(order.Items)
-		// Process each order item.
-		for _, item := range order.Items {
-			if item.Book.ID == 0 {
-				log.Printf("[Warning] Invalid book in order %d", order.ID)
-				continue
-			}
-
-			// Calculate revenue for this item.
-			revenue := item.Book.Price * float64(item.Quantity)
-			// If we've already seen this book, update its totals.
-			if existing, exists := bookRevenueMap[item.Book.ID]; exists {
-				existing.QuantitySold += item.Quantity
-				existing.TotalRevenue += revenue
-			} else {
-				// Otherwise, create a new TopSellingBook entry.
-				bookRevenueMap[item.Book.ID] = &StructureData.TopSellingBook{
-					Book: item.Book,
-					QuantitySold: item.Quantity,
-					TotalRevenue: revenue,
-				}
-			}
-		}
-	}
-
-	// Convert the map to a slice.
-	var topSellers []StructureData.TopSellingBook
-	for _, tsb := range bookRevenueMap {
-		topSellers = append(topSellers, *tsb)
-	}
-
-	// Sort the top sellers by descending total revenue.
-	sort.Slice(topSellers, func(i, j int) bool {
-		return topSellers[i].TotalRevenue > topSellers[j].TotalRevenue
-	})
-
-	// Limit the list to at most five top-selling books.
-	if len(topSellers) > 5 {
-		report.TopSellingBooks = topSellers[:5]
-	} else {
-		report.TopSellingBooks = topSellers
-	}
-
-	log.Printf("[Report] Final Result: (Timestamp=%s, TotalRevenue=%.2f, TotalOrders=%d, PendingOrders=%d, SuccessfulOrders=%d, TopSellingBooks=%+v)",
-		report.Timestamp.Format(time.RFC3339),
-		report.TotalRevenue,
-		report.TotalOrders,
-		report.PendingOrders,
-		report.SuccessfulOrders,
-		report.TopSellingBooks,
-	)
-
-	// Save the report to PostgreSQL.
-	if _, err := reportStore.SaveSalesReport(report); err != nil {
-		log.Printf("[Error] Failed to save sales report: %v", err)
-	}
-}
-
-func getExclusionReason(orderTime, startTime, endTime time.Time) string {
-	if orderTime.Before(startTime) {
-		return fmt.Sprintf("Too old (Order: %s < Start: %s)",
-			orderTime.Format(time.RFC3339),
-			startTime.Format(time.RFC3339),
-		)
-	}
-	return fmt.Sprintf("Too new (Order: %s > End: %s)",
-		orderTime.Format(time.RFC3339),
-		endTime.Format(time.RFC3339),
-	)
-}
-
-
-// GetSalesReport handles GET /reports/sales by retrieving sales reports from PostgreSQL.
-func GetSalesReport(ctx context.Context, w http.ResponseWriter, r *http.Request) {
-	reportStore := postgresStores.GetPostgresSalesReportStoreInstance()
-
-	reports, err := reportStore.GetAllSalesReports()
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{
-			Message: "Failed to retrieve sales reports",
-		})
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(reports)
-}
@@ -0,0 +1,150 @@
+package Controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	"finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// ReportHandler generates the daily sales reports from Orders and serves them from
+// Reports.
+type ReportHandler struct {
+	Orders  interfaces.OrderStore
+	Reports interfaces.SalesReportStore
+	Log     *log.Logger
+}
+
+func (h *ReportHandler) GenerateSalesReport(ctx context.Context) {
+	orderStore := h.Orders
+	reportStore := h.Reports
+
+	// The report covers the 24 hours up to now, compared in UTC.
+	endTime := time.Now().UTC()
+	startTime := endTime.Add(-24 * time.Hour)
+
+	var report StructureData.SalesReport
+	report.Timestamp = endTime
+	report.StatusCounts = make(map[string]int, len(StructureData.OrderStatuses))
+	for _, status := range StructureData.OrderStatuses {
+		report.StatusCounts[status] = 0
+	}
+	// Initialize a map to accumulate revenue and quantity per book.
+	bookRevenueMap := make(map[int]*StructureData.TopSellingBook)
+
+	h.Log.Printf("[Report] Time Range (UTC): %s to %s",
+		startTime.Format(time.RFC3339),
+		endTime.Format(time.RFC3339),
+	)
+
+	orders, err := orderStore.GetOrdersInTimeRange(ctx, startTime, endTime)
+	if err != nil {
+		h.Log.Printf("[Error] Failed to load orders for the sales report: %v", err)
+		return
+	}
+
+	for _, order := range orders {
+		h.Log.Printf("[Order %d] Created: %s | Status: %s | Total: $%.2f",
+			order.ID,
+			order.CreatedAt.UTC().Format(time.RFC3339),
+			order.Status,
+			order.TotalPrice,
+		)
+		report.TotalOrders++
+		report.StatusCounts[order.Status]++
+
+		switch order.Status {
+		case StructureData.OrderStatusPaid, StructureData.OrderStatusSuccess,
+			StructureData.OrderStatusShipped, StructureData.OrderStatusDelivered:
+			report.SuccessfulOrders++
+		case StructureData.OrderStatusPending:
+			report.PendingOrders++
+		}
+
+		// Cancelled and refunded orders bring in no revenue.
+		if !StructureData.IsOrderRevenue(order.Status) {
+			continue
+		}
+		report.TotalRevenue += order.TotalPrice
+		// Process each order item.
+		for _, item := range order.Items {
+			if item.Book.ID == 0 {
+				h.Log.Printf("[Warning] Invalid book in order %d", order.ID)
+				continue
+			}
+
+			// Revenue uses the price the item was bought at, not today's price.
+			revenue := item.Subtotal()
+			// If we've already seen this book, update its totals.
+			if existing, exists := bookRevenueMap[item.Book.ID]; exists {
+				existing.QuantitySold += item.Quantity
+				existing.TotalRevenue += revenue
+			} else {
+				// Otherwise, create a new TopSellingBook entry.
+				bookRevenueMap[item.Book.ID] = &StructureData.TopSellingBook{
+					Book: StructureData.Book{
+						ID:    item.Book.ID,
+						Title: item.BookTitle,
+						Price: item.UnitPrice,
+					},
+					QuantitySold: item.Quantity,
+					TotalRevenue: revenue,
+				}
+			}
+		}
+	}
+
+	// Convert the map to a slice.
+	var topSellers []StructureData.TopSellingBook
+	for _, tsb := range bookRevenueMap {
+		topSellers = append(topSellers, *tsb)
+	}
+
+	// Sort the top sellers by descending total revenue.
+	sort.Slice(topSellers, func(i, j int) bool {
+		return topSellers[i].TotalRevenue > topSellers[j].TotalRevenue
+	})
+
+	// Limit the list to at most five top-selling books.
+	if len(topSellers) > 5 {
+		report.TopSellingBooks = topSellers[:5]
+	} else {
+		report.TopSellingBooks = topSellers
+	}
+
+	h.Log.Printf("[Report] Final Result: (Timestamp=%s, TotalRevenue=%.2f, TotalOrders=%d, PendingOrders=%d, SuccessfulOrders=%d, StatusCounts=%v, TopSellingBooks=%+v)",
+		report.Timestamp.Format(time.RFC3339),
+		report.TotalRevenue,
+		report.TotalOrders,
+		report.PendingOrders,
+		report.SuccessfulOrders,
+		report.StatusCounts,
+		report.TopSellingBooks,
+	)
+
+	// Save the report.
+	if _, err := reportStore.SaveSalesReport(ctx, report); err != nil {
+		h.Log.Printf("[Error] Failed to save sales report: %v", err)
+	}
+}
+
+// GetSalesReport handles GET /reports/sales by retrieving the stored sales reports.
+func (h *ReportHandler) GetSalesReport(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	reportStore := h.Reports
+
+	reports, err := reportStore.GetAllSalesReports(ctx)
+	if err != nil {
+		utils.WriteError(w, r, StructureData.InternalError("Failed to retrieve sales reports"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reports)
+}
@@ -1,35 +1,54 @@
 package Controllers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strconv"
 	"time"
 
+	interfaces "finalProject/Interfaces"
 	"finalProject/StructureData"
-	"finalProject/postgresStores"
+	"finalProject/auth"
+	"finalProject/utils"
 )
 
+// ReviewHandler serves the /reviews routes.
+type ReviewHandler struct {
+	Reviews interfaces.ReviewStore
+}
+
 // CreateReview handles POST /reviews.
-// It decodes the review input, sets CreatedAt to the current time,
-// then creates the review using the PostgreSQL ReviewStore.
-func CreateReview(w http.ResponseWriter, r *http.Request) {
-	reviewStore := postgresStores.GetPostgresReviewStoreInstance()
+// It decodes the review input, attributes it to the authenticated customer,
+// sets CreatedAt to the current time, then creates the review in the review store.
+func (h *ReviewHandler) CreateReview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	reviewStore := h.Reviews
 
 	var review StructureData.Review
 	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid review input"})
+		utils.WriteError(w, r, StructureData.ValidationError("Invalid review input"))
+		return
+	}
+	if review.Rating < 1 || review.Rating > 5 {
+		utils.WriteError(w, r, StructureData.FieldValidationError("rating", "Rating must be between 1 and 5"))
 		return
 	}
 
+	// The reviewer is always the authenticated customer, whatever the body says.
+	customerID, ok := auth.CustomerIDFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, r, StructureData.UnauthorizedError("Authentication required"))
+		return
+	}
+	review.CustomerID = customerID
+
 	// Overwrite CreatedAt with current time
 	review.CreatedAt = time.Now()
 
-	createdReview, errResp := reviewStore.CreateReview(review)
+	createdReview, errResp := reviewStore.CreateReview(ctx, review)
 	if errResp != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errResp)
+		utils.WriteError(w, r, errResp)
 		return
 	}
 
@@ -39,26 +58,24 @@ func CreateReview(w http.ResponseWriter, r *http.Request) {
 
 // GetReviewsByBook handles GET /reviews?book_id=1.
 // It retrieves all reviews for a given book.
-func GetReviewsByBook(w http.ResponseWriter, r *http.Request) {
-	reviewStore := postgresStores.GetPostgresReviewStoreInstance()
+func (h *ReviewHandler) GetReviewsByBook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	reviewStore := h.Reviews
 
 	bookIDStr := r.URL.Query().Get("book_id")
 	if bookIDStr == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "book_id is required"})
+		utils.WriteError(w, r, StructureData.FieldValidationError("book_id", "book_id is required"))
 		return
 	}
 	bookID, err := strconv.Atoi(bookIDStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid book_id"})
+		utils.WriteError(w, r, StructureData.FieldValidationError("book_id", "Invalid book_id"))
 		return
 	}
 
-	reviews, errResp := reviewStore.GetReviewsByBookID(bookID)
+	reviews, errResp := reviewStore.GetReviewsByBookID(ctx, bookID)
 	if errResp != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errResp)
+		utils.WriteError(w, r, errResp)
 		return
 	}
 
@@ -70,23 +87,35 @@ func GetReviewsByBook(w http.ResponseWriter, r *http.Request) {
 
 // DeleteReview handles DELETE /reviews/:id.
 // It deletes the review by ID.
-func DeleteReview(w http.ResponseWriter, r *http.Request) {
-	reviewStore := postgresStores.GetPostgresReviewStoreInstance()
+func (h *ReviewHandler) DeleteReview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	reviewStore := h.Reviews
 
 	idStr := r.URL.Path[len("/reviews/"):]
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StructureData.ErrorResponse{Message: "Invalid review ID"})
+		utils.WriteError(w, r, StructureData.FieldValidationError("id", "Invalid review ID"))
 		return
 	}
 
-	errResp := reviewStore.DeleteReview(id)
-	if errResp != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(errResp)
+	if _, errResp := reviewStore.GetReview(ctx, id); errResp != nil {
+		utils.WriteError(w, r, errResp)
+		return
+	}
+
+	if errResp := reviewStore.DeleteReview(ctx, id); errResp != nil {
+		utils.WriteError(w, r, errResp)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// ReviewOwnerID returns the ID of the customer who wrote the review with the given ID.
+func (h *ReviewHandler) ReviewOwnerID(ctx context.Context, id int) (int, bool) {
+	review, errResp := h.Reviews.GetReview(ctx, id)
+	if errResp != nil {
+		return 0, false
+	}
+	return review.CustomerID, true
+}
@@ -0,0 +1,33 @@
+package Controllers
+
+import (
+	"net/http"
+
+	"finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// searchFilter is one filter of a search request: its location in the request
+// body, as a prefix for error messages, and the fields it may test.
+type searchFilter struct {
+	path   string
+	filter *StructureData.Filter
+	fields map[string]string
+}
+
+// parseSearchFilters parses the filters of a search request, writing a validation
+// problem when one of them is invalid.
+func parseSearchFilters(w http.ResponseWriter, r *http.Request, filters ...searchFilter) bool {
+	for _, f := range filters {
+		if errResp := utils.ParseFilter(f.filter, f.fields); errResp != nil {
+			errResp.Message = f.path + errResp.Message
+			for i := range errResp.Fields {
+				errResp.Fields[i].Field = f.path + errResp.Fields[i].Field
+				errResp.Fields[i].Message = errResp.Message
+			}
+			utils.WriteError(w, r, errResp)
+			return false
+		}
+	}
+	return true
+}
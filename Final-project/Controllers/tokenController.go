@@ -1,61 +1,173 @@
 package Controllers
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
+	interfaces "finalProject/Interfaces"
 	"finalProject/StructureData"
 	"finalProject/auth"
-	postgresStores "finalProject/postgresStores"
+	"finalProject/utils"
 	"net/http"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 )
 
+// TokenHandler serves login, token refresh and logout. Tokens are signed by Issuer
+// and recorded in Tokens.
+type TokenHandler struct {
+	Customers interfaces.CustomerStore
+	Tokens    interfaces.TokenStore
+	Issuer    *auth.Issuer
+}
+
 type TokenRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
+// RefreshRequest carries the opaque refresh token for /token/refresh and /logout.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 // GenerateToken authenticates the user and generates a JWT token
-func GenerateToken(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	store := postgresStores.GetPostgresCustomerStoreInstance()
+func (h *TokenHandler) GenerateToken(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
 
 	var request TokenRequest
-	var user StructureData.Customer
 	// Decode JSON request body
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+		utils.WriteError(w, r, StructureData.ValidationError("invalid request body"))
 		return
 	}
 
-	// Query user from the database
-	query := "SELECT id, email, username, password FROM customers WHERE email = $1"
-	row := store.DB.QueryRow(query, request.Email)
-	err := row.Scan(&user.ID, &user.Email, &user.Username, &user.Password)
-	if err == sql.ErrNoRows {
-		http.Error(w, `{"error": "user not found"}`, http.StatusUnauthorized)
-		return
-	} else if err != nil {
-		http.Error(w, `{"error": "database error"}`, http.StatusInternalServerError)
+	// Look the user up by email
+	user, errResp := h.Customers.GetCustomerByEmail(ctx, request.Email)
+	if errResp != nil {
+		utils.WriteError(w, r, StructureData.UnauthorizedError("user not found"))
 		return
 	}
 
 	// Check password
 	if err := user.CheckPassword(request.Password); err != nil {
-		http.Error(w, `{"error": "invalid credentials"}`, http.StatusUnauthorized)
+		utils.WriteError(w, r, StructureData.UnauthorizedError("invalid credentials"))
 		return
 	}
 
-	// Generate JWT token
-	tokenString, err := auth.GenerateJWT(user.ID, user.Email, user.Username)
-	if err != nil {
-		http.Error(w, `{"error": "failed to generate token"}`, http.StatusInternalServerError)
+	// Generate the access/refresh token pair
+	pair, errResp := issueTokenPair(ctx, h.Issuer, h.Tokens, user)
+	if errResp != nil {
+		utils.WriteError(w, r, StructureData.InternalError("failed to generate token"))
 		return
 	}
 
 	// Send response
-	response, _ := json.Marshal(map[string]string{"token": tokenString})
+	response, _ := json.Marshal(pair)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write(response)
 }
+
+// RefreshToken exchanges a valid refresh token for a new token pair. The presented
+// refresh token is rotated: it cannot be used again.
+func (h *TokenHandler) RefreshToken(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+	tokenStore := h.Tokens
+	customerStore := h.Customers
+
+	var request RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.RefreshToken == "" {
+		utils.WriteError(w, r, StructureData.ValidationError("invalid request body"))
+		return
+	}
+
+	// Look up the owner before minting so the new access token carries current claims.
+	customerID, errResp := tokenStore.RefreshTokenOwner(ctx, auth.HashRefreshToken(request.RefreshToken))
+	if errResp != nil {
+		utils.WriteError(w, r, StructureData.UnauthorizedError("invalid refresh token"))
+		return
+	}
+	user, errResp := customerStore.GetCustomer(ctx, customerID)
+	if errResp != nil {
+		utils.WriteError(w, r, StructureData.UnauthorizedError("invalid refresh token"))
+		return
+	}
+
+	pair, err := h.Issuer.GenerateTokenPair(user.ID, user.Email, user.Username, user.Role)
+	if err != nil {
+		utils.WriteError(w, r, StructureData.InternalError("failed to generate token"))
+		return
+	}
+	if _, errResp := tokenStore.RotateRefreshToken(ctx,
+		auth.HashRefreshToken(request.RefreshToken),
+		auth.HashRefreshToken(pair.RefreshToken),
+		pair.AccessJTI,
+		pair.RefreshExpiresAt,
+		time.Now().Add(h.Issuer.AccessTokenTTL),
+	); errResp != nil {
+		utils.WriteError(w, r, errResp)
+		return
+	}
+
+	response, _ := json.Marshal(pair)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+// Logout revokes the caller's access token and, when given, its refresh token.
+// It must run behind the auth middleware so the caller's claims are in the context.
+func (h *TokenHandler) Logout(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+	tokenStore := h.Tokens
+
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		utils.WriteError(w, r, StructureData.UnauthorizedError("request does not contain an access token"))
+		return
+	}
+
+	var request RefreshRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			utils.WriteError(w, r, StructureData.ValidationError("invalid request body"))
+			return
+		}
+	}
+
+	if request.RefreshToken != "" {
+		if errResp := tokenStore.RevokeRefreshToken(ctx, claims.ID, auth.HashRefreshToken(request.RefreshToken)); errResp != nil {
+			utils.WriteError(w, r, errResp)
+			return
+		}
+	}
+
+	if claims.Id != "" {
+		if errResp := tokenStore.RevokeAccessToken(ctx, claims.Id, time.Unix(claims.ExpiresAt, 0)); errResp != nil {
+			utils.WriteError(w, r, errResp)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// issueTokenPair generates a token pair for user with issuer and records its
+// refresh token in tokens.
+func issueTokenPair(ctx context.Context, issuer *auth.Issuer, tokens interfaces.TokenStore, user StructureData.Customer) (auth.TokenPair, *StructureData.ErrorResponse) {
+	pair, err := issuer.GenerateTokenPair(user.ID, user.Email, user.Username, user.Role)
+	if err != nil {
+		return auth.TokenPair{}, StructureData.InternalError("Error generating JWT token")
+	}
+	errResp := tokens.SaveRefreshToken(ctx,
+		user.ID,
+		auth.HashRefreshToken(pair.RefreshToken),
+		pair.AccessJTI,
+		pair.RefreshExpiresAt,
+	)
+	if errResp != nil {
+		return auth.TokenPair{}, errResp
+	}
+	return pair, nil
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"finalProject/StructureData"
+	"finalProject/utils"
 	"net/http"
 
 	"github.com/julienschmidt/httprouter"
@@ -15,21 +16,21 @@ func RegisteredUser(db *sql.DB, w http.ResponseWriter, r *http.Request, _ httpro
 
 	// Decode JSON request body
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+		utils.WriteError(w, r, StructureData.ValidationError("invalid request body"))
 		return
 	}
 
 	// Hash the password
 	if err := user.HashPassword(user.Password); err != nil {
-		http.Error(w, `{"error": "failed to hash password"}`, http.StatusInternalServerError)
+		utils.WriteError(w, r, StructureData.InternalError("failed to hash password"))
 		return
 	}
 
 	// Insert user into the database
 	query := "INSERT INTO customers (email, username, password) VALUES ($1, $2, $3) RETURNING id"
-	err := db.QueryRow(query, user.Email, user.Username, user.Password).Scan(&user.ID)
+	err := db.QueryRowContext(r.Context(), query, user.Email, user.Username, user.Password).Scan(&user.ID)
 	if err != nil {
-		http.Error(w, `{"error": "failed to create user"}`, http.StatusInternalServerError)
+		utils.WriteError(w, r, StructureData.InternalError("failed to create user"))
 		return
 	}
 
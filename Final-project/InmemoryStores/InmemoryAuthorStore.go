@@ -2,6 +2,9 @@
 package InmemoryStores
 
 import (
+	"context"
+	"log"
+	"sort"
 	"sync"
 
 	interfaces "finalProject/Interfaces"
@@ -13,59 +16,89 @@ type InMemoryAuthorStore struct {
 	mu      sync.RWMutex
 	authors map[int]data.Author
 	nextID  int
+	// books and orders hold the books deleted along with their author and the
+	// orders that keep them from being deleted. A store that only caches authors
+	// has neither, and deletes just the author.
+	books  *InMemoryBookStore
+	orders *InMemoryOrderStore
+	// journal records the store's changes, if it is durable.
+	journal *Journal
 }
 
-var (
-	authorStoreInstance *InMemoryAuthorStore
-	authorOnce          sync.Once
-)
+var _ interfaces.AuthorStore = (*InMemoryAuthorStore)(nil)
 
-// GetAuthorStoreInstance returns the singleton instance of InMemoryAuthorStore.
-func GetAuthorStoreInstance() interfaces.AuthorStore {
-	authorOnce.Do(func() {
-		authorStoreInstance = &InMemoryAuthorStore{
-			authors: make(map[int]data.Author),
-			nextID:  1,
-		}
-	})
-	return authorStoreInstance
+// NewInMemoryAuthorStore returns an empty author store.
+func NewInMemoryAuthorStore() *InMemoryAuthorStore {
+	return &InMemoryAuthorStore{
+		authors: make(map[int]data.Author),
+		nextID:  1,
+	}
 }
 
 // CreateAuthor adds a new author to the store.
-func (store *InMemoryAuthorStore) CreateAuthor(author data.Author) (data.Author, *data.ErrorResponse) {
+func (store *InMemoryAuthorStore) CreateAuthor(ctx context.Context, author data.Author) (data.Author, *data.ErrorResponse) {
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
 	if author.ID != 0 {
 		if _, exists := store.authors[author.ID]; exists {
-			return data.Author{}, &data.ErrorResponse{Message: "Author ID already exists"}
-		}
-		store.authors[author.ID] = author
-		if author.ID >= store.nextID {
-			store.nextID = author.ID + 1
+			return data.Author{}, data.ConflictError("Author ID already exists")
 		}
 	} else {
 		author.ID = store.nextID
-		store.nextID++
-		store.authors[author.ID] = author
+	}
+	if errResp := store.journal.save(put(authorsTable, author.ID, author)); errResp != nil {
+		return data.Author{}, errResp
+	}
+	store.authors[author.ID] = author
+	if author.ID >= store.nextID {
+		store.nextID = author.ID + 1
 	}
 	return author, nil
 }
 
+// AddAuthorDirectly stores author as given, replacing any author with the same ID.
+func (store *InMemoryAuthorStore) AddAuthorDirectly(ctx context.Context, author data.Author) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if errResp := store.journal.save(put(authorsTable, author.ID, author)); errResp != nil {
+		log.Printf("Author %d not stored: %s", author.ID, errResp.Message)
+		return
+	}
+	if author.ID >= store.nextID {
+		store.nextID = author.ID + 1
+	}
+	store.authors[author.ID] = author
+}
+
 // GetAuthor retrieves an author by ID.
-func (store *InMemoryAuthorStore) GetAuthor(id int) (data.Author, *data.ErrorResponse) {
+func (store *InMemoryAuthorStore) GetAuthor(ctx context.Context, id int) (data.Author, *data.ErrorResponse) {
 	store.mu.RLock()
 	defer store.mu.RUnlock()
 
 	author, exists := store.authors[id]
 	if !exists {
-		return data.Author{}, &data.ErrorResponse{Message: "Author not found"}
+		return data.Author{}, data.NotFoundError("Author not found")
 	}
 	return author, nil
 }
 
+// GetAuthorByDetails finds the author with exactly these names and bio.
+func (store *InMemoryAuthorStore) GetAuthorByDetails(ctx context.Context, firstName, lastName, bio string) (data.Author, *data.ErrorResponse) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	for _, author := range store.authors {
+		if author.FirstName == firstName && author.LastName == lastName && author.Bio == bio {
+			return author, nil
+		}
+	}
+	return data.Author{}, data.NotFoundError("Author not found")
+}
+
 // GetAllAuthors retrieves all authors.
-func (store *InMemoryAuthorStore) GetAllAuthors() []data.Author {
+func (store *InMemoryAuthorStore) GetAllAuthors(ctx context.Context) []data.Author {
 	store.mu.RLock()
 	defer store.mu.RUnlock()
 
@@ -76,64 +109,82 @@ func (store *InMemoryAuthorStore) GetAllAuthors() []data.Author {
 	return authors
 }
 
+// ListAuthors returns the page of authors selected by opts.
+func (store *InMemoryAuthorStore) ListAuthors(ctx context.Context, opts data.ListOptions) ([]data.Author, data.ListMeta, *data.ErrorResponse) {
+	authors, meta := utils.Paginate(store.GetAllAuthors(ctx), opts)
+	return authors, meta, nil
+}
+
 // UpdateAuthor updates an author's details.
-func (store *InMemoryAuthorStore) UpdateAuthor(id int, author data.Author) (data.Author, *data.ErrorResponse) {
+func (store *InMemoryAuthorStore) UpdateAuthor(ctx context.Context, id int, author data.Author) (data.Author, *data.ErrorResponse) {
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
 	_, exists := store.authors[id]
 	if !exists {
-		return data.Author{}, &data.ErrorResponse{Message: "Author not found"}
+		return data.Author{}, data.NotFoundError("Author not found")
 	}
 	author.ID = id
+	if errResp := store.journal.save(put(authorsTable, id, author)); errResp != nil {
+		return data.Author{}, errResp
+	}
 	store.authors[id] = author
 	return author, nil
 }
 
-// DeleteAuthor removes an author by ID.
-func (store *InMemoryAuthorStore) DeleteAuthor(id int) *data.ErrorResponse {
+// DeleteAuthor removes an author by ID along with its books, unless an order
+// refers to any of them.
+func (store *InMemoryAuthorStore) DeleteAuthor(ctx context.Context, id int) *data.ErrorResponse {
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
 	_, exists := store.authors[id]
 	if !exists {
-		return &data.ErrorResponse{Message: "Author not found"}
+		return data.NotFoundError("Author not found")
+	}
+	var errResp *data.ErrorResponse
+	if store.books == nil {
+		errResp = store.journal.save(remove(authorsTable, id))
+	} else {
+		errResp = store.orders.withOrderedBooks(func(ordered map[int]bool) *data.ErrorResponse {
+			return store.books.deleteAuthorBooks(id, func(ids []int, books []mutation) *data.ErrorResponse {
+				for _, bookID := range ids {
+					if ordered[bookID] {
+						return data.ConflictError("Author has books linked to orders")
+					}
+				}
+				return store.journal.save(append(books, remove(authorsTable, id))...)
+			})
+		})
+	}
+	if errResp != nil {
+		return errResp
 	}
 	delete(store.authors, id)
 	return nil
 }
 
-// SearchAuthors filters authors based on the search criteria.
-func (store *InMemoryAuthorStore) SearchAuthors(criteria data.AuthorSearchCriteria) ([]data.Author, *data.ErrorResponse) {
+// SearchAuthors filters authors based on the search criteria. Results are ordered
+// by similarity for fuzzy criteria, and by ID otherwise.
+func (store *InMemoryAuthorStore) SearchAuthors(ctx context.Context, criteria data.AuthorSearchCriteria) ([]data.Author, *data.ErrorResponse) {
 	store.mu.RLock()
 	defer store.mu.RUnlock()
 
 	var result []data.Author
+	scores := map[int]float64{}
 	for _, author := range store.authors {
-		if len(criteria.IDs) > 0 && !utils.ContainsInt(criteria.IDs, author.ID) {
-			continue
-		}
-		if len(criteria.FirstNames) > 0 && !utils.ContainsString(criteria.FirstNames, author.FirstName) {
-			continue
-		}
-		if len(criteria.LastNames) > 0 && !utils.ContainsString(criteria.LastNames, author.LastName) {
+		score, ok := utils.AuthorScore(author, criteria)
+		if !ok {
 			continue
 		}
-		if len(criteria.Keywords) > 0 {
-			matched := false
-			for _, keyword := range criteria.Keywords {
-				if utils.ContainsIgnoreCase(author.FirstName, keyword) ||
-					utils.ContainsIgnoreCase(author.LastName, keyword) ||
-					utils.ContainsIgnoreCase(author.Bio, keyword) {
-					matched = true
-					break
-				}
-			}
-			if !matched {
-				continue
-			}
-		}
+		scores[author.ID] = score
 		result = append(result, author)
 	}
+	sort.Slice(result, func(i, j int) bool {
+		if scores[result[i].ID] != scores[result[j].ID] {
+			return scores[result[i].ID] > scores[result[j].ID]
+		}
+		return result[i].ID < result[j].ID
+	})
 	return result, nil
 }
@@ -2,6 +2,11 @@
 package InmemoryStores
 
 import (
+	"context"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 
 	interfaces "finalProject/Interfaces"
@@ -14,45 +19,42 @@ type InMemoryBookStore struct {
 	mu     sync.RWMutex
 	books  map[int]data.Book
 	nextID int
+	// journal records the store's changes, if it is durable.
+	journal *Journal
 }
 
-var (
-	bookStoreInstance *InMemoryBookStore
-	bookOnce          sync.Once
-)
+var _ interfaces.BookStore = (*InMemoryBookStore)(nil)
 
-// GetBookStoreInstance returns the singleton instance of InMemoryBookStore.
-func GetBookStoreInstance() interfaces.BookStore {
-	bookOnce.Do(func() {
-		bookStoreInstance = &InMemoryBookStore{
-			books:  make(map[int]data.Book),
-			nextID: 1,
-		}
-	})
-	return bookStoreInstance
+// NewInMemoryBookStore returns an empty book store.
+func NewInMemoryBookStore() *InMemoryBookStore {
+	return &InMemoryBookStore{
+		books:  make(map[int]data.Book),
+		nextID: 1,
+	}
 }
 
 // CreateBook adds a new book to the store.
-func (store *InMemoryBookStore) CreateBook(book data.Book) (data.Book, *data.ErrorResponse) {
+func (store *InMemoryBookStore) CreateBook(ctx context.Context, book data.Book) (data.Book, *data.ErrorResponse) {
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
 	if book.Stock < 1 {
-		return data.Book{}, &data.ErrorResponse{Message: "Book stock must be at least 1"}
+		return data.Book{}, data.FieldValidationError("stock", "Book stock must be at least 1")
 	}
 
 	if book.ID != 0 {
 		if _, exists := store.books[book.ID]; exists {
-			return data.Book{}, &data.ErrorResponse{Message: "Book ID already exists"}
-		}
-		store.books[book.ID] = book
-		if book.ID >= store.nextID {
-			store.nextID = book.ID + 1
+			return data.Book{}, data.ConflictError("Book ID already exists")
 		}
 	} else {
 		book.ID = store.nextID
-		store.nextID++
-		store.books[book.ID] = book
+	}
+	if errResp := store.journal.save(put(booksTable, book.ID, book)); errResp != nil {
+		return data.Book{}, errResp
+	}
+	store.books[book.ID] = book
+	if book.ID >= store.nextID {
+		store.nextID = book.ID + 1
 	}
 	return book, nil
 }
@@ -61,46 +63,52 @@ func (store *InMemoryBookStore) CreateBook(book data.Book) (data.Book, *data.Err
 
 
 // GetBook retrieves a book by its ID
-func (store *InMemoryBookStore) GetBook(id int) (data.Book, *data.ErrorResponse) {
+func (store *InMemoryBookStore) GetBook(ctx context.Context, id int) (data.Book, *data.ErrorResponse) {
 	store.mu.RLock()
 	defer store.mu.RUnlock()
 
 	book, exists := store.books[id]
 	if !exists {
-		return data.Book{}, &data.ErrorResponse{Message: "Book not found"}
+		return data.Book{}, data.NotFoundError("Book not found")
 	}
 	return book, nil
 }
 
 // UpdateBook updates the details of an existing book
-func (store *InMemoryBookStore) UpdateBook(id int, book data.Book) (data.Book, *data.ErrorResponse) {
+func (store *InMemoryBookStore) UpdateBook(ctx context.Context, id int, book data.Book) (data.Book, *data.ErrorResponse) {
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
 	_, exists := store.books[id]
 	if !exists {
-		return data.Book{}, &data.ErrorResponse{Message: "Book not found"}
+		return data.Book{}, data.NotFoundError("Book not found")
 	}
 	book.ID = id
+	if errResp := store.journal.save(put(booksTable, id, book)); errResp != nil {
+		return data.Book{}, errResp
+	}
 	store.books[id] = book
 	return book, nil
 }
 
 // DeleteBook removes a book from the store
-func (store *InMemoryBookStore) DeleteBook(id int) *data.ErrorResponse {
+func (store *InMemoryBookStore) DeleteBook(ctx context.Context, id int) *data.ErrorResponse {
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
 	_, exists := store.books[id]
 	if !exists {
-		return &data.ErrorResponse{Message: "Book not found"}
+		return data.NotFoundError("Book not found")
+	}
+	if errResp := store.journal.save(remove(booksTable, id)); errResp != nil {
+		return errResp
 	}
 	delete(store.books, id)
 	return nil
 }
 
 // GetAllBooks retrieves all books
-func (store *InMemoryBookStore) GetAllBooks() []data.Book {
+func (store *InMemoryBookStore) GetAllBooks(ctx context.Context) []data.Book {
 	store.mu.RLock()
 	defer store.mu.RUnlock()
 
@@ -111,7 +119,13 @@ func (store *InMemoryBookStore) GetAllBooks() []data.Book {
 	return books
 }
 
-func (store *InMemoryBookStore) SearchBooks(criteria data.BookSearchCriteria) ([]data.Book, *data.ErrorResponse) {
+// ListBooks returns the page of books selected by opts.
+func (store *InMemoryBookStore) ListBooks(ctx context.Context, opts data.ListOptions) ([]data.Book, data.ListMeta, *data.ErrorResponse) {
+	books, meta := utils.Paginate(store.GetAllBooks(ctx), opts)
+	return books, meta, nil
+}
+
+func (store *InMemoryBookStore) SearchBooks(ctx context.Context, criteria data.BookSearchCriteria) ([]data.Book, *data.ErrorResponse) {
 	store.mu.RLock()
 	defer store.mu.RUnlock()
 
@@ -121,6 +135,9 @@ func (store *InMemoryBookStore) SearchBooks(criteria data.BookSearchCriteria) ([
 		if len(criteria.IDs) > 0 && !utils.ContainsInt(criteria.IDs, book.ID) {
 			continue
 		}
+		if !utils.MatchFilter(criteria.Filter, book) {
+			continue
+		}
 		if len(criteria.Titles) > 0 && !utils.ContainsString(criteria.Titles, book.Title) {
 			continue
 		}
@@ -176,10 +193,153 @@ func (store *InMemoryBookStore) SearchBooks(criteria data.BookSearchCriteria) ([
 	return result, nil
 }
 
-func (store *InMemoryBookStore) AddBookDirectly(book data.Book) {
+// SearchBookFacets counts the books matching criteria by each facet named in
+// criteria.Facets.
+func (store *InMemoryBookStore) SearchBookFacets(ctx context.Context, criteria data.BookSearchCriteria) (data.BookFacets, *data.ErrorResponse) {
+	books, errResp := store.SearchBooks(ctx, criteria)
+	if errResp != nil {
+		return data.BookFacets{}, errResp
+	}
+
+	var facets data.BookFacets
+	for _, facet := range criteria.Facets {
+		switch facet {
+		case data.FacetGenre:
+			counts := map[string]int{}
+			for _, book := range books {
+				for _, genre := range book.Genres {
+					counts[genre]++
+				}
+			}
+			facets.Genres = byCount(counts, nil)
+		case data.FacetAuthor:
+			counts := map[string]int{}
+			labels := map[string]string{}
+			for _, book := range books {
+				key := strconv.Itoa(book.Author.ID)
+				counts[key]++
+				labels[key] = book.Author.FullName()
+			}
+			facets.Authors = byCount(counts, labels)
+		case data.FacetPrice:
+			counts := make([]int, len(data.PriceBandLimits)+1)
+			for _, book := range books {
+				counts[data.PriceBandIndex(book.Price)]++
+			}
+			for i, count := range counts {
+				if count > 0 {
+					facets.PriceBands = append(facets.PriceBands, data.FacetCount{Key: data.PriceBand(i), Count: count})
+				}
+			}
+		case data.FacetRating:
+			counts := make([]int, 6)
+			for _, book := range books {
+				stars := 0
+				if book.ReviewStats != nil {
+					stars = min(int(book.ReviewStats.AverageRating), 5)
+				}
+				counts[stars]++
+			}
+			for stars, count := range counts {
+				if count > 0 {
+					facets.Ratings = append(facets.Ratings, data.FacetCount{Key: strconv.Itoa(stars), Count: count})
+				}
+			}
+		}
+	}
+	return facets, nil
+}
+
+// byCount turns counts per key into facet counts, most frequent first.
+func byCount(counts map[string]int, labels map[string]string) []data.FacetCount {
+	facet := make([]data.FacetCount, 0, len(counts))
+	for key, count := range counts {
+		facet = append(facet, data.FacetCount{Key: key, Label: labels[key], Count: count})
+	}
+	sort.Slice(facet, func(i, j int) bool {
+		if facet[i].Count != facet[j].Count {
+			return facet[i].Count > facet[j].Count
+		}
+		return facet[i].Key < facet[j].Key
+	})
+	return facet
+}
+
+// Weights of the fields searched by TextSearchBooks, matching the default weights
+// Postgres gives to the A, B and C labels of books.search_vector.
+const (
+	titleWeight  = 1.0
+	authorWeight = 0.4
+	bioWeight    = 0.2
+)
+
+// TextSearchBooks ranks the books matching every word of query.Query as a word
+// prefix. It mirrors the Postgres full-text search without stemming.
+func (store *InMemoryBookStore) TextSearchBooks(ctx context.Context, query data.BookTextQuery) ([]data.BookSearchHit, data.ListMeta, *data.ErrorResponse) {
+	terms := utils.SearchTerms(query.Query)
+
+	store.mu.RLock()
+	var hits []data.BookSearchHit
+	for _, book := range store.books {
+		fields := []struct {
+			text   string
+			weight float64
+		}{
+			{book.Title, titleWeight},
+			{book.Author.FullName(), authorWeight},
+			{strings.Join(book.Genres, " "), authorWeight},
+			{book.Author.Bio, bioWeight},
+		}
+		score := 0.0
+		matchedAll := true
+		for _, term := range terms {
+			matched := false
+			for _, field := range fields {
+				if n := utils.CountMatches(field.text, []string{term}); n > 0 {
+					score += float64(n) * field.weight
+					matched = true
+				}
+			}
+			if !matched {
+				matchedAll = false
+				break
+			}
+		}
+		if !matchedAll {
+			continue
+		}
+		hits = append(hits, data.BookSearchHit{
+			Book:  book,
+			Score: score,
+			Highlights: data.BookHighlights{
+				Title:   utils.HighlightTerms(book.Title, terms),
+				Snippet: utils.Snippet(book.Author.Bio, terms, 30),
+			},
+		})
+	}
+	store.mu.RUnlock()
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Book.ID < hits[j].Book.ID
+	})
+	meta := data.ListMeta{Total: len(hits), Limit: query.Limit, Offset: query.Offset}
+	start := min(query.Offset, len(hits))
+	end := min(start+query.Limit, len(hits))
+	return append([]data.BookSearchHit{}, hits[start:end]...), meta, nil
+}
+
+// AddBookDirectly stores book as given, replacing any book with the same ID.
+func (store *InMemoryBookStore) AddBookDirectly(ctx context.Context, book data.Book) {
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
+	if errResp := store.journal.save(put(booksTable, book.ID, book)); errResp != nil {
+		log.Printf("Book %d not stored: %s", book.ID, errResp.Message)
+		return
+	}
 	// Ensure the next ID is updated to prevent ID collisions
 	if book.ID >= store.nextID {
 		store.nextID = book.ID + 1
@@ -187,3 +347,161 @@ func (store *InMemoryBookStore) AddBookDirectly(book data.Book) {
 
 	store.books[book.ID] = book
 }
+
+// setReviewStats replaces the review stats of a book, if it still exists. commit
+// is handed the change to the book, if any, before it is made, and can save it
+// along with its own; if commit fails, the book is left as it was.
+func (store *InMemoryBookStore) setReviewStats(id int, stats data.BookReviewAggregate, commit func(book []mutation) *data.ErrorResponse) *data.ErrorResponse {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	book, exists := store.books[id]
+	if !exists {
+		return commit(nil)
+	}
+	book.ReviewStats = &stats
+	if errResp := commit([]mutation{put(booksTable, id, book)}); errResp != nil {
+		return errResp
+	}
+	store.books[id] = book
+	return nil
+}
+
+// deleteAuthorBooks removes the books of an author. commit is handed the IDs of
+// the books and the changes removing them before they are made, and can refuse
+// them or save them along with its own; if commit fails, the books are left as
+// they were.
+func (store *InMemoryBookStore) deleteAuthorBooks(authorID int, commit func(ids []int, books []mutation) *data.ErrorResponse) *data.ErrorResponse {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	var ids []int
+	var changes []mutation
+	for id, book := range store.books {
+		if book.Author.ID == authorID {
+			ids = append(ids, id)
+			changes = append(changes, remove(booksTable, id))
+		}
+	}
+	if errResp := commit(ids, changes); errResp != nil {
+		return errResp
+	}
+	for _, id := range ids {
+		delete(store.books, id)
+	}
+	return nil
+}
+
+// moveStock returns the quantities held by returned to stock and reserves stock for
+// items, as one step. Items that cannot be reserved are rejected for the same
+// reasons as in PostgreSQL; if data.CheckRejections refuses the result under
+// allowPartial, no stock moves at all. Reserved items capture the book's current
+// title, price and author. It returns the reserved and rejected items and the new
+// stock of every book it touched.
+//
+// Before any stock moves, commit is handed the reserved items and the changes to
+// their books, so that it can save them along with the order; if commit fails, no
+// stock moves either.
+func (store *InMemoryBookStore) moveStock(returned, items []data.OrderItem, allowPartial bool, commit func(reserved []data.OrderItem, stock []mutation) *data.ErrorResponse) ([]data.OrderItem, []data.OrderItemRejection, data.StockLevels, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	// Work on a copy of the stock so a refused order leaves the store untouched.
+	levels := store.returnedStock(returned)
+	stock := func(book data.Book) int {
+		if level, ok := levels[book.ID]; ok {
+			return level
+		}
+		return book.Stock
+	}
+
+	var reserved []data.OrderItem
+	var rejected []data.OrderItemRejection
+	for _, item := range items {
+		book, exists := store.books[item.Book.ID]
+		rejection := data.OrderItemRejection{BookID: item.Book.ID, Quantity: item.Quantity}
+		switch {
+		case item.Quantity < 1:
+			rejection.Reason = data.RejectionInvalidQuantity
+		case !exists:
+			rejection.Reason = data.RejectionNotFound
+		case stock(book) < item.Quantity:
+			available := stock(book)
+			rejection.Reason = data.RejectionInsufficientStock
+			rejection.Available = &available
+		default:
+			levels[book.ID] = stock(book) - item.Quantity
+			book.Stock = levels[book.ID]
+			item.Book = book
+			item.UnitPrice = book.Price
+			item.BookTitle = book.Title
+			item.AuthorName = book.Author.FullName()
+			reserved = append(reserved, item)
+			continue
+		}
+		rejected = append(rejected, rejection)
+	}
+	if errResp := data.CheckRejections(reserved, rejected, allowPartial); errResp != nil {
+		return nil, nil, nil, errResp
+	}
+	if errResp := commit(reserved, store.stockMutations(levels)); errResp != nil {
+		return nil, nil, nil, errResp
+	}
+
+	store.setStock(levels)
+	return reserved, rejected, levels, nil
+}
+
+// returnStock adds the quantities held by items back to their books and returns
+// the new stock levels. Books that have been deleted are skipped. As with
+// moveStock, commit is handed the changes to the books first, and if it fails the
+// stock is left as it was.
+func (store *InMemoryBookStore) returnStock(items []data.OrderItem, commit func(stock []mutation) *data.ErrorResponse) (data.StockLevels, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	levels := store.returnedStock(items)
+	if errResp := commit(store.stockMutations(levels)); errResp != nil {
+		return nil, errResp
+	}
+	store.setStock(levels)
+	return levels, nil
+}
+
+// returnedStock computes the stock of the books of items once their quantities are
+// returned, without changing the store. store.mu must be held.
+func (store *InMemoryBookStore) returnedStock(items []data.OrderItem) data.StockLevels {
+	levels := data.StockLevels{}
+	for _, item := range items {
+		book, exists := store.books[item.Book.ID]
+		if !exists {
+			continue
+		}
+		if _, ok := levels[book.ID]; !ok {
+			levels[book.ID] = book.Stock
+		}
+		levels[book.ID] += item.Quantity
+	}
+	return levels
+}
+
+// stockMutations returns the changes that set the stock of the books in levels.
+// store.mu must be held.
+func (store *InMemoryBookStore) stockMutations(levels data.StockLevels) []mutation {
+	var mutations []mutation
+	for id, stock := range levels {
+		book := store.books[id]
+		book.Stock = stock
+		mutations = append(mutations, put(booksTable, id, book))
+	}
+	return mutations
+}
+
+// setStock sets the stock of the books in levels. store.mu must be held.
+func (store *InMemoryBookStore) setStock(levels data.StockLevels) {
+	for id, stock := range levels {
+		book := store.books[id]
+		book.Stock = stock
+		store.books[id] = book
+	}
+}
@@ -0,0 +1,132 @@
+package InmemoryStores
+
+import (
+	"context"
+	"testing"
+
+	data "finalProject/StructureData"
+)
+
+// textSearchStore returns a book store with books that match "hob" in different
+// fields, so their ranking shows the weight of each field.
+func textSearchStore(t *testing.T) *InMemoryBookStore {
+	t.Helper()
+	ctx := context.Background()
+	tolkien := data.Author{ID: 1, FirstName: "J.R.R.", LastName: "Tolkien", Bio: "Professor at Oxford and author of The Hobbit and The Lord of the Rings."}
+	hobson := data.Author{ID: 2, FirstName: "Mary", LastName: "Hobson", Bio: "Writes about gardens."}
+	plain := data.Author{ID: 3, FirstName: "Ann", LastName: "Smith", Bio: "Has a hobby of hiking."}
+
+	store := NewInMemoryBookStore()
+	for _, book := range []data.Book{
+		{ID: 1, Title: "The Hobbit", Author: tolkien, Genres: []string{"Fantasy"}},
+		{ID: 2, Title: "Gardens", Author: hobson, Genres: []string{"Nature"}},
+		{ID: 3, Title: "Mountains", Author: plain, Genres: []string{"Travel"}},
+		{ID: 4, Title: "The Silmarillion", Author: tolkien, Genres: []string{"Fantasy"}},
+		{ID: 5, Title: "Cooking", Author: data.Author{ID: 4, FirstName: "Bo", LastName: "Chef"}, Genres: []string{"Food"}},
+	} {
+		store.AddBookDirectly(ctx, book)
+	}
+	return store
+}
+
+func TestTextSearchBooksRanking(t *testing.T) {
+	store := textSearchStore(t)
+	tests := []struct {
+		name  string
+		query string
+		want  []int
+	}{
+		// Title (1.0) + bio (0.2) > author name (0.4) > bio (0.2); ties by ID.
+		{"fields are weighted", "hob", []int{1, 2, 3, 4}},
+		{"every word must match", "hob tolk", []int{1, 4}},
+		{"words match as prefixes only", "obbit", nil},
+		{"genres count like the author", "fantasy", []int{1, 4}},
+		{"case is ignored", "SILMA", []int{4}},
+	}
+	for _, tt := range tests {
+		hits, meta, errResp := store.TextSearchBooks(context.Background(), data.BookTextQuery{Query: tt.query, Limit: 50})
+		if errResp != nil {
+			t.Fatalf("%s: %s", tt.name, errResp.Message)
+		}
+		var got []int
+		for _, hit := range hits {
+			got = append(got, hit.Book.ID)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got books %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got books %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+		if meta.Total != len(tt.want) {
+			t.Errorf("%s: total = %d, want %d", tt.name, meta.Total, len(tt.want))
+		}
+	}
+}
+
+func TestTextSearchBooksScores(t *testing.T) {
+	store := textSearchStore(t)
+	hits, _, _ := store.TextSearchBooks(context.Background(), data.BookTextQuery{Query: "hob", Limit: 50})
+	want := map[int]float64{
+		1: titleWeight + bioWeight,
+		2: authorWeight,
+		3: bioWeight,
+		4: bioWeight,
+	}
+	for _, hit := range hits {
+		if diff := hit.Score - want[hit.Book.ID]; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("book %d scored %g, want %g", hit.Book.ID, hit.Score, want[hit.Book.ID])
+		}
+	}
+}
+
+func TestTextSearchBooksHighlights(t *testing.T) {
+	store := textSearchStore(t)
+	hits, _, _ := store.TextSearchBooks(context.Background(), data.BookTextQuery{Query: "hob", Limit: 1})
+	if len(hits) != 1 {
+		t.Fatalf("got %d hits, want 1", len(hits))
+	}
+	highlights := hits[0].Highlights
+	if want := "The <mark>Hobbit</mark>"; highlights.Title != want {
+		t.Errorf("title highlight = %q, want %q", highlights.Title, want)
+	}
+	if want := "author of The <mark>Hobbit</mark> and The Lord of the Rings"; highlights.Snippet != want {
+		t.Errorf("snippet = %q, want %q", highlights.Snippet, want)
+	}
+}
+
+func TestTextSearchBooksPaging(t *testing.T) {
+	store := textSearchStore(t)
+	tests := []struct {
+		limit, offset int
+		want          []int
+	}{
+		{2, 0, []int{1, 2}},
+		{2, 2, []int{3, 4}},
+		{2, 3, []int{4}},
+		{2, 4, nil},
+		{50, 10, nil},
+	}
+	for _, tt := range tests {
+		hits, meta, errResp := store.TextSearchBooks(context.Background(), data.BookTextQuery{Query: "hob", Limit: tt.limit, Offset: tt.offset})
+		if errResp != nil {
+			t.Fatal(errResp.Message)
+		}
+		if meta.Total != 4 || meta.Limit != tt.limit || meta.Offset != tt.offset {
+			t.Errorf("limit %d offset %d: meta = %+v", tt.limit, tt.offset, meta)
+		}
+		if len(hits) != len(tt.want) {
+			t.Errorf("limit %d offset %d: got %d hits, want %v", tt.limit, tt.offset, len(hits), tt.want)
+			continue
+		}
+		for i, hit := range hits {
+			if hit.Book.ID != tt.want[i] {
+				t.Errorf("limit %d offset %d: hit %d is book %d, want %d", tt.limit, tt.offset, i, hit.Book.ID, tt.want[i])
+			}
+		}
+	}
+}
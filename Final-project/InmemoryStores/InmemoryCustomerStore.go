@@ -1,6 +1,9 @@
 package InmemoryStores
 
 import (
+	"context"
+	"log"
+	"sort"
 	"sync"
 	"time"
 
@@ -13,26 +16,22 @@ type InMemoryCustomerStore struct {
 	mu        sync.RWMutex
 	customers map[int]data.Customer
 	nextID    int
+	// journal records the store's changes, if it is durable.
+	journal *Journal
 }
 
-var (
-	customerStoreInstance *InMemoryCustomerStore
-	customerOnce          sync.Once
-)
+var _ interfaces.CustomerStore = (*InMemoryCustomerStore)(nil)
 
-// GetCustomerStoreInstance returns the singleton instance of InMemoryCustomerStore
-func GetCustomerStoreInstance() interfaces.CustomerStore {
-	customerOnce.Do(func() {
-		customerStoreInstance = &InMemoryCustomerStore{
-			customers: make(map[int]data.Customer),
-			nextID:    1,
-		}
-	})
-	return customerStoreInstance
+// NewInMemoryCustomerStore returns an empty customer store.
+func NewInMemoryCustomerStore() *InMemoryCustomerStore {
+	return &InMemoryCustomerStore{
+		customers: make(map[int]data.Customer),
+		nextID:    1,
+	}
 }
 
 // CreateCustomer adds a new customer to the store
-func (store *InMemoryCustomerStore) CreateCustomer(customer data.Customer) (data.Customer, *data.ErrorResponse) {
+func (store *InMemoryCustomerStore) CreateCustomer(ctx context.Context, customer data.Customer) (data.Customer, *data.ErrorResponse) {
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
@@ -42,35 +41,64 @@ func (store *InMemoryCustomerStore) CreateCustomer(customer data.Customer) (data
 	if customer.ID != 0 {
 		// Use the provided ID (for example, when coming from PostgreSQL)
 		if _, exists := store.customers[customer.ID]; exists {
-			return data.Customer{}, &data.ErrorResponse{Message: "Customer ID already exists"}
-		}
-		store.customers[customer.ID] = customer
-		if customer.ID >= store.nextID {
-			store.nextID = customer.ID + 1
+			return data.Customer{}, data.ConflictError("Customer ID already exists")
 		}
 	} else {
 		// Otherwise assign a new ID.
 		customer.ID = store.nextID
-		store.nextID++
-		store.customers[customer.ID] = customer
+	}
+	if errResp := store.journal.save(put(customersTable, customer.ID, storedCustomer(customer))); errResp != nil {
+		return data.Customer{}, errResp
+	}
+	store.customers[customer.ID] = customer
+	if customer.ID >= store.nextID {
+		store.nextID = customer.ID + 1
 	}
 	return customer, nil
 }
 
+// AddCustomerDirectly stores customer as given, replacing any customer with the same ID.
+func (store *InMemoryCustomerStore) AddCustomerDirectly(ctx context.Context, customer data.Customer) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if errResp := store.journal.save(put(customersTable, customer.ID, storedCustomer(customer))); errResp != nil {
+		log.Printf("Customer %d not stored: %s", customer.ID, errResp.Message)
+		return
+	}
+	if customer.ID >= store.nextID {
+		store.nextID = customer.ID + 1
+	}
+	store.customers[customer.ID] = customer
+}
+
 // GetCustomer retrieves a customer by its ID
-func (store *InMemoryCustomerStore) GetCustomer(id int) (data.Customer, *data.ErrorResponse) {
+func (store *InMemoryCustomerStore) GetCustomer(ctx context.Context, id int) (data.Customer, *data.ErrorResponse) {
 	store.mu.RLock()
 	defer store.mu.RUnlock()
 
 	customer, exists := store.customers[id]
 	if !exists {
-		return data.Customer{}, &data.ErrorResponse{Message: "Customer not found"}
+		return data.Customer{}, data.NotFoundError("Customer not found")
 	}
 	return customer, nil
 }
 
+// GetCustomerByEmail retrieves the customer with the given email.
+func (store *InMemoryCustomerStore) GetCustomerByEmail(ctx context.Context, email string) (data.Customer, *data.ErrorResponse) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	for _, customer := range store.customers {
+		if customer.Email == email {
+			return customer, nil
+		}
+	}
+	return data.Customer{}, data.NotFoundError("Customer not found")
+}
+
 // GetAllCustomers retrieves all customers
-func (store *InMemoryCustomerStore) GetAllCustomers() []data.Customer {
+func (store *InMemoryCustomerStore) GetAllCustomers(ctx context.Context) []data.Customer {
 	store.mu.RLock()
 	defer store.mu.RUnlock()
 
@@ -81,46 +109,75 @@ func (store *InMemoryCustomerStore) GetAllCustomers() []data.Customer {
 	return customers
 }
 
-// UpdateCustomer updates the details of an existing customer
-func (store *InMemoryCustomerStore) UpdateCustomer(id int, customer data.Customer) (data.Customer, *data.ErrorResponse) {
+// ListCustomers returns the page of customers selected by opts.
+func (store *InMemoryCustomerStore) ListCustomers(ctx context.Context, opts data.ListOptions) ([]data.Customer, data.ListMeta, *data.ErrorResponse) {
+	customers, meta := utils.Paginate(store.GetAllCustomers(ctx), opts)
+	return customers, meta, nil
+}
+
+// UpdateCustomer updates the details of an existing customer. As in PostgreSQL,
+// the role, password and creation time are kept.
+func (store *InMemoryCustomerStore) UpdateCustomer(ctx context.Context, id int, customer data.Customer) (data.Customer, *data.ErrorResponse) {
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
-	_, exists := store.customers[id]
+	existing, exists := store.customers[id]
 	if !exists {
-		return data.Customer{}, &data.ErrorResponse{Message: "Customer not found"}
+		return data.Customer{}, data.NotFoundError("Customer not found")
 	}
 	customer.ID = id
+	customer.Role = existing.Role
+	customer.Password = existing.Password
+	customer.CreatedAt = existing.CreatedAt
+	if errResp := store.journal.save(put(customersTable, id, storedCustomer(customer))); errResp != nil {
+		return data.Customer{}, errResp
+	}
 	store.customers[id] = customer
 	return customer, nil
 }
 
 // DeleteCustomer removes a customer from the store
-func (store *InMemoryCustomerStore) DeleteCustomer(id int) *data.ErrorResponse {
+func (store *InMemoryCustomerStore) DeleteCustomer(ctx context.Context, id int) *data.ErrorResponse {
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
 	_, exists := store.customers[id]
 	if !exists {
-		return &data.ErrorResponse{Message: "Customer not found"}
+		return data.NotFoundError("Customer not found")
+	}
+	if errResp := store.journal.save(remove(customersTable, id)); errResp != nil {
+		return errResp
 	}
 	delete(store.customers, id)
 	return nil
 }
 
-// SearchCustomers filters customers based on the search criteria
-func (store *InMemoryCustomerStore) SearchCustomers(criteria data.CustomerSearchCriteria) ([]data.Customer, *data.ErrorResponse) {
+// SearchCustomers filters customers based on the search criteria. Results are
+// ordered by name similarity for fuzzy criteria, and by ID otherwise.
+func (store *InMemoryCustomerStore) SearchCustomers(ctx context.Context, criteria data.CustomerSearchCriteria) ([]data.Customer, *data.ErrorResponse) {
 	store.mu.RLock()
 	defer store.mu.RUnlock()
 
 	var result []data.Customer
+	scores := map[int]float64{}
 	for _, customer := range store.customers {
 		if len(criteria.IDs) > 0 && !utils.ContainsInt(criteria.IDs, customer.ID) {
 			continue
 		}
-		if len(criteria.Names) > 0 && !utils.ContainsString(criteria.Names, customer.Name) {
+		if !utils.MatchFilter(criteria.Filter, customer) {
 			continue
 		}
+		if len(criteria.Names) > 0 {
+			if criteria.Fuzzy {
+				score, ok := utils.FuzzyScore(criteria.Names, customer.Name)
+				if !ok {
+					continue
+				}
+				scores[customer.ID] = score
+			} else if !utils.ContainsString(criteria.Names, customer.Name) {
+				continue
+			}
+		}
 		if len(criteria.Emails) > 0 && !utils.ContainsString(criteria.Emails, customer.Email) {
 			continue
 		}
@@ -135,6 +192,12 @@ func (store *InMemoryCustomerStore) SearchCustomers(criteria data.CustomerSearch
 		}
 		result = append(result, customer)
 	}
+	sort.Slice(result, func(i, j int) bool {
+		if scores[result[i].ID] != scores[result[j].ID] {
+			return scores[result[i].ID] > scores[result[j].ID]
+		}
+		return result[i].ID < result[j].ID
+	})
 	return result, nil
 }
 
@@ -2,9 +2,12 @@
 package InmemoryStores
 
 import (
+	"context"
+	"log"
 	"sync"
 	"time"
 
+	interfaces "finalProject/Interfaces"
 	data "finalProject/StructureData"
 	"finalProject/utils"
 )
@@ -13,39 +16,39 @@ type InMemoryOrderStore struct {
 	mu     sync.RWMutex
 	orders map[int]data.Order
 	nextID int
+	// books holds the stock the orders take their items from.
+	books *InMemoryBookStore
+	// history holds the status changes of each order, oldest first.
+	history      map[int][]data.OrderStatusChange
+	nextChangeID int
+	// journal records the store's changes, if it is durable.
+	journal *Journal
 }
 
 var (
-	orderStoreInstance *InMemoryOrderStore
-	orderOnce          sync.Once
+	_ interfaces.OrderStore     = (*InMemoryOrderStore)(nil)
+	_ interfaces.OrderProcessor = (*InMemoryOrderStore)(nil)
 )
 
-// GetOrderStoreInstance returns the singleton instance of InMemoryOrderStore.
-func GetOrderStoreInstance() *InMemoryOrderStore {
-	orderOnce.Do(func() {
-		orderStoreInstance = &InMemoryOrderStore{
-			orders: make(map[int]data.Order),
-			nextID: 1,
-		}
-	})
-	return orderStoreInstance
+// NewInMemoryOrderStore returns an empty order store whose items come from books.
+func NewInMemoryOrderStore(books *InMemoryBookStore) *InMemoryOrderStore {
+	return &InMemoryOrderStore{
+		orders:       make(map[int]data.Order),
+		nextID:       1,
+		books:        books,
+		history:      make(map[int][]data.OrderStatusChange),
+		nextChangeID: 1,
+	}
 }
 
 // CreateOrder adds a new order to the store.
-func (store *InMemoryOrderStore) CreateOrder(order data.Order) (data.Order, *data.ErrorResponse) {
+func (store *InMemoryOrderStore) CreateOrder(ctx context.Context, order data.Order) (data.Order, *data.ErrorResponse) {
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
-	// Calculate total price.
-	totalPrice := 0.0
-	for i, item := range order.Items {
-		bookStore := GetBookStoreInstance()
-		book, err := bookStore.GetBook(item.Book.ID)
-		if err != nil {
-			return data.Order{}, &data.ErrorResponse{Message: "Book not found for item in order"}
-		}
-		order.Items[i].Book = book
-		totalPrice += book.Price * float64(item.Quantity)
+	totalPrice, errResp := store.priceOrderItems(ctx, order.Items)
+	if errResp != nil {
+		return data.Order{}, errResp
 	}
 	order.TotalPrice = totalPrice
 	order.CreatedAt = time.Now()
@@ -54,84 +57,129 @@ func (store *InMemoryOrderStore) CreateOrder(order data.Order) (data.Order, *dat
 	if order.ID != 0 {
 		// Check if already exists.
 		if _, exists := store.orders[order.ID]; exists {
-			return data.Order{}, &data.ErrorResponse{Message: "Order ID already exists"}
-		}
-		store.orders[order.ID] = order
-		if order.ID >= store.nextID {
-			store.nextID = order.ID + 1
+			return data.Order{}, data.ConflictError("Order ID already exists")
 		}
 	} else {
 		order.ID = store.nextID
-		store.nextID++
-		store.orders[order.ID] = order
+	}
+	if errResp := store.journal.save(put(ordersTable, order.ID, order)); errResp != nil {
+		return data.Order{}, errResp
+	}
+	store.orders[order.ID] = order
+	if order.ID >= store.nextID {
+		store.nextID = order.ID + 1
 	}
 	return order, nil
 }
 
-// (The other methods remain unchanged.)
+// AddOrderDirectly stores order as given, replacing any order with the same ID.
+func (store *InMemoryOrderStore) AddOrderDirectly(ctx context.Context, order data.Order) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
 
+	if errResp := store.journal.save(put(ordersTable, order.ID, order)); errResp != nil {
+		log.Printf("Order %d not stored: %s", order.ID, errResp.Message)
+		return
+	}
+	if order.ID >= store.nextID {
+		store.nextID = order.ID + 1
+	}
+	store.orders[order.ID] = order
+}
+
+// priceOrderItems fills in the current book details of each item and returns the
+// order total. Items that do not carry a purchase-time snapshot yet are priced from
+// the current book; items that do keep their snapshot, even if the book is gone.
+func (store *InMemoryOrderStore) priceOrderItems(ctx context.Context, items []data.OrderItem) (float64, *data.ErrorResponse) {
+	totalPrice := 0.0
+	for i, item := range items {
+		book, err := store.books.GetBook(ctx, item.Book.ID)
+		if err == nil {
+			items[i].Book = book
+		}
+		if item.BookTitle == "" {
+			if err != nil {
+				return 0, data.FieldValidationError("items", "Book not found for item in order")
+			}
+			items[i].UnitPrice = book.Price
+			items[i].BookTitle = book.Title
+			items[i].AuthorName = book.Author.FullName()
+		}
+		totalPrice += items[i].Subtotal()
+	}
+	return totalPrice, nil
+}
 
 // GetOrder retrieves an order by its ID
-func (store *InMemoryOrderStore) GetOrder(id int) (data.Order, *data.ErrorResponse) {
+func (store *InMemoryOrderStore) GetOrder(ctx context.Context, id int) (data.Order, *data.ErrorResponse) {
 	store.mu.RLock()
 	defer store.mu.RUnlock()
 
 	order, exists := store.orders[id]
 	if !exists {
-		return data.Order{}, &data.ErrorResponse{Message: "Order not found"}
+		return data.Order{}, data.NotFoundError("Order not found")
 	}
 	return order, nil
 }
 
 // UpdateOrder updates the details of an existing order
-// UpdateOrder updates the details of an existing order
-func (store *InMemoryOrderStore) UpdateOrder(id int, order data.Order) (data.Order, *data.ErrorResponse) {
-    store.mu.Lock()
-    defer store.mu.Unlock()
-
-    _, exists := store.orders[id]
-    if !exists {
-        return data.Order{}, &data.ErrorResponse{Message: "Order not found"}
-    }
-
-    // Calculate the total price of the order
-    totalPrice := 0.0
-    for i, item := range order.Items {
-        // Ensure the book exists and fetch its details
-        bookStore := GetBookStoreInstance()
-        book, err := bookStore.GetBook(item.Book.ID)
-        if err != nil {
-            return data.Order{}, &data.ErrorResponse{Message: "Book not found for item in order"}
-        }
-        // Update the book details in the order
-        order.Items[i].Book = book
-
-        // Calculate price * quantity and add to total
-        totalPrice += book.Price * float64(item.Quantity)
-    }
-
-    order.TotalPrice = totalPrice // Set the calculated total price
-    order.ID = id
-    store.orders[id] = order
-    return order, nil
-}
+func (store *InMemoryOrderStore) UpdateOrder(ctx context.Context, id int, order data.Order) (data.Order, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
 
+	_, exists := store.orders[id]
+	if !exists {
+		return data.Order{}, data.NotFoundError("Order not found")
+	}
+
+	totalPrice, errResp := store.priceOrderItems(ctx, order.Items)
+	if errResp != nil {
+		return data.Order{}, errResp
+	}
+
+	order.TotalPrice = totalPrice // Set the calculated total price
+	order.ID = id
+	if errResp := store.journal.save(put(ordersTable, id, order)); errResp != nil {
+		return data.Order{}, errResp
+	}
+	store.orders[id] = order
+	return order, nil
+}
 
 // DeleteOrder removes an order from the store
-func (store *InMemoryOrderStore) DeleteOrder(id int) *data.ErrorResponse {
+func (store *InMemoryOrderStore) DeleteOrder(ctx context.Context, id int) *data.ErrorResponse {
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
 	_, exists := store.orders[id]
 	if !exists {
-		return &data.ErrorResponse{Message: "Order not found"}
+		return data.NotFoundError("Order not found")
+	}
+	if errResp := store.journal.save(remove(ordersTable, id), remove(orderHistoryTable, id)); errResp != nil {
+		return errResp
 	}
 	delete(store.orders, id)
+	delete(store.history, id)
 	return nil
 }
 
+// withOrderedBooks calls f with the IDs of the books that orders refer to, while
+// no order can be placed or changed.
+func (store *InMemoryOrderStore) withOrderedBooks(f func(ordered map[int]bool) *data.ErrorResponse) *data.ErrorResponse {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	ordered := make(map[int]bool)
+	for _, order := range store.orders {
+		for _, item := range order.Items {
+			ordered[item.Book.ID] = true
+		}
+	}
+	return f(ordered)
+}
+
 // GetAllOrders retrieves all orders from the store
-func (store *InMemoryOrderStore) GetAllOrders() []data.Order {
+func (store *InMemoryOrderStore) GetAllOrders(ctx context.Context) []data.Order {
 	store.mu.RLock()
 	defer store.mu.RUnlock()
 
@@ -142,8 +190,14 @@ func (store *InMemoryOrderStore) GetAllOrders() []data.Order {
 	return orders
 }
 
+// ListOrders returns the page of orders selected by opts.
+func (store *InMemoryOrderStore) ListOrders(ctx context.Context, opts data.ListOptions) ([]data.Order, data.ListMeta, *data.ErrorResponse) {
+	orders, meta := utils.Paginate(store.GetAllOrders(ctx), opts)
+	return orders, meta, nil
+}
+
 // SearchOrders filters orders based on the search criteria
-func (store *InMemoryOrderStore) SearchOrders(criteria data.OrderSearchCriteria) ([]data.Order, *data.ErrorResponse) {
+func (store *InMemoryOrderStore) SearchOrders(ctx context.Context, criteria data.OrderSearchCriteria) ([]data.Order, *data.ErrorResponse) {
 	store.mu.RLock()
 	defer store.mu.RUnlock()
 
@@ -152,6 +206,9 @@ func (store *InMemoryOrderStore) SearchOrders(criteria data.OrderSearchCriteria)
 		if len(criteria.IDs) > 0 && !utils.ContainsInt(criteria.IDs, order.ID) {
 			continue
 		}
+		if !utils.MatchFilter(criteria.Filter, order) {
+			continue
+		}
 		if len(criteria.CustomerIDs) > 0 && !utils.ContainsInt(criteria.CustomerIDs, order.Customer.ID) {
 			continue
 		}
@@ -167,7 +224,7 @@ func (store *InMemoryOrderStore) SearchOrders(criteria data.OrderSearchCriteria)
 		if !criteria.MaxCreatedAt.IsZero() && order.CreatedAt.After(criteria.MaxCreatedAt) {
 			continue
 		}
-		
+
 		if !matchOrderItems(order.Items, criteria.ItemCriteria) {
 			continue
 		}
@@ -199,6 +256,9 @@ func matchBookCriteria(book data.Book, criteria data.BookSearchCriteria) bool {
 	if len(criteria.IDs) > 0 && !utils.ContainsInt(criteria.IDs, book.ID) {
 		return false
 	}
+	if !utils.MatchFilter(criteria.Filter, book) {
+		return false
+	}
 	if len(criteria.Titles) > 0 && !utils.ContainsString(criteria.Titles, book.Title) {
 		return false
 	}
@@ -222,15 +282,160 @@ func matchBookCriteria(book data.Book, criteria data.BookSearchCriteria) bool {
 	}
 	return true
 }
-func (store *InMemoryOrderStore) GetOrdersInTimeRange(start, end time.Time) ([]data.Order, error) {
+
+// GetOrdersInTimeRange retrieves the orders created between start and end
+// inclusive.
+func (store *InMemoryOrderStore) GetOrdersInTimeRange(ctx context.Context, start, end time.Time) ([]data.Order, error) {
 	store.mu.RLock()
 	defer store.mu.RUnlock()
 
 	var filteredOrders []data.Order
 	for _, order := range store.orders {
-		if order.CreatedAt.After(start) && order.CreatedAt.Before(end) {
+		if !order.CreatedAt.Before(start) && !order.CreatedAt.After(end) {
 			filteredOrders = append(filteredOrders, order)
 		}
 	}
 	return filteredOrders, nil
 }
+
+// PlaceOrder reserves stock for the order's items and stores the order, under the
+// same rules as the PostgreSQL order store: the order is refused if any item
+// cannot be supplied, unless allowPartial leaves those items out.
+func (store *InMemoryOrderStore) PlaceOrder(ctx context.Context, order data.Order, allowPartial bool) (data.Order, data.StockLevels, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	_, rejected, levels, errResp := store.books.moveStock(nil, order.Items, allowPartial, func(items []data.OrderItem, stock []mutation) *data.ErrorResponse {
+		order.Items = items
+		order.RejectedItems = nil
+		order.TotalPrice = data.ItemsTotal(items)
+		order.ID = store.nextID
+		return store.journal.save(append(stock, put(ordersTable, order.ID, order))...)
+	})
+	if errResp != nil {
+		return data.Order{}, nil, errResp
+	}
+	store.nextID++
+	store.orders[order.ID] = order
+
+	order.RejectedItems = rejected
+	return order, levels, nil
+}
+
+// ReplaceOrder returns the stock held by order id and reserves stock for the new
+// items in one step, then overwrites the order. Only pending orders can be
+// replaced.
+func (store *InMemoryOrderStore) ReplaceOrder(ctx context.Context, id int, order data.Order, allowPartial bool) (data.Order, data.StockLevels, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	existing, errResp := store.modifiableOrder(id)
+	if errResp != nil {
+		return data.Order{}, nil, errResp
+	}
+	_, rejected, levels, errResp := store.books.moveStock(existing.Items, order.Items, allowPartial, func(items []data.OrderItem, stock []mutation) *data.ErrorResponse {
+		order.ID = id
+		order.Items = items
+		order.RejectedItems = nil
+		order.TotalPrice = data.ItemsTotal(items)
+		return store.journal.save(append(stock, put(ordersTable, id, order))...)
+	})
+	if errResp != nil {
+		return data.Order{}, nil, errResp
+	}
+	store.orders[id] = order
+
+	order.RejectedItems = rejected
+	return order, levels, nil
+}
+
+// RemoveOrder deletes order id and returns its items to stock. Only pending orders
+// can be removed.
+func (store *InMemoryOrderStore) RemoveOrder(ctx context.Context, id int) (data.StockLevels, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	existing, errResp := store.modifiableOrder(id)
+	if errResp != nil {
+		return nil, errResp
+	}
+	levels, errResp := store.books.returnStock(existing.Items, func(stock []mutation) *data.ErrorResponse {
+		return store.journal.save(append(stock, remove(ordersTable, id), remove(orderHistoryTable, id))...)
+	})
+	if errResp != nil {
+		return nil, errResp
+	}
+	delete(store.orders, id)
+	delete(store.history, id)
+	return levels, nil
+}
+
+// modifiableOrder returns order id if it is still pending, the only status in
+// which its items may change. store.mu must be held.
+func (store *InMemoryOrderStore) modifiableOrder(id int) (data.Order, *data.ErrorResponse) {
+	order, exists := store.orders[id]
+	if !exists {
+		return data.Order{}, data.ErrOrderNotFound
+	}
+	if order.Status != data.OrderStatusPending {
+		return data.Order{}, data.ErrOrderLocked
+	}
+	return order, nil
+}
+
+// TransitionOrder moves order id to status to and records the change in the
+// order's status history. Cancelling an order returns its items to stock. actorID
+// is the customer making the change, or 0 if it is not known.
+func (store *InMemoryOrderStore) TransitionOrder(ctx context.Context, id int, to string, actorID int, note string) (data.OrderStatusChange, data.StockLevels, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	order, exists := store.orders[id]
+	if !exists {
+		return data.OrderStatusChange{}, nil, data.ErrOrderNotFound
+	}
+	if !data.CanTransitionOrder(order.Status, to) {
+		return data.OrderStatusChange{}, nil, data.ErrInvalidTransition
+	}
+
+	change := data.OrderStatusChange{
+		ID:         store.nextChangeID,
+		OrderID:    id,
+		FromStatus: order.Status,
+		ToStatus:   to,
+		ChangedAt:  time.Now(),
+		Note:       note,
+	}
+	if actorID != 0 {
+		change.ChangedBy = &actorID
+	}
+	history := append(append([]data.OrderStatusChange(nil), store.history[id]...), change)
+	order.Status = to
+	mutations := []mutation{put(ordersTable, id, order), put(orderHistoryTable, id, history)}
+
+	levels := data.StockLevels{}
+	var errResp *data.ErrorResponse
+	if to == data.OrderStatusCancelled {
+		levels, errResp = store.books.returnStock(order.Items, func(stock []mutation) *data.ErrorResponse {
+			return store.journal.save(append(stock, mutations...)...)
+		})
+	} else {
+		errResp = store.journal.save(mutations...)
+	}
+	if errResp != nil {
+		return data.OrderStatusChange{}, nil, errResp
+	}
+
+	store.nextChangeID++
+	store.history[id] = history
+	store.orders[id] = order
+	return change, levels, nil
+}
+
+// GetOrderHistory returns the status changes of order id, oldest first.
+func (store *InMemoryOrderStore) GetOrderHistory(ctx context.Context, id int) ([]data.OrderStatusChange, *data.ErrorResponse) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	return append([]data.OrderStatusChange{}, store.history[id]...), nil
+}
@@ -0,0 +1,138 @@
+package InmemoryStores
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+// InMemoryReviewStore keeps reviews in memory. Like the PostgreSQL review store,
+// it keeps the review stats of each book up to date as its reviews change.
+type InMemoryReviewStore struct {
+	mu      sync.RWMutex
+	reviews map[int]data.Review
+	nextID  int
+	// books holds the books the reviews are about.
+	books *InMemoryBookStore
+	// journal records the store's changes, if it is durable.
+	journal *Journal
+}
+
+var _ interfaces.ReviewStore = (*InMemoryReviewStore)(nil)
+
+// NewInMemoryReviewStore returns an empty review store for the books in books.
+func NewInMemoryReviewStore(books *InMemoryBookStore) *InMemoryReviewStore {
+	return &InMemoryReviewStore{
+		reviews: make(map[int]data.Review),
+		nextID:  1,
+		books:   books,
+	}
+}
+
+// CreateReview adds a review of an existing book.
+func (store *InMemoryReviewStore) CreateReview(ctx context.Context, review data.Review) (data.Review, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, errResp := store.books.GetBook(ctx, review.BookID); errResp != nil {
+		return data.Review{}, data.FieldValidationError("book_id", "Book %d not found", review.BookID)
+	}
+
+	if review.ID != 0 {
+		if _, exists := store.reviews[review.ID]; exists {
+			return data.Review{}, data.ConflictError("Review ID already exists")
+		}
+	} else {
+		review.ID = store.nextID
+	}
+	if errResp := store.saveReview(review, false); errResp != nil {
+		return data.Review{}, errResp
+	}
+	if review.ID >= store.nextID {
+		store.nextID = review.ID + 1
+	}
+	return review, nil
+}
+
+// GetReview retrieves a review by its ID.
+func (store *InMemoryReviewStore) GetReview(ctx context.Context, id int) (data.Review, *data.ErrorResponse) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	review, exists := store.reviews[id]
+	if !exists {
+		return data.Review{}, data.NotFoundError("Review not found")
+	}
+	return review, nil
+}
+
+// GetReviewsByBookID retrieves all reviews of a book, most recent first.
+func (store *InMemoryReviewStore) GetReviewsByBookID(ctx context.Context, bookID int) ([]data.Review, *data.ErrorResponse) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	var reviews []data.Review
+	for _, review := range store.reviews {
+		if review.BookID == bookID {
+			reviews = append(reviews, review)
+		}
+	}
+	sort.Slice(reviews, func(i, j int) bool {
+		if !reviews[i].CreatedAt.Equal(reviews[j].CreatedAt) {
+			return reviews[i].CreatedAt.After(reviews[j].CreatedAt)
+		}
+		return reviews[i].ID > reviews[j].ID
+	})
+	return reviews, nil
+}
+
+// DeleteReview removes a review.
+func (store *InMemoryReviewStore) DeleteReview(ctx context.Context, id int) *data.ErrorResponse {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	review, exists := store.reviews[id]
+	if !exists {
+		return data.NotFoundError("Review not found")
+	}
+	return store.saveReview(review, true)
+}
+
+// saveReview adds review to the store, or deletes it if deleted is set, and
+// recomputes the review stats of its book from its reviews. store.mu must be held.
+func (store *InMemoryReviewStore) saveReview(review data.Review, deleted bool) *data.ErrorResponse {
+	var stats data.BookReviewAggregate
+	total := 0
+	for _, other := range store.reviews {
+		if other.BookID == review.BookID && other.ID != review.ID {
+			stats.ReviewCount++
+			total += other.Rating
+		}
+	}
+	change := put(reviewsTable, review.ID, review)
+	if deleted {
+		change = remove(reviewsTable, review.ID)
+	} else {
+		stats.ReviewCount++
+		total += review.Rating
+	}
+	if stats.ReviewCount > 0 {
+		stats.AverageRating = float64(total) / float64(stats.ReviewCount)
+	}
+
+	errResp := store.books.setReviewStats(review.BookID, stats, func(book []mutation) *data.ErrorResponse {
+		return store.journal.save(append(book, change)...)
+	})
+	if errResp != nil {
+		return errResp
+	}
+	if deleted {
+		delete(store.reviews, review.ID)
+	} else {
+		store.reviews[review.ID] = review
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package InmemoryStores
+
+import (
+	"context"
+	"sync"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+// InMemorySalesReportStore keeps generated sales reports in memory, oldest first.
+type InMemorySalesReportStore struct {
+	mu      sync.RWMutex
+	reports []data.SalesReport
+	// journal records the store's changes, if it is durable.
+	journal *Journal
+}
+
+var _ interfaces.SalesReportStore = (*InMemorySalesReportStore)(nil)
+
+// NewInMemorySalesReportStore returns an empty sales report store.
+func NewInMemorySalesReportStore() *InMemorySalesReportStore {
+	return &InMemorySalesReportStore{}
+}
+
+// SaveSalesReport appends a sales report.
+func (store *InMemorySalesReportStore) SaveSalesReport(ctx context.Context, report data.SalesReport) (*data.SalesReport, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if errResp := store.journal.save(put(salesReportsTable, len(store.reports), report)); errResp != nil {
+		return nil, errResp
+	}
+	store.reports = append(store.reports, report)
+	return &report, nil
+}
+
+// GetAllSalesReports returns every saved sales report.
+func (store *InMemorySalesReportStore) GetAllSalesReports(ctx context.Context) ([]data.SalesReport, *data.ErrorResponse) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	return append([]data.SalesReport(nil), store.reports...), nil
+}
@@ -0,0 +1,208 @@
+package InmemoryStores
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+// refreshToken is an issued refresh token, kept by hash.
+type refreshToken struct {
+	CustomerID int       `json:"customer_id"`
+	AccessJTI  string    `json:"access_jti"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Revoked    bool      `json:"revoked"`
+}
+
+// InMemoryTokenStore keeps refresh tokens and revoked access token IDs (jti) in
+// memory, with the same rotation and reuse rules as the PostgreSQL token store.
+type InMemoryTokenStore struct {
+	mu            sync.Mutex
+	refreshTokens map[string]*refreshToken
+	// revoked maps the jti of each revoked access token to its expiry.
+	revoked map[string]time.Time
+	// journal records the store's changes, if it is durable.
+	journal *Journal
+}
+
+var _ interfaces.TokenStore = (*InMemoryTokenStore)(nil)
+
+// NewInMemoryTokenStore returns an empty token store.
+func NewInMemoryTokenStore() *InMemoryTokenStore {
+	return &InMemoryTokenStore{
+		refreshTokens: make(map[string]*refreshToken),
+		revoked:       make(map[string]time.Time),
+	}
+}
+
+// SaveRefreshToken records a newly issued refresh token (by hash) for a customer,
+// along with the jti of the access token issued alongside it.
+func (store *InMemoryTokenStore) SaveRefreshToken(ctx context.Context, customerID int, tokenHash string, accessJTI string, expiresAt time.Time) *data.ErrorResponse {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, exists := store.refreshTokens[tokenHash]; exists {
+		return &data.ErrorResponse{Message: "Failed to save refresh token: duplicate token"}
+	}
+	token := refreshToken{CustomerID: customerID, AccessJTI: accessJTI, ExpiresAt: expiresAt}
+	if errResp := store.journal.save(put(refreshTokensTable, tokenHash, token)); errResp != nil {
+		return errResp
+	}
+	store.refreshTokens[tokenHash] = &token
+	return nil
+}
+
+// RefreshTokenOwner returns the ID of the customer a refresh token was issued to.
+func (store *InMemoryTokenStore) RefreshTokenOwner(ctx context.Context, tokenHash string) (int, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	token, exists := store.refreshTokens[tokenHash]
+	if !exists {
+		return 0, data.UnauthorizedError("Invalid refresh token")
+	}
+	return token.CustomerID, nil
+}
+
+// RotateRefreshToken revokes the refresh token identified by oldHash and stores its
+// replacement. The access token issued with the old refresh token is revoked until
+// revokeAccessUntil. Presenting a refresh token that was already rotated or revoked
+// revokes every outstanding refresh token of its customer.
+func (store *InMemoryTokenStore) RotateRefreshToken(ctx context.Context, oldHash string, newHash string, newAccessJTI string, newExpiresAt time.Time, revokeAccessUntil time.Time) (int, *data.ErrorResponse) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	old, exists := store.refreshTokens[oldHash]
+	if !exists {
+		return 0, data.UnauthorizedError("Invalid refresh token")
+	}
+	if old.Revoked {
+		var revoked []mutation
+		for hash, token := range store.refreshTokens {
+			if token.CustomerID == old.CustomerID && !token.Revoked {
+				revokedToken := *token
+				revokedToken.Revoked = true
+				revoked = append(revoked, put(refreshTokensTable, hash, revokedToken))
+			}
+		}
+		if errResp := store.journal.save(revoked...); errResp != nil {
+			return 0, errResp
+		}
+		for _, token := range store.refreshTokens {
+			if token.CustomerID == old.CustomerID {
+				token.Revoked = true
+			}
+		}
+		log.Printf("Refresh token reuse detected for customer %d; all refresh tokens revoked", old.CustomerID)
+		return 0, data.UnauthorizedError("Refresh token has been revoked")
+	}
+	if time.Now().After(old.ExpiresAt) {
+		return 0, data.UnauthorizedError("Refresh token expired")
+	}
+
+	replacement := refreshToken{CustomerID: old.CustomerID, AccessJTI: newAccessJTI, ExpiresAt: newExpiresAt}
+	revokedOld := *old
+	revokedOld.Revoked = true
+	mutations := []mutation{put(refreshTokensTable, newHash, replacement), put(refreshTokensTable, oldHash, revokedOld)}
+	if _, exists := store.revoked[old.AccessJTI]; old.AccessJTI != "" && !exists {
+		mutations = append(mutations, put(revokedTokensTable, old.AccessJTI, revokeAccessUntil))
+	}
+	if errResp := store.journal.save(mutations...); errResp != nil {
+		return 0, errResp
+	}
+
+	store.refreshTokens[newHash] = &replacement
+	old.Revoked = true
+	if old.AccessJTI != "" {
+		store.revokeJTI(old.AccessJTI, revokeAccessUntil)
+	}
+	return old.CustomerID, nil
+}
+
+// RevokeRefreshToken revokes the refresh token identified by tokenHash if it belongs
+// to customerID.
+func (store *InMemoryTokenStore) RevokeRefreshToken(ctx context.Context, customerID int, tokenHash string) *data.ErrorResponse {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	token, exists := store.refreshTokens[tokenHash]
+	if !exists || token.CustomerID != customerID || token.Revoked {
+		return data.FieldValidationError("refresh_token", "Refresh token not found")
+	}
+	revokedToken := *token
+	revokedToken.Revoked = true
+	if errResp := store.journal.save(put(refreshTokensTable, tokenHash, revokedToken)); errResp != nil {
+		return errResp
+	}
+	token.Revoked = true
+	return nil
+}
+
+// RevokeAccessToken adds an access token's jti to the revocation list until expiresAt.
+func (store *InMemoryTokenStore) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) *data.ErrorResponse {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, exists := store.revoked[jti]; exists {
+		return nil
+	}
+	if errResp := store.journal.save(put(revokedTokensTable, jti, expiresAt)); errResp != nil {
+		return errResp
+	}
+	store.revokeJTI(jti, expiresAt)
+	return nil
+}
+
+// revokeJTI revokes an access token until expiresAt. A token that is already
+// revoked keeps its entry. store.mu must be held.
+func (store *InMemoryTokenStore) revokeJTI(jti string, expiresAt time.Time) {
+	if _, exists := store.revoked[jti]; !exists {
+		store.revoked[jti] = expiresAt
+	}
+}
+
+// IsAccessTokenRevoked reports whether the access token with the given jti was revoked.
+func (store *InMemoryTokenStore) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	_, revoked := store.revoked[jti]
+	return revoked, nil
+}
+
+// PurgeExpiredTokens drops refresh tokens and revocation entries that have expired.
+func (store *InMemoryTokenStore) PurgeExpiredTokens(ctx context.Context) *data.ErrorResponse {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := time.Now()
+	var expired []mutation
+	for jti, expiresAt := range store.revoked {
+		if expiresAt.Before(now) {
+			expired = append(expired, remove(revokedTokensTable, jti))
+		}
+	}
+	for hash, token := range store.refreshTokens {
+		if token.ExpiresAt.Before(now) {
+			expired = append(expired, remove(refreshTokensTable, hash))
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+	if errResp := store.journal.save(expired...); errResp != nil {
+		return errResp
+	}
+	for _, m := range expired {
+		if m.Table == revokedTokensTable {
+			delete(store.revoked, m.Key)
+		} else {
+			delete(store.refreshTokens, m.Key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package InmemoryStores
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	data "finalProject/StructureData"
+)
+
+// Stores are the in-memory stores that serve the whole API when no database is
+// used. Orders and reviews refer to the books of Books, and deleting an author
+// deletes its books from Books.
+type Stores struct {
+	Books     *InMemoryBookStore
+	Authors   *InMemoryAuthorStore
+	Customers *InMemoryCustomerStore
+	Orders    *InMemoryOrderStore
+	Reviews   *InMemoryReviewStore
+	Reports   *InMemorySalesReportStore
+	Tokens    *InMemoryTokenStore
+}
+
+// NewStores returns a set of empty stores.
+func NewStores() Stores {
+	books := NewInMemoryBookStore()
+	orders := NewInMemoryOrderStore(books)
+	authors := NewInMemoryAuthorStore()
+	authors.books, authors.orders = books, orders
+	return Stores{
+		Books:     books,
+		Authors:   authors,
+		Customers: NewInMemoryCustomerStore(),
+		Orders:    orders,
+		Reviews:   NewInMemoryReviewStore(books),
+		Reports:   NewInMemorySalesReportStore(),
+		Tokens:    NewInMemoryTokenStore(),
+	}
+}
+
+// Fixture files read by LoadFixtures, each a JSON array of records, in the order
+// they are loaded.
+const (
+	AuthorsFixture      = "authors.json"
+	BooksFixture        = "books.json"
+	CustomersFixture    = "customers.json"
+	OrdersFixture       = "orders.json"
+	ReviewsFixture      = "reviews.json"
+	SalesReportsFixture = "sales_reports.json"
+)
+
+// LoadFixtures seeds the stores from the fixture files in dir; missing files are
+// skipped. Records keep the IDs they are given and get the next free one
+// otherwise, and references to other records (the author of a book, the customer
+// and books of an order, the book of a review) are by ID and must resolve.
+// Customer passwords may be given in clear text; they are hashed. Orders are
+// recorded as they are: they take no stock from their books.
+func (s Stores) LoadFixtures(ctx context.Context, dir string) error {
+	var authors []data.Author
+	if err := readFixture(dir, AuthorsFixture, &authors); err != nil {
+		return err
+	}
+	for _, author := range authors {
+		if _, errResp := s.Authors.CreateAuthor(ctx, author); errResp != nil {
+			return fixtureError(AuthorsFixture, author.ID, errResp.Message)
+		}
+	}
+
+	var books []data.Book
+	if err := readFixture(dir, BooksFixture, &books); err != nil {
+		return err
+	}
+	for _, book := range books {
+		author, errResp := s.Authors.GetAuthor(ctx, book.Author.ID)
+		if errResp != nil {
+			return fixtureError(BooksFixture, book.ID, fmt.Sprintf("author %d: %s", book.Author.ID, errResp.Message))
+		}
+		book.Author = author
+		if book.CreatedAt.IsZero() {
+			book.CreatedAt = time.Now()
+		}
+		if _, errResp := s.Books.CreateBook(ctx, book); errResp != nil {
+			return fixtureError(BooksFixture, book.ID, errResp.Message)
+		}
+	}
+
+	var customers []data.Customer
+	if err := readFixture(dir, CustomersFixture, &customers); err != nil {
+		return err
+	}
+	for _, customer := range customers {
+		if customer.Password == "" {
+			return fixtureError(CustomersFixture, customer.ID, "password is required")
+		}
+		if !isPasswordHash(customer.Password) {
+			if err := customer.HashPassword(customer.Password); err != nil {
+				return fixtureError(CustomersFixture, customer.ID, err.Error())
+			}
+		}
+		if customer.Role == "" {
+			customer.Role = data.RoleUser
+		}
+		created, errResp := s.Customers.CreateCustomer(ctx, customer)
+		if errResp != nil {
+			return fixtureError(CustomersFixture, customer.ID, errResp.Message)
+		}
+		if !customer.CreatedAt.IsZero() {
+			created.CreatedAt = customer.CreatedAt
+			s.Customers.AddCustomerDirectly(ctx, created)
+		}
+	}
+
+	var orders []data.Order
+	if err := readFixture(dir, OrdersFixture, &orders); err != nil {
+		return err
+	}
+	for _, order := range orders {
+		customer, errResp := s.Customers.GetCustomer(ctx, order.Customer.ID)
+		if errResp != nil {
+			return fixtureError(OrdersFixture, order.ID, fmt.Sprintf("customer %d: %s", order.Customer.ID, errResp.Message))
+		}
+		order.Customer = customer
+		if order.Status == "" {
+			order.Status = data.OrderStatusPending
+		}
+		if !data.IsOrderStatus(order.Status) {
+			return fixtureError(OrdersFixture, order.ID, fmt.Sprintf("unknown status %q", order.Status))
+		}
+		created, errResp := s.Orders.CreateOrder(ctx, order)
+		if errResp != nil {
+			return fixtureError(OrdersFixture, order.ID, errResp.Message)
+		}
+		if !order.CreatedAt.IsZero() {
+			created.CreatedAt = order.CreatedAt
+			s.Orders.AddOrderDirectly(ctx, created)
+		}
+	}
+
+	var reviews []data.Review
+	if err := readFixture(dir, ReviewsFixture, &reviews); err != nil {
+		return err
+	}
+	for _, review := range reviews {
+		if review.CreatedAt.IsZero() {
+			review.CreatedAt = time.Now()
+		}
+		if _, errResp := s.Reviews.CreateReview(ctx, review); errResp != nil {
+			return fixtureError(ReviewsFixture, review.ID, errResp.Message)
+		}
+	}
+
+	var reports []data.SalesReport
+	if err := readFixture(dir, SalesReportsFixture, &reports); err != nil {
+		return err
+	}
+	for _, report := range reports {
+		if _, errResp := s.Reports.SaveSalesReport(ctx, report); errResp != nil {
+			return fixtureError(SalesReportsFixture, 0, errResp.Message)
+		}
+	}
+
+	log.Printf("Loaded fixtures from %s: %d authors, %d books, %d customers, %d orders, %d reviews, %d sales reports",
+		dir, len(authors), len(books), len(customers), len(orders), len(reviews), len(reports))
+	return nil
+}
+
+// readFixture decodes the fixture file name in dir into records, leaving them
+// empty when the file does not exist.
+func readFixture(dir, name string, records interface{}) error {
+	raw, err := os.ReadFile(filepath.Join(dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading fixture: %w", err)
+	}
+	if err := json.Unmarshal(raw, records); err != nil {
+		return fmt.Errorf("parsing fixture %s: %w", name, err)
+	}
+	return nil
+}
+
+// fixtureError reports a record of a fixture file that could not be loaded.
+func fixtureError(name string, id int, message string) error {
+	if id == 0 {
+		return fmt.Errorf("fixture %s: %s", name, message)
+	}
+	return fmt.Errorf("fixture %s: record %d: %s", name, id, message)
+}
+
+// isPasswordHash reports whether password is already a bcrypt hash.
+func isPasswordHash(password string) bool {
+	return strings.HasPrefix(password, "$2a$") || strings.HasPrefix(password, "$2b$") || strings.HasPrefix(password, "$2y$")
+}
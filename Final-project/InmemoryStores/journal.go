@@ -0,0 +1,468 @@
+package InmemoryStores
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	data "finalProject/StructureData"
+)
+
+// Tables of the records a journal holds.
+const (
+	authorsTable       = "authors"
+	booksTable         = "books"
+	customersTable     = "customers"
+	ordersTable        = "orders"
+	orderHistoryTable  = "order_history"
+	reviewsTable       = "reviews"
+	salesReportsTable  = "sales_reports"
+	refreshTokensTable = "refresh_tokens"
+	revokedTokensTable = "revoked_tokens"
+)
+
+// mutation is the new state of one record of a store. A mutation without a Value
+// deletes the record.
+type mutation struct {
+	Table string      `json:"table"`
+	Key   string      `json:"key"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// put returns a mutation that sets the record of table with key to value.
+func put(table string, key interface{}, value interface{}) mutation {
+	return mutation{Table: table, Key: fmt.Sprint(key), Value: value}
+}
+
+// remove returns a mutation that deletes the record of table with key.
+func remove(table string, key interface{}) mutation {
+	return mutation{Table: table, Key: fmt.Sprint(key)}
+}
+
+// loggedMutation is a mutation as read back from the log.
+type loggedMutation struct {
+	Table string          `json:"table"`
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// storedCustomer is a customer as the journal keeps it. Unlike data.Customer, it
+// is written to JSON with its password hash.
+type storedCustomer data.Customer
+
+const (
+	snapshotFile  = "snapshot.json"
+	segmentPrefix = "journal-"
+	segmentSuffix = ".log"
+)
+
+// A Journal makes a set of Stores durable. Every change to the stores is appended
+// to a write-ahead log in the journal's directory, and synced, before the stores
+// apply it; a change the log cannot take fails and leaves the stores as they were.
+// Snapshot writes the whole state out, after which the log starts over in a new
+// segment file and the older ones are removed.
+type Journal struct {
+	dir    string
+	stores Stores
+
+	// snapshotMu serialises snapshots.
+	snapshotMu sync.Mutex
+	// snapshotSegment is the Segment of the latest snapshot, or 0 if there is none.
+	snapshotSegment int
+
+	mu sync.Mutex
+	// file is the segment being appended to, numbered segment and holding size
+	// bytes. It is nil once the journal is closed.
+	file     *os.File
+	segment  int
+	size     int64
+	restored bool
+}
+
+// OpenJournal restores stores, which must be empty, from the latest snapshot in dir
+// and the log written after it, then records every change made to them there. dir
+// is created if it does not exist.
+func OpenJournal(dir string, stores Stores) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating journal directory: %w", err)
+	}
+	j := &Journal{dir: dir, stores: stores}
+
+	snap, err := readSnapshot(dir)
+	if err != nil {
+		return nil, err
+	}
+	if snap != nil {
+		stores.restore(*snap)
+		j.snapshotSegment = snap.Segment
+		j.segment = snap.Segment - 1
+		j.restored = true
+	}
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	replayed := 0
+	for i, segment := range segments {
+		if segment < j.snapshotSegment {
+			// Left behind by a snapshot that was interrupted before cleaning up.
+			if err := os.Remove(segmentPath(dir, segment)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		count, err := j.replay(segment, i == len(segments)-1)
+		if err != nil {
+			return nil, err
+		}
+		replayed += count
+		j.segment = segment
+	}
+	if replayed > 0 {
+		j.restored = true
+	}
+
+	if _, err := j.startSegment(); err != nil {
+		return nil, err
+	}
+	stores.setJournal(j)
+	if j.restored {
+		log.Printf("Restored the stores from %s, replaying %d logged changes", dir, replayed)
+	}
+	return j, nil
+}
+
+// Restored reports whether the stores were restored from anything in the journal's
+// directory, as opposed to starting empty.
+func (j *Journal) Restored() bool {
+	return j.restored
+}
+
+// save appends mutations to the log as one entry, which is replayed whole or not
+// at all, and syncs it to disk. A nil Journal saves nothing.
+func (j *Journal) save(mutations ...mutation) *data.ErrorResponse {
+	if j == nil {
+		return nil
+	}
+	if err := j.append(mutations); err != nil {
+		log.Printf("Error writing to the journal: %v", err)
+		return &data.ErrorResponse{Message: "Failed to save the change"}
+	}
+	return nil
+}
+
+func (j *Journal) append(mutations []mutation) error {
+	entry, err := json.Marshal(mutations)
+	if err != nil {
+		return err
+	}
+	entry = append(entry, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return errors.New("journal is closed")
+	}
+	_, err = j.file.Write(entry)
+	if err == nil {
+		err = j.file.Sync()
+	}
+	if err != nil {
+		// Drop whatever part of the entry reached the file, so that the change
+		// that failed is not replayed and the next entry starts a line of its own.
+		if truncErr := j.file.Truncate(j.size); truncErr != nil {
+			log.Printf("Error truncating %s: %v", j.file.Name(), truncErr)
+		}
+		return err
+	}
+	j.size += int64(len(entry))
+	return nil
+}
+
+// Snapshot writes the state of the stores to the snapshot file and removes the log
+// it replaces. Nothing is written if nothing changed since the last snapshot.
+func (j *Journal) Snapshot() error {
+	j.snapshotMu.Lock()
+	defer j.snapshotMu.Unlock()
+
+	j.mu.Lock()
+	if j.file == nil {
+		j.mu.Unlock()
+		return errors.New("journal is closed")
+	}
+	if j.segment == j.snapshotSegment && j.size == 0 {
+		j.mu.Unlock()
+		return nil
+	}
+	segment, err := j.startSegment()
+	j.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// Every change logged before the new segment was applied under the lock of
+	// its store, which snapshot waits for, so the snapshot holds all of them.
+	// Changes logged since may be in it too; replaying them again is harmless, as
+	// each mutation holds a record's whole new state.
+	snap := j.stores.snapshot()
+	snap.Segment = segment
+	snap.TakenAt = time.Now()
+	if err := writeSnapshot(j.dir, snap); err != nil {
+		return err
+	}
+	j.snapshotSegment = segment
+
+	segments, err := listSegments(j.dir)
+	if err != nil {
+		return err
+	}
+	for _, old := range segments {
+		if old < segment {
+			if err := os.Remove(segmentPath(j.dir, old)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RunSnapshots takes a snapshot every interval until ctx is done.
+func (j *Journal) RunSnapshots(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := j.Snapshot(); err != nil {
+				log.Printf("Error taking a snapshot: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Close takes a last snapshot and closes the log. Changes made to the stores
+// afterwards fail.
+func (j *Journal) Close() error {
+	err := j.Snapshot()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file != nil {
+		if closeErr := j.file.Close(); err == nil {
+			err = closeErr
+		}
+		j.file = nil
+	}
+	return err
+}
+
+// startSegment closes the segment being written and starts the next one,
+// returning its number. j.mu must be held, unless the journal is being opened.
+func (j *Journal) startSegment() (int, error) {
+	next := j.segment + 1
+	file, err := os.OpenFile(segmentPath(j.dir, next), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	info, err := file.Stat()
+	if err == nil {
+		err = syncDir(j.dir)
+	}
+	if err != nil {
+		file.Close()
+		return 0, err
+	}
+	if j.file != nil {
+		if err := j.file.Close(); err != nil {
+			log.Printf("Error closing %s: %v", j.file.Name(), err)
+		}
+	}
+	j.file, j.segment, j.size = file, next, info.Size()
+	return next, nil
+}
+
+// replay applies the entries of a segment to the stores and returns how many
+// mutations it held. An entry cut short at the end of the last segment, by a
+// crash while it was written, was never applied; it is dropped from the file.
+func (j *Journal) replay(segment int, last bool) (int, error) {
+	path := segmentPath(j.dir, segment)
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	count := 0
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return count, nil
+		}
+		if err != nil && err != io.EOF {
+			return count, err
+		}
+		var mutations []loggedMutation
+		complete := err == nil
+		if complete {
+			complete = json.Unmarshal(line, &mutations) == nil
+		}
+		if !complete {
+			if _, err := reader.Peek(1); !last || err != io.EOF {
+				return count, fmt.Errorf("%s: corrupt entry at offset %d", path, offset)
+			}
+			log.Printf("Dropping an incomplete entry at the end of %s", path)
+			return count, os.Truncate(path, offset)
+		}
+		for _, m := range mutations {
+			if err := j.stores.apply(m); err != nil {
+				return count, fmt.Errorf("%s: offset %d: %s %s: %w", path, offset, m.Table, m.Key, err)
+			}
+		}
+		count += len(mutations)
+		offset += int64(len(line))
+	}
+}
+
+func segmentPath(dir string, segment int) string {
+	return filepath.Join(dir, fmt.Sprintf("%s%06d%s", segmentPrefix, segment, segmentSuffix))
+}
+
+// listSegments returns the numbers of the segment files in dir, in order.
+func listSegments(dir string) ([]int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var segments []int
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		segment, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix))
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+	sort.Ints(segments)
+	return segments, nil
+}
+
+// syncDir syncs dir, so that files created or renamed in it survive a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// apply replays a logged mutation onto the stores. It is only used while the
+// journal is opened, before the stores are shared, and so takes no locks.
+func (s Stores) apply(m loggedMutation) error {
+	switch m.Table {
+	case authorsTable:
+		return applyRecord(s.Authors.authors, &s.Authors.nextID, m)
+	case booksTable:
+		return applyRecord(s.Books.books, &s.Books.nextID, m)
+	case customersTable:
+		return applyRecord(s.Customers.customers, &s.Customers.nextID, m)
+	case ordersTable:
+		return applyRecord(s.Orders.orders, &s.Orders.nextID, m)
+	case orderHistoryTable:
+		if err := applyRecord(s.Orders.history, new(int), m); err != nil {
+			return err
+		}
+		id, _ := strconv.Atoi(m.Key)
+		for _, change := range s.Orders.history[id] {
+			if change.ID >= s.Orders.nextChangeID {
+				s.Orders.nextChangeID = change.ID + 1
+			}
+		}
+		return nil
+	case reviewsTable:
+		return applyRecord(s.Reviews.reviews, &s.Reviews.nextID, m)
+	case salesReportsTable:
+		index, err := strconv.Atoi(m.Key)
+		if err != nil || index < 0 || index > len(s.Reports.reports) {
+			return fmt.Errorf("no sales report %s to replace", m.Key)
+		}
+		var report data.SalesReport
+		if err := json.Unmarshal(m.Value, &report); err != nil {
+			return err
+		}
+		if index == len(s.Reports.reports) {
+			s.Reports.reports = append(s.Reports.reports, report)
+		} else {
+			s.Reports.reports[index] = report
+		}
+		return nil
+	case refreshTokensTable:
+		if m.Value == nil {
+			delete(s.Tokens.refreshTokens, m.Key)
+			return nil
+		}
+		token := &refreshToken{}
+		if err := json.Unmarshal(m.Value, token); err != nil {
+			return err
+		}
+		s.Tokens.refreshTokens[m.Key] = token
+		return nil
+	case revokedTokensTable:
+		if m.Value == nil {
+			delete(s.Tokens.revoked, m.Key)
+			return nil
+		}
+		var expiresAt time.Time
+		if err := json.Unmarshal(m.Value, &expiresAt); err != nil {
+			return err
+		}
+		s.Tokens.revoked[m.Key] = expiresAt
+		return nil
+	}
+	return fmt.Errorf("unknown table %q", m.Table)
+}
+
+// applyRecord replays m onto the records of a table kept by ID. nextID is moved
+// past the ID of every record seen, deleted ones included, so that IDs are not
+// handed out twice.
+func applyRecord[T any](records map[int]T, nextID *int, m loggedMutation) error {
+	id, err := strconv.Atoi(m.Key)
+	if err != nil {
+		return err
+	}
+	if id >= *nextID {
+		*nextID = id + 1
+	}
+	if m.Value == nil {
+		delete(records, id)
+		return nil
+	}
+	var record T
+	if err := json.Unmarshal(m.Value, &record); err != nil {
+		return err
+	}
+	records[id] = record
+	return nil
+}
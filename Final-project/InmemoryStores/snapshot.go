@@ -0,0 +1,193 @@
+package InmemoryStores
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	data "finalProject/StructureData"
+)
+
+// snapshot is the whole state of a set of Stores, as written by Journal.Snapshot.
+type snapshot struct {
+	// Segment is the first log segment whose changes the snapshot may lack; it
+	// and the segments after it are replayed on top of the snapshot.
+	Segment int       `json:"segment"`
+	TakenAt time.Time `json:"taken_at"`
+
+	Authors        []data.Author                    `json:"authors"`
+	NextAuthorID   int                              `json:"next_author_id"`
+	Books          []data.Book                      `json:"books"`
+	NextBookID     int                              `json:"next_book_id"`
+	Customers      []storedCustomer                 `json:"customers"`
+	NextCustomerID int                              `json:"next_customer_id"`
+	Orders         []data.Order                     `json:"orders"`
+	NextOrderID    int                              `json:"next_order_id"`
+	OrderHistory   map[int][]data.OrderStatusChange `json:"order_history"`
+	NextChangeID   int                              `json:"next_change_id"`
+	Reviews        []data.Review                    `json:"reviews"`
+	NextReviewID   int                              `json:"next_review_id"`
+	SalesReports   []data.SalesReport               `json:"sales_reports"`
+	RefreshTokens  map[string]refreshToken          `json:"refresh_tokens"`
+	RevokedTokens  map[string]time.Time             `json:"revoked_tokens"`
+}
+
+// snapshot copies the state of the stores, taking the lock of each in turn.
+func (s Stores) snapshot() snapshot {
+	var snap snapshot
+
+	s.Authors.mu.RLock()
+	snap.Authors, snap.NextAuthorID = byID(s.Authors.authors), s.Authors.nextID
+	s.Authors.mu.RUnlock()
+
+	s.Books.mu.RLock()
+	snap.Books, snap.NextBookID = byID(s.Books.books), s.Books.nextID
+	s.Books.mu.RUnlock()
+
+	s.Customers.mu.RLock()
+	for _, customer := range byID(s.Customers.customers) {
+		snap.Customers = append(snap.Customers, storedCustomer(customer))
+	}
+	snap.NextCustomerID = s.Customers.nextID
+	s.Customers.mu.RUnlock()
+
+	s.Orders.mu.RLock()
+	snap.Orders, snap.NextOrderID = byID(s.Orders.orders), s.Orders.nextID
+	snap.OrderHistory = make(map[int][]data.OrderStatusChange, len(s.Orders.history))
+	for id, changes := range s.Orders.history {
+		snap.OrderHistory[id] = append([]data.OrderStatusChange(nil), changes...)
+	}
+	snap.NextChangeID = s.Orders.nextChangeID
+	s.Orders.mu.RUnlock()
+
+	s.Reviews.mu.RLock()
+	snap.Reviews, snap.NextReviewID = byID(s.Reviews.reviews), s.Reviews.nextID
+	s.Reviews.mu.RUnlock()
+
+	s.Reports.mu.RLock()
+	snap.SalesReports = append([]data.SalesReport(nil), s.Reports.reports...)
+	s.Reports.mu.RUnlock()
+
+	s.Tokens.mu.Lock()
+	snap.RefreshTokens = make(map[string]refreshToken, len(s.Tokens.refreshTokens))
+	for hash, token := range s.Tokens.refreshTokens {
+		snap.RefreshTokens[hash] = *token
+	}
+	snap.RevokedTokens = make(map[string]time.Time, len(s.Tokens.revoked))
+	for jti, expiresAt := range s.Tokens.revoked {
+		snap.RevokedTokens[jti] = expiresAt
+	}
+	s.Tokens.mu.Unlock()
+
+	return snap
+}
+
+// restore loads snap into the stores, which must be empty and not yet shared.
+func (s Stores) restore(snap snapshot) {
+	for _, author := range snap.Authors {
+		s.Authors.authors[author.ID] = author
+	}
+	s.Authors.nextID = max(s.Authors.nextID, snap.NextAuthorID)
+	for _, book := range snap.Books {
+		s.Books.books[book.ID] = book
+	}
+	s.Books.nextID = max(s.Books.nextID, snap.NextBookID)
+	for _, customer := range snap.Customers {
+		s.Customers.customers[customer.ID] = data.Customer(customer)
+	}
+	s.Customers.nextID = max(s.Customers.nextID, snap.NextCustomerID)
+	for _, order := range snap.Orders {
+		s.Orders.orders[order.ID] = order
+	}
+	s.Orders.nextID = max(s.Orders.nextID, snap.NextOrderID)
+	for id, changes := range snap.OrderHistory {
+		s.Orders.history[id] = changes
+	}
+	s.Orders.nextChangeID = max(s.Orders.nextChangeID, snap.NextChangeID)
+	for _, review := range snap.Reviews {
+		s.Reviews.reviews[review.ID] = review
+	}
+	s.Reviews.nextID = max(s.Reviews.nextID, snap.NextReviewID)
+	s.Reports.reports = snap.SalesReports
+	for hash, token := range snap.RefreshTokens {
+		s.Tokens.refreshTokens[hash] = &token
+	}
+	for jti, expiresAt := range snap.RevokedTokens {
+		s.Tokens.revoked[jti] = expiresAt
+	}
+}
+
+// setJournal has every store record its changes in j.
+func (s Stores) setJournal(j *Journal) {
+	s.Authors.journal = j
+	s.Books.journal = j
+	s.Customers.journal = j
+	s.Orders.journal = j
+	s.Reviews.journal = j
+	s.Reports.journal = j
+	s.Tokens.journal = j
+}
+
+// byID returns the records of a store ordered by ID.
+func byID[T any](records map[int]T) []T {
+	ids := make([]int, 0, len(records))
+	for id := range records {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	sorted := make([]T, len(ids))
+	for i, id := range ids {
+		sorted[i] = records[id]
+	}
+	return sorted
+}
+
+// readSnapshot reads the snapshot in dir, or returns nil if there is none.
+func readSnapshot(dir string) (*snapshot, error) {
+	raw, err := os.ReadFile(filepath.Join(dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+	var snap snapshot
+	if err := json.Unmarshal(raw, &snap); err != nil {
+		return nil, fmt.Errorf("parsing snapshot %s: %w", filepath.Join(dir, snapshotFile), err)
+	}
+	return &snap, nil
+}
+
+// writeSnapshot replaces the snapshot in dir with snap. The new snapshot is written
+// to a temporary file first, so a crash leaves either the old one or the new one.
+func writeSnapshot(dir string, snap snapshot) error {
+	raw, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, snapshotFile+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, snapshotFile)); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
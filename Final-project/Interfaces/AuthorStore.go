@@ -1,14 +1,20 @@
 package Interfaces
 
 import (
+	"context"
 	data "finalProject/StructureData"
 )
 
 type AuthorStore interface {
-	CreateAuthor(author data.Author) (data.Author, *data.ErrorResponse)
-	GetAuthor(id int) (data.Author, *data.ErrorResponse)
-	UpdateAuthor(id int, author data.Author) (data.Author, *data.ErrorResponse)
-	DeleteAuthor(id int) *data.ErrorResponse
-	SearchAuthors(criteria data.AuthorSearchCriteria) ([]data.Author, *data.ErrorResponse)
-	GetAllAuthors() []data.Author 
+	CreateAuthor(ctx context.Context, author data.Author) (data.Author, *data.ErrorResponse)
+	GetAuthor(ctx context.Context, id int) (data.Author, *data.ErrorResponse)
+	// GetAuthorByDetails finds the author with exactly these names and bio.
+	GetAuthorByDetails(ctx context.Context, firstName, lastName, bio string) (data.Author, *data.ErrorResponse)
+	UpdateAuthor(ctx context.Context, id int, author data.Author) (data.Author, *data.ErrorResponse)
+	// DeleteAuthor deletes the author and its books as one change. If an order
+	// refers to any of the books, it is a conflict and nothing is deleted.
+	DeleteAuthor(ctx context.Context, id int) *data.ErrorResponse
+	SearchAuthors(ctx context.Context, criteria data.AuthorSearchCriteria) ([]data.Author, *data.ErrorResponse)
+	GetAllAuthors(ctx context.Context) []data.Author 
+	ListAuthors(ctx context.Context, opts data.ListOptions) ([]data.Author, data.ListMeta, *data.ErrorResponse)
 }
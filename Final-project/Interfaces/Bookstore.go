@@ -1,15 +1,18 @@
 package Interfaces
 
 import (
+	"context"
 	data "finalProject/StructureData"
 )
 
 type BookStore interface {
-	CreateBook(book data.Book) (data.Book, *data.ErrorResponse)
-	GetBook(id int) (data.Book, *data.ErrorResponse)
-	UpdateBook(id int, book data.Book) (data.Book, *data.ErrorResponse)
-	DeleteBook(id int) *data.ErrorResponse
-	GetAllBooks() []data.Book
-	AddBookDirectly(book data.Book)
-	SearchBooks(criteria data.BookSearchCriteria) ([]data.Book, *data.ErrorResponse)
+	CreateBook(ctx context.Context, book data.Book) (data.Book, *data.ErrorResponse)
+	GetBook(ctx context.Context, id int) (data.Book, *data.ErrorResponse)
+	UpdateBook(ctx context.Context, id int, book data.Book) (data.Book, *data.ErrorResponse)
+	DeleteBook(ctx context.Context, id int) *data.ErrorResponse
+	GetAllBooks(ctx context.Context) []data.Book
+	ListBooks(ctx context.Context, opts data.ListOptions) ([]data.Book, data.ListMeta, *data.ErrorResponse)
+	SearchBooks(ctx context.Context, criteria data.BookSearchCriteria) ([]data.Book, *data.ErrorResponse)
+	SearchBookFacets(ctx context.Context, criteria data.BookSearchCriteria) (data.BookFacets, *data.ErrorResponse)
+	TextSearchBooks(ctx context.Context, query data.BookTextQuery) ([]data.BookSearchHit, data.ListMeta, *data.ErrorResponse)
 }
@@ -1,14 +1,19 @@
 package Interfaces
 
 import (
+	"context"
 	data "finalProject/StructureData"
 )
 
 type CustomerStore interface {
-	CreateCustomer(customer data.Customer) (data.Customer, *data.ErrorResponse)
-	GetCustomer(id int) (data.Customer, *data.ErrorResponse)
-	GetAllCustomers() []data.Customer
-	UpdateCustomer(id int, customer data.Customer) (data.Customer, *data.ErrorResponse)
-	DeleteCustomer(id int) *data.ErrorResponse
-	SearchCustomers(criteria data.CustomerSearchCriteria) ([]data.Customer, *data.ErrorResponse)
+	CreateCustomer(ctx context.Context, customer data.Customer) (data.Customer, *data.ErrorResponse)
+	GetCustomer(ctx context.Context, id int) (data.Customer, *data.ErrorResponse)
+	// GetCustomerByEmail finds the customer with email, including the password
+	// hash needed to check their credentials.
+	GetCustomerByEmail(ctx context.Context, email string) (data.Customer, *data.ErrorResponse)
+	GetAllCustomers(ctx context.Context) []data.Customer
+	ListCustomers(ctx context.Context, opts data.ListOptions) ([]data.Customer, data.ListMeta, *data.ErrorResponse)
+	UpdateCustomer(ctx context.Context, id int, customer data.Customer) (data.Customer, *data.ErrorResponse)
+	DeleteCustomer(ctx context.Context, id int) *data.ErrorResponse
+	SearchCustomers(ctx context.Context, criteria data.CustomerSearchCriteria) ([]data.Customer, *data.ErrorResponse)
 }
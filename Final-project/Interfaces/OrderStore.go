@@ -1,14 +1,31 @@
 package Interfaces
 
 import (
+	"context"
 	data "finalProject/StructureData"
+	"time"
 )
 
 type OrderStore interface {
-	CreateOrder(order data.Order) (data.Order, *data.ErrorResponse)
-	GetOrder(id int) (data.Order, *data.ErrorResponse)
-	UpdateOrder(id int, order data.Order) (data.Order, *data.ErrorResponse)
-	DeleteOrder(id int) *data.ErrorResponse
-	GetAllOrders() []data.Order
-	SearchOrders(criteria data.OrderSearchCriteria) ([]data.Order, *data.ErrorResponse)
+	CreateOrder(ctx context.Context, order data.Order) (data.Order, *data.ErrorResponse)
+	GetOrder(ctx context.Context, id int) (data.Order, *data.ErrorResponse)
+	UpdateOrder(ctx context.Context, id int, order data.Order) (data.Order, *data.ErrorResponse)
+	DeleteOrder(ctx context.Context, id int) *data.ErrorResponse
+	GetAllOrders(ctx context.Context) []data.Order
+	// GetOrdersInTimeRange returns the orders, with their items, created between
+	// start and end inclusive.
+	GetOrdersInTimeRange(ctx context.Context, start, end time.Time) ([]data.Order, error)
+	ListOrders(ctx context.Context, opts data.ListOptions) ([]data.Order, data.ListMeta, *data.ErrorResponse)
+	SearchOrders(ctx context.Context, criteria data.OrderSearchCriteria) ([]data.Order, *data.ErrorResponse)
+}
+
+// OrderProcessor places and changes orders together with the stock their items
+// hold, so that two orders can never take the same copy of a book. The returned
+// StockLevels hold the new stock of every book a change touched.
+type OrderProcessor interface {
+	PlaceOrder(ctx context.Context, order data.Order, allowPartial bool) (data.Order, data.StockLevels, *data.ErrorResponse)
+	ReplaceOrder(ctx context.Context, id int, order data.Order, allowPartial bool) (data.Order, data.StockLevels, *data.ErrorResponse)
+	RemoveOrder(ctx context.Context, id int) (data.StockLevels, *data.ErrorResponse)
+	TransitionOrder(ctx context.Context, id int, to string, actorID int, note string) (data.OrderStatusChange, data.StockLevels, *data.ErrorResponse)
+	GetOrderHistory(ctx context.Context, id int) ([]data.OrderStatusChange, *data.ErrorResponse)
 }
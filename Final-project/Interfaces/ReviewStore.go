@@ -0,0 +1,15 @@
+package Interfaces
+
+import (
+	"context"
+	data "finalProject/StructureData"
+)
+
+// ReviewStore keeps the reviews customers leave on books. Creating or deleting a
+// review updates the review stats of its book.
+type ReviewStore interface {
+	CreateReview(ctx context.Context, review data.Review) (data.Review, *data.ErrorResponse)
+	GetReview(ctx context.Context, id int) (data.Review, *data.ErrorResponse)
+	GetReviewsByBookID(ctx context.Context, bookID int) ([]data.Review, *data.ErrorResponse)
+	DeleteReview(ctx context.Context, id int) *data.ErrorResponse
+}
@@ -0,0 +1,11 @@
+package Interfaces
+
+import (
+	"context"
+	data "finalProject/StructureData"
+)
+
+type SalesReportStore interface {
+	SaveSalesReport(ctx context.Context, report data.SalesReport) (*data.SalesReport, *data.ErrorResponse)
+	GetAllSalesReports(ctx context.Context) ([]data.SalesReport, *data.ErrorResponse)
+}
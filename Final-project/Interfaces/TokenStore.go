@@ -0,0 +1,22 @@
+package Interfaces
+
+import (
+	"context"
+	"time"
+
+	data "finalProject/StructureData"
+)
+
+// TokenStore keeps the refresh tokens issued to customers, by hash, and the access
+// tokens revoked before they expire, by jti.
+type TokenStore interface {
+	SaveRefreshToken(ctx context.Context, customerID int, tokenHash string, accessJTI string, expiresAt time.Time) *data.ErrorResponse
+	RefreshTokenOwner(ctx context.Context, tokenHash string) (int, *data.ErrorResponse)
+	// RotateRefreshToken replaces the refresh token oldHash by newHash and revokes
+	// the access token issued with the old one, returning the owner's ID.
+	RotateRefreshToken(ctx context.Context, oldHash string, newHash string, newAccessJTI string, newExpiresAt time.Time, revokeAccessUntil time.Time) (int, *data.ErrorResponse)
+	RevokeRefreshToken(ctx context.Context, customerID int, tokenHash string) *data.ErrorResponse
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) *data.ErrorResponse
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	PurgeExpiredTokens(ctx context.Context) *data.ErrorResponse
+}
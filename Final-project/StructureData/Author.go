@@ -1,15 +1,42 @@
 package StructureData
 
+import "strings"
+
 type Author struct {
 	ID int `json:"id"`
 	FirstName string `json:"first_name"`
 	LastName string `json:"last_name"`
 	Bio string `json:"bio"`
    }
+
+// FullName returns the author's first and last name separated by a space.
+func (a Author) FullName() string {
+	return strings.TrimSpace(a.FirstName + " " + a.LastName)
+}
    
    type AuthorSearchCriteria struct {
 	IDs         []int    `json:"ids,omitempty"`         
 	FirstNames  []string `json:"first_names,omitempty"`  
 	LastNames   []string `json:"last_names,omitempty"`   
 	Keywords    []string `json:"keywords,omitempty"`     
-}
\ No newline at end of file
+	// Fuzzy lets FirstNames, LastNames and Keywords match misspelt words, and
+	// orders the results by similarity, best first.
+	Fuzzy bool `json:"fuzzy,omitempty"`
+	// Filter is an optional boolean expression over AuthorFilterFields, combined
+	// with the fields above by AND.
+	Filter *Filter `json:"filter,omitempty"`
+}
+
+// AuthorSortFields lists the fields authors can be sorted by.
+var AuthorSortFields = []string{"id", "first_name", "last_name"}
+
+// SortValue returns the value of sort field field, one of AuthorSortFields.
+func (a Author) SortValue(field string) interface{} {
+	switch field {
+	case "first_name":
+		return a.FirstName
+	case "last_name":
+		return a.LastName
+	}
+	return a.ID
+}
@@ -1,6 +1,9 @@
 package StructureData
 
-import ("time")
+import (
+	"fmt"
+	"time"
+)
 
 
 
@@ -32,4 +35,119 @@ type BookSearchCriteria struct {
 	MaxAverageRating float64 `json:"max_average_rating,omitempty"`
 	MinReviewCount   int     `json:"min_review_count,omitempty"`
 	MaxReviewCount   int     `json:"max_review_count,omitempty"`
-}
\ No newline at end of file
+	// Facets names the aggregate counts, from BookFacetNames, to return with
+	// the results.
+	Facets []string `json:"facets,omitempty"`
+	// Filter is an optional boolean expression over BookFilterFields, combined
+	// with the fields above by AND.
+	Filter *Filter `json:"filter,omitempty"`
+}
+
+// Facets that can be requested with a book search.
+const (
+	FacetGenre  = "genre"
+	FacetAuthor = "author"
+	FacetPrice  = "price"
+	FacetRating = "rating"
+)
+
+// BookFacetNames lists the facets a book search can return.
+var BookFacetNames = []string{FacetGenre, FacetAuthor, FacetPrice, FacetRating}
+
+// PriceBandLimits are the upper bounds of the price bands counted by the price
+// facet. Prices at or above the last limit fall into an open-ended band.
+var PriceBandLimits = []float64{10, 25, 50}
+
+// PriceBand returns the key of the price band that the i-th band, as numbered
+// from 0 for prices below PriceBandLimits[0], is counted under: "0-10", "10-25",
+// "25-50" or "50+".
+func PriceBand(i int) string {
+	if i >= len(PriceBandLimits) {
+		return fmt.Sprintf("%g+", PriceBandLimits[len(PriceBandLimits)-1])
+	}
+	lower := 0.0
+	if i > 0 {
+		lower = PriceBandLimits[i-1]
+	}
+	return fmt.Sprintf("%g-%g", lower, PriceBandLimits[i])
+}
+
+// PriceBandIndex returns the number of the price band price falls into.
+func PriceBandIndex(price float64) int {
+	for i, limit := range PriceBandLimits {
+		if price < limit {
+			return i
+		}
+	}
+	return len(PriceBandLimits)
+}
+
+// FacetCount is the number of matching books sharing one facet value.
+type FacetCount struct {
+	Key   string `json:"key"`
+	Label string `json:"label,omitempty"`
+	Count int    `json:"count"`
+}
+
+// BookFacets holds the facets requested with a book search. Genres and authors
+// are ordered by count, most frequent first; price bands and ratings by key.
+// Ratings are keyed by the whole number of stars of a book's average rating,
+// "0" for books without reviews.
+type BookFacets struct {
+	Genres     []FacetCount `json:"genres,omitempty"`
+	Authors    []FacetCount `json:"authors,omitempty"`
+	PriceBands []FacetCount `json:"price_bands,omitempty"`
+	Ratings    []FacetCount `json:"ratings,omitempty"`
+}
+
+// BookSearchResponse is returned by a book search that asked for facets.
+type BookSearchResponse struct {
+	Data   []Book     `json:"data"`
+	Facets BookFacets `json:"facets"`
+}
+
+// BookTextQuery is a full-text search over the book catalog. Every word of Query
+// must match, as a word prefix, the title, author name, genres or author bio.
+type BookTextQuery struct {
+	Query  string
+	Limit  int
+	Offset int
+}
+
+// BookSearchHit is one result of a full-text book search. A higher Score means a
+// better match; title matches outweigh matches in the author name, genres and bio.
+type BookSearchHit struct {
+	Book       Book           `json:"book"`
+	Score      float64        `json:"score"`
+	Highlights BookHighlights `json:"highlights"`
+}
+
+// BookHighlights marks the matched words of a search hit with <mark> tags.
+type BookHighlights struct {
+	Title   string `json:"title"`
+	Snippet string `json:"snippet,omitempty"` // Excerpt of the author bio.
+}
+
+// Highlight tags wrapped around matched words.
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+// BookSortFields lists the fields books can be sorted by.
+var BookSortFields = []string{"id", "title", "price", "stock", "published_at"}
+
+// SortValue returns the value of sort field field, one of BookSortFields.
+func (b Book) SortValue(field string) interface{} {
+	switch field {
+	case "title":
+		return b.Title
+	case "price":
+		return b.Price
+	case "stock":
+		return b.Stock
+	case "published_at":
+		return b.PublishedAt
+	}
+	return b.ID
+}
@@ -7,6 +7,12 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Roles a customer account can hold; carried in the JWT "role" claim.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type Customer struct {
 	
 	ID        int       `json:"id"`
@@ -15,6 +21,7 @@ type Customer struct {
 	Email     string    `json:"email"`
 	Password  string    `json:"password"`
 	Address   Address   `json:"address"`
+	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -25,6 +32,12 @@ type CustomerSearchCriteria struct {
 	MinCreatedAt    time.Time             `json:"min_created_at,omitempty"`
 	MaxCreatedAt    time.Time             `json:"max_created_at,omitempty"`
 	AddressCriteria AddressSearchCriteria `json:"address_criteria,omitempty"` // Embedded address filtering criteria
+	// Fuzzy lets Names match misspelt words, and orders the results by similarity,
+	// best first.
+	Fuzzy bool `json:"fuzzy,omitempty"`
+	// Filter is an optional boolean expression over CustomerFilterFields,
+	// combined with the fields above by AND.
+	Filter *Filter `json:"filter,omitempty"`
 }
 
 func (user *Customer) HashPassword(password string) error {
@@ -54,4 +67,20 @@ func (c Customer) MarshalJSON() ([]byte, error) {
 		Password: "...", // Always set to "..."
 		Alias:    (*Alias)(&c),
 	})
-}
\ No newline at end of file
+}
+
+// CustomerSortFields lists the fields customers can be sorted by.
+var CustomerSortFields = []string{"id", "name", "email", "created_at"}
+
+// SortValue returns the value of sort field field, one of CustomerSortFields.
+func (c Customer) SortValue(field string) interface{} {
+	switch field {
+	case "name":
+		return c.Name
+	case "email":
+		return c.Email
+	case "created_at":
+		return c.CreatedAt
+	}
+	return c.ID
+}
@@ -1,8 +1,118 @@
 package StructureData
 
+import "fmt"
+
+// ErrorCode is the stable, machine-readable kind of an ErrorResponse. It decides
+// the HTTP status of the response and is sent to clients as the problem's code.
+type ErrorCode string
+
+const (
+	// CodeValidation is a request that is malformed or breaks a rule on its
+	// fields. Fields says which ones, when known.
+	CodeValidation ErrorCode = "validation_failed"
+	// CodeUnauthorized is a missing, invalid or revoked credential.
+	CodeUnauthorized ErrorCode = "unauthorized"
+	// CodeForbidden is a caller without the right to do what it asked.
+	CodeForbidden ErrorCode = "forbidden"
+	// CodeNotFound is a record that does not exist.
+	CodeNotFound ErrorCode = "not_found"
+	// CodeConflict is a change the current state of a record does not allow,
+	// such as deleting a book that orders refer to.
+	CodeConflict ErrorCode = "conflict"
+	// CodeInsufficientStock is an order refused because its books are out of
+	// stock. RejectedItems says which.
+	CodeInsufficientStock ErrorCode = "insufficient_stock"
+	// CodeInternal is a failure of the server or its storage.
+	CodeInternal ErrorCode = "internal"
+)
+
+// FieldError is what is wrong with one field of a request. Field is its path in
+// the JSON body (e.g. "items[1].quantity") or the name of a query parameter.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ErrorResponse is the error of a store or of request validation. Its Code decides
+// how the API answers it; an ErrorResponse without one is internal.
 type ErrorResponse struct {
-	Message string `json:"error"`
+	Code    ErrorCode `json:"code,omitempty"`
+	Message string    `json:"error"`
+	// Fields lists the invalid fields of a validation error.
+	Fields []FieldError `json:"fields,omitempty"`
+	// RejectedItems lists the order lines that caused an order to be refused.
+	RejectedItems []OrderItemRejection `json:"rejected_items,omitempty"`
 }
+
 func (e *ErrorResponse) Error() string {
 	return e.Message
-}
\ No newline at end of file
+}
+
+// Kind returns the code of the error, CodeInternal when none is set.
+func (e *ErrorResponse) Kind() ErrorCode {
+	if e.Code == "" {
+		return CodeInternal
+	}
+	return e.Code
+}
+
+// newError returns an error with code and a message formatted as by fmt.Sprintf.
+func newError(code ErrorCode, format string, args ...interface{}) *ErrorResponse {
+	if len(args) > 0 {
+		format = fmt.Sprintf(format, args...)
+	}
+	return &ErrorResponse{Code: code, Message: format}
+}
+
+// ValidationError returns a CodeValidation error about the request as a whole.
+func ValidationError(format string, args ...interface{}) *ErrorResponse {
+	return newError(CodeValidation, format, args...)
+}
+
+// FieldValidationError returns a CodeValidation error about one field of the
+// request; the message is both the error's and the field's.
+func FieldValidationError(field, format string, args ...interface{}) *ErrorResponse {
+	errResp := newError(CodeValidation, format, args...)
+	errResp.Fields = []FieldError{{Field: field, Message: errResp.Message}}
+	return errResp
+}
+
+// UnauthorizedError returns a CodeUnauthorized error.
+func UnauthorizedError(format string, args ...interface{}) *ErrorResponse {
+	return newError(CodeUnauthorized, format, args...)
+}
+
+// ForbiddenError returns a CodeForbidden error.
+func ForbiddenError(format string, args ...interface{}) *ErrorResponse {
+	return newError(CodeForbidden, format, args...)
+}
+
+// NotFoundError returns a CodeNotFound error.
+func NotFoundError(format string, args ...interface{}) *ErrorResponse {
+	return newError(CodeNotFound, format, args...)
+}
+
+// ConflictError returns a CodeConflict error.
+func ConflictError(format string, args ...interface{}) *ErrorResponse {
+	return newError(CodeConflict, format, args...)
+}
+
+// InternalError returns a CodeInternal error.
+func InternalError(format string, args ...interface{}) *ErrorResponse {
+	return newError(CodeInternal, format, args...)
+}
+
+// Problem is an RFC 7807 problem details document, the body of every error
+// response, served as application/problem+json. Code, Errors and RejectedItems
+// are extension members.
+type Problem struct {
+	Type     string    `json:"type"`
+	Title    string    `json:"title"`
+	Status   int       `json:"status"`
+	Detail   string    `json:"detail,omitempty"`
+	Instance string    `json:"instance,omitempty"`
+	Code     ErrorCode `json:"code"`
+	// Errors lists the invalid fields of a validation problem.
+	Errors        []FieldError         `json:"errors,omitempty"`
+	RejectedItems []OrderItemRejection `json:"rejected_items,omitempty"`
+}
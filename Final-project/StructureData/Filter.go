@@ -0,0 +1,183 @@
+package StructureData
+
+// Filter is a node of a boolean filter expression accepted by the search
+// endpoints. A node either combines other nodes with And, Or or Not, or tests one
+// field with Op:
+//
+//	{"and": [
+//	  {"not": {"field": "genres", "op": "eq", "value": "Horror"}},
+//	  {"or": [
+//	    {"field": "price", "op": "range", "min": 5, "max": 20},
+//	    {"field": "title", "op": "contains", "value": "dune"}
+//	  ]}
+//	]}
+//
+// Filters are validated with utils.ParseFilter, which also converts Value,
+// Values, Min and Max to the field's Go type. The stores expect parsed filters.
+type Filter struct {
+	And []Filter `json:"and,omitempty"`
+	Or  []Filter `json:"or,omitempty"`
+	Not *Filter  `json:"not,omitempty"`
+
+	Field  string        `json:"field,omitempty"`
+	Op     string        `json:"op,omitempty"`     // One of the FilterOp constants
+	Value  interface{}   `json:"value,omitempty"`  // eq, contains
+	Values []interface{} `json:"values,omitempty"` // in
+	Min    interface{}   `json:"min,omitempty"`    // range, inclusive
+	Max    interface{}   `json:"max,omitempty"`    // range, inclusive
+}
+
+// Filter predicates.
+const (
+	FilterOpEq       = "eq"       // equals Value
+	FilterOpIn       = "in"       // equals one of Values
+	FilterOpRange    = "range"    // between Min and Max; either may be left out
+	FilterOpContains = "contains" // contains Value, ignoring case
+)
+
+// Types of filterable fields. For list fields, eq and in test whether any element
+// equals the value, and contains whether any element contains it.
+const (
+	FilterString     = "string"
+	FilterNumber     = "number"
+	FilterTime       = "time" // RFC 3339 values
+	FilterStringList = "string_list"
+)
+
+// FilterOps lists the predicates allowed on each type of field.
+var FilterOps = map[string][]string{
+	FilterString:     {FilterOpEq, FilterOpIn, FilterOpContains},
+	FilterNumber:     {FilterOpEq, FilterOpIn, FilterOpRange},
+	FilterTime:       {FilterOpEq, FilterOpIn, FilterOpRange},
+	FilterStringList: {FilterOpEq, FilterOpIn, FilterOpContains},
+}
+
+// MaxFilterNodes bounds the size of a filter expression.
+const MaxFilterNodes = 100
+
+// Filterable is implemented by records the in-memory stores filter. FilterValue
+// returns the value of a field named in the record's filter field table: a
+// string, int, float64, time.Time or []string.
+type Filterable interface {
+	FilterValue(field string) interface{}
+}
+
+// BookFilterFields maps the fields a book filter may test to their types.
+var BookFilterFields = map[string]string{
+	"id":           FilterNumber,
+	"title":        FilterString,
+	"genres":       FilterStringList,
+	"published_at": FilterTime,
+	"price":        FilterNumber,
+	"stock":        FilterNumber,
+	"author_id":    FilterNumber,
+	"author_name":  FilterString,
+}
+
+// FilterValue returns the value of field, one of BookFilterFields.
+func (b Book) FilterValue(field string) interface{} {
+	switch field {
+	case "title":
+		return b.Title
+	case "genres":
+		return b.Genres
+	case "published_at":
+		return b.PublishedAt
+	case "price":
+		return b.Price
+	case "stock":
+		return b.Stock
+	case "author_id":
+		return b.Author.ID
+	case "author_name":
+		return b.Author.FullName()
+	}
+	return b.ID
+}
+
+// AuthorFilterFields maps the fields an author filter may test to their types.
+var AuthorFilterFields = map[string]string{
+	"id":         FilterNumber,
+	"first_name": FilterString,
+	"last_name":  FilterString,
+	"bio":        FilterString,
+}
+
+// FilterValue returns the value of field, one of AuthorFilterFields.
+func (a Author) FilterValue(field string) interface{} {
+	switch field {
+	case "first_name":
+		return a.FirstName
+	case "last_name":
+		return a.LastName
+	case "bio":
+		return a.Bio
+	}
+	return a.ID
+}
+
+// CustomerFilterFields maps the fields a customer filter may test to their types.
+var CustomerFilterFields = map[string]string{
+	"id":          FilterNumber,
+	"name":        FilterString,
+	"email":       FilterString,
+	"username":    FilterString,
+	"role":        FilterString,
+	"created_at":  FilterTime,
+	"street":      FilterString,
+	"city":        FilterString,
+	"state":       FilterString,
+	"postal_code": FilterString,
+	"country":     FilterString,
+}
+
+// FilterValue returns the value of field, one of CustomerFilterFields.
+func (c Customer) FilterValue(field string) interface{} {
+	switch field {
+	case "name":
+		return c.Name
+	case "email":
+		return c.Email
+	case "username":
+		return c.Username
+	case "role":
+		return c.Role
+	case "created_at":
+		return c.CreatedAt
+	case "street":
+		return c.Address.Street
+	case "city":
+		return c.Address.City
+	case "state":
+		return c.Address.State
+	case "postal_code":
+		return c.Address.PostalCode
+	case "country":
+		return c.Address.Country
+	}
+	return c.ID
+}
+
+// OrderFilterFields maps the fields an order filter may test to their types.
+var OrderFilterFields = map[string]string{
+	"id":          FilterNumber,
+	"customer_id": FilterNumber,
+	"total_price": FilterNumber,
+	"created_at":  FilterTime,
+	"status":      FilterString,
+}
+
+// FilterValue returns the value of field, one of OrderFilterFields.
+func (o Order) FilterValue(field string) interface{} {
+	switch field {
+	case "customer_id":
+		return o.Customer.ID
+	case "total_price":
+		return o.TotalPrice
+	case "created_at":
+		return o.CreatedAt
+	case "status":
+		return o.Status
+	}
+	return o.ID
+}
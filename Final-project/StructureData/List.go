@@ -0,0 +1,54 @@
+package StructureData
+
+// Page size limits for list endpoints.
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 500
+)
+
+// SortField orders a list by one field, ascending unless Desc is set.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ListOptions selects one page of a list. A page starts either at Offset or,
+// when After is set, right after the row a cursor was issued for.
+type ListOptions struct {
+	Limit  int
+	Offset int
+	// Sort always ends with the "id" field so that the order is total.
+	Sort []SortField
+	// After holds the sort values of the last row of the previous page, decoded
+	// from the request's cursor.
+	After  []interface{}
+	Fields []string
+}
+
+// Wants reports whether field should be part of the response. All fields are
+// wanted when no fields were selected.
+func (opts ListOptions) Wants(field string) bool {
+	if len(opts.Fields) == 0 {
+		return true
+	}
+	for _, f := range opts.Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// ListMeta describes the page returned by a list endpoint.
+type ListMeta struct {
+	Total      int    `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ListResponse is the body returned by list endpoints.
+type ListResponse struct {
+	Data interface{} `json:"data"`
+	Meta ListMeta    `json:"meta"`
+}
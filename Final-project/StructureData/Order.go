@@ -1,17 +1,78 @@
 package StructureData
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
+
 const (
-	OrderStatusPending = "pending"
+	OrderStatusPending   = "pending"
+	OrderStatusPaid      = "paid"
+	OrderStatusShipped   = "shipped"
+	OrderStatusDelivered = "delivered"
+	OrderStatusCancelled = "cancelled"
+	OrderStatusRefunded  = "refunded"
+	// OrderStatusSuccess is what paid orders were marked with before the lifecycle
+	// above existed. Such orders move on exactly like paid ones.
 	OrderStatusSuccess = "success"
 )
+
+// OrderStatuses lists every order status in lifecycle order.
+var OrderStatuses = []string{
+	OrderStatusPending,
+	OrderStatusPaid,
+	OrderStatusSuccess,
+	OrderStatusShipped,
+	OrderStatusDelivered,
+	OrderStatusCancelled,
+	OrderStatusRefunded,
+}
+
+// orderTransitions maps each status to the statuses an order may move to from it.
+// Cancelled and refunded orders are final.
+var orderTransitions = map[string][]string{
+	OrderStatusPending:   {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:      {OrderStatusShipped, OrderStatusCancelled, OrderStatusRefunded},
+	OrderStatusSuccess:   {OrderStatusShipped, OrderStatusCancelled, OrderStatusRefunded},
+	OrderStatusShipped:   {OrderStatusDelivered, OrderStatusRefunded},
+	OrderStatusDelivered: {OrderStatusRefunded},
+}
+
+// IsOrderStatus reports whether status is a known order status.
+func IsOrderStatus(status string) bool {
+	for _, s := range OrderStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// CanTransitionOrder reports whether an order in status from may be moved to status to.
+func CanTransitionOrder(from, to string) bool {
+	for _, s := range orderTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOrderRevenue reports whether orders in status count towards sales revenue,
+// that is, they have not been cancelled or refunded.
+func IsOrderRevenue(status string) bool {
+	return status != OrderStatusCancelled && status != OrderStatusRefunded
+}
 type Order struct {
 	ID         int         `json:"id"`
 	Customer   Customer    `json:"customer"`
 	Items      []OrderItem `json:"items"`
 	TotalPrice float64     `json:"total_price"`
 	CreatedAt  time.Time   `json:"created_at"`
-	Status     string      `json:"status"` // Either "pending" or "success"
+	Status     string      `json:"status"` // One of OrderStatuses
+	// RejectedItems is only set on responses to allow_partial requests, listing
+	// the lines that were left out.
+	RejectedItems []OrderItemRejection `json:"rejected_items,omitempty"`
 
 }
 
@@ -25,4 +86,85 @@ type OrderSearchCriteria struct {
 	MaxCreatedAt  time.Time               `json:"max_created_at,omitempty"`
 	Status        string                  `json:"status,omitempty"`
 	ItemCriteria  OrderItemSearchCriteria `json:"item_criteria,omitempty"`
-}
\ No newline at end of file
+	// Filter is an optional boolean expression over OrderFilterFields, combined
+	// with the fields above by AND.
+	Filter *Filter `json:"filter,omitempty"`
+}
+
+// OrderTransition is the request body for moving an order to a new status.
+type OrderTransition struct {
+	Status string `json:"status"`
+	Note   string `json:"note,omitempty"`
+}
+
+// OrderStatusChange is one entry in an order's status history.
+type OrderStatusChange struct {
+	ID         int       `json:"id"`
+	OrderID    int       `json:"order_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ChangedAt  time.Time `json:"changed_at"`
+	ChangedBy  *int      `json:"changed_by,omitempty"`
+	Note       string    `json:"note,omitempty"`
+}
+
+// OrderSortFields lists the fields orders can be sorted by.
+var OrderSortFields = []string{"id", "created_at", "total_price", "status"}
+
+// SortValue returns the value of sort field field, one of OrderSortFields.
+func (o Order) SortValue(field string) interface{} {
+	switch field {
+	case "created_at":
+		return o.CreatedAt
+	case "total_price":
+		return o.TotalPrice
+	case "status":
+		return o.Status
+	}
+	return o.ID
+}
+
+// StockLevels maps book IDs to their stock after a committed order change, so
+// callers can bring cached copies of those books up to date.
+type StockLevels map[int]int
+
+// Errors returned by the stock-reserving methods of the order processors.
+var (
+	ErrOrderNotFound     = NotFoundError("Order not found")
+	ErrOrderLocked       = ConflictError("Only pending orders can be modified")
+	ErrEmptyOrder        = FieldValidationError("items", "Order must contain at least one item")
+	ErrInvalidTransition = ConflictError("Order status transition not allowed")
+)
+
+// CheckRejections decides whether an order can go ahead with the reserved items.
+func CheckRejections(reserved []OrderItem, rejected []OrderItemRejection, allowPartial bool) *ErrorResponse {
+	switch {
+	case len(reserved) == 0 && len(rejected) == 0:
+		return ErrEmptyOrder
+	case len(reserved) == 0:
+		return rejectionError("No valid books available", rejected)
+	case len(rejected) > 0 && !allowPartial:
+		return rejectionError("Some items cannot be ordered", rejected)
+	}
+	return nil
+}
+
+// rejectionError returns the error refusing an order for its rejected items. It is
+// CodeInsufficientStock when they are all short of stock, and otherwise
+// CodeValidation, with a field error on the items for each missing book or invalid
+// quantity.
+func rejectionError(message string, rejected []OrderItemRejection) *ErrorResponse {
+	errResp := &ErrorResponse{Code: CodeInsufficientStock, Message: message, RejectedItems: rejected}
+	for _, rejection := range rejected {
+		switch rejection.Reason {
+		case RejectionNotFound:
+			errResp.Fields = append(errResp.Fields, FieldError{Field: "items", Message: fmt.Sprintf("Book %d not found", rejection.BookID)})
+		case RejectionInvalidQuantity:
+			errResp.Fields = append(errResp.Fields, FieldError{Field: "items", Message: fmt.Sprintf("Quantity %d of book %d must be at least 1", rejection.Quantity, rejection.BookID)})
+		}
+	}
+	if len(errResp.Fields) > 0 {
+		errResp.Code = CodeValidation
+	}
+	return errResp
+}
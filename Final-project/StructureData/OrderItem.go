@@ -3,10 +3,44 @@ package StructureData
 type OrderItem struct {
 	Book Book `json:"book"`
 	Quantity int `json:"quantity"`
+	// UnitPrice, BookTitle and AuthorName are copied from the book when the order
+	// is placed, so later catalog changes leave the order as it was bought.
+	UnitPrice  float64 `json:"unit_price"`
+	BookTitle  string  `json:"book_title"`
+	AuthorName string  `json:"author_name"`
    }
+
+// Subtotal returns the item's price at purchase time multiplied by its quantity.
+func (item OrderItem) Subtotal() float64 {
+	return item.UnitPrice * float64(item.Quantity)
+}
+
+// ItemsTotal returns the sum of the subtotals of items.
+func ItemsTotal(items []OrderItem) float64 {
+	total := 0.0
+	for _, item := range items {
+		total += item.Subtotal()
+	}
+	return total
+}
    
 type OrderItemSearchCriteria struct {
 	BookCriteria BookSearchCriteria `json:"book_criteria,omitempty"`
 	MinQuantity  int                `json:"min_quantity,omitempty"`
 	MaxQuantity  int                `json:"max_quantity,omitempty"`
 }
+
+// Reasons an order line can be rejected.
+const (
+	RejectionNotFound          = "not_found"
+	RejectionInsufficientStock = "insufficient_stock"
+	RejectionInvalidQuantity   = "invalid_quantity"
+)
+
+// OrderItemRejection describes an order line that could not be reserved.
+type OrderItemRejection struct {
+	BookID    int    `json:"book_id"`
+	Quantity  int    `json:"quantity"`
+	Reason    string `json:"reason"`
+	Available *int   `json:"available,omitempty"` // set for insufficient_stock
+}
@@ -12,6 +12,8 @@ type SalesReport struct {
     TotalOrders      int              `json:"total_orders"`
     SuccessfulOrders int              `json:"successful_orders"`
     PendingOrders    int              `json:"pending_orders"`
+    // StatusCounts holds the number of orders in each status.
+    StatusCounts     map[string]int   `json:"status_counts"`
     TopSellingBooks  []TopSellingBook `json:"top_selling_books"`
 }
 
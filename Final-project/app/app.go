@@ -0,0 +1,136 @@
+// Package app assembles the HTTP API: it builds the handlers over a set of stores,
+// protects them with the authorization policies and registers them on a router.
+// Nothing in it reaches for a package-level store, so several Apps, each over its
+// own stores, can run side by side, in tests as much as in production.
+package app
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	controllers "finalProject/Controllers"
+	interfaces "finalProject/Interfaces"
+	"finalProject/auth"
+	"finalProject/config"
+	"finalProject/middlewares"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// tokenPurgeInterval is how often expired refresh tokens and revocation entries
+// are dropped.
+const tokenPurgeInterval = 1 * time.Hour
+
+// Stores are the stores an App serves from.
+type Stores struct {
+	Books     interfaces.BookStore
+	Authors   interfaces.AuthorStore
+	Customers interfaces.CustomerStore
+	Orders    interfaces.OrderStore
+	// Processor places and changes orders together with the stock they hold. It
+	// must work on the same orders and books as Orders and Books.
+	Processor interfaces.OrderProcessor
+	Reviews   interfaces.ReviewStore
+	Reports   interfaces.SalesReportStore
+	Tokens    interfaces.TokenStore
+}
+
+// App is one instance of the HTTP API.
+type App struct {
+	Config config.Config
+	Stores Stores
+	Logger *log.Logger
+	Router *httprouter.Router
+
+	Auth      *middlewares.Authenticator
+	Authors   *controllers.AuthorHandler
+	Books     *controllers.BookHandler
+	Customers *controllers.CustomerHandler
+	Orders    *controllers.OrderHandler
+	Reports   *controllers.ReportHandler
+	Reviews   *controllers.ReviewHandler
+	Tokens    *controllers.TokenHandler
+	// Health reports on nothing until the caller fills in what is behind the
+	// stores: the database and the caches.
+	Health *controllers.HealthHandler
+}
+
+// New builds the API described by cfg over stores, logging to logger.
+func New(cfg config.Config, stores Stores, logger *log.Logger) *App {
+	issuer := auth.NewIssuer(cfg.JWT.Secret, cfg.JWT.AccessTokenTTL.Duration, cfg.JWT.RefreshTokenTTL.Duration)
+	a := &App{
+		Config: cfg,
+		Stores: stores,
+		Logger: logger,
+		Router: httprouter.New(),
+
+		Auth: &middlewares.Authenticator{Issuer: issuer, Tokens: stores.Tokens},
+		Authors: &controllers.AuthorHandler{
+			Authors: stores.Authors,
+			Books:   stores.Books,
+		},
+		Books: &controllers.BookHandler{
+			Books:   stores.Books,
+			Authors: stores.Authors,
+			Orders:  stores.Orders,
+			Log:     logger,
+		},
+		Customers: &controllers.CustomerHandler{
+			Customers: stores.Customers,
+			Orders:    stores.Orders,
+			Issuer:    issuer,
+			Tokens:    stores.Tokens,
+			Log:       logger,
+		},
+		Orders: &controllers.OrderHandler{
+			Orders:    stores.Orders,
+			Processor: stores.Processor,
+			Customers: stores.Customers,
+		},
+		Reports: &controllers.ReportHandler{
+			Orders:  stores.Orders,
+			Reports: stores.Reports,
+			Log:     logger,
+		},
+		Reviews: &controllers.ReviewHandler{Reviews: stores.Reviews},
+		Tokens: &controllers.TokenHandler{
+			Customers: stores.Customers,
+			Tokens:    stores.Tokens,
+			Issuer:    issuer,
+		},
+		Health: &controllers.HealthHandler{},
+	}
+	a.routes()
+	return a
+}
+
+// Handler returns the router, giving every request the configured deadline.
+func (a *App) Handler() http.Handler {
+	return middlewares.Timeout(a.Config.Server.RequestTimeout.Duration, a.Router)
+}
+
+// RunMaintenance generates a sales report every configured report interval and
+// purges expired tokens every hour, until ctx is done.
+func (a *App) RunMaintenance(ctx context.Context) {
+	reports := time.NewTicker(a.Config.Reports.Interval.Duration)
+	defer reports.Stop()
+	purges := time.NewTicker(tokenPurgeInterval)
+	defer purges.Stop()
+
+	for {
+		select {
+		case <-reports.C:
+			a.Logger.Println("Generating periodic sales report...")
+			a.Reports.GenerateSalesReport(ctx)
+		case <-purges.C:
+			if err := a.Stores.Tokens.PurgeExpiredTokens(ctx); err != nil {
+				a.Logger.Printf("Error purging expired tokens: %v", err.Message)
+			}
+		case <-ctx.Done():
+			a.Logger.Println("Stopped periodic maintenance.")
+			return
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	inmemoryStores "finalProject/InmemoryStores"
+	data "finalProject/StructureData"
+	"finalProject/config"
+)
+
+// Records seeded by newTestApp.
+var (
+	admin = data.Customer{ID: 1, Name: "Ada Admin", Username: "ada", Email: "ada@example.com", Role: data.RoleAdmin}
+	alice = data.Customer{ID: 2, Name: "Alice", Username: "alice", Email: "alice@example.com", Role: data.RoleUser}
+	bob   = data.Customer{ID: 3, Name: "Bob", Username: "bob", Email: "bob@example.com", Role: data.RoleUser}
+
+	tolkien = data.Author{ID: 1, FirstName: "J.R.R.", LastName: "Tolkien", Bio: "Author of The Hobbit."}
+	hobbit  = data.Book{ID: 1, Title: "The Hobbit", Author: tolkien, Genres: []string{"Fantasy"}, Price: 10, Stock: 5}
+)
+
+// newTestApp returns an App over fresh in-memory stores holding admin, alice, bob,
+// tolkien and hobbit.
+func newTestApp(t *testing.T) (*App, inmemoryStores.Stores) {
+	t.Helper()
+	ctx := context.Background()
+	mem := inmemoryStores.NewStores()
+	for _, customer := range []data.Customer{admin, alice, bob} {
+		mem.Customers.AddCustomerDirectly(ctx, customer)
+	}
+	if _, errResp := mem.Authors.CreateAuthor(ctx, tolkien); errResp != nil {
+		t.Fatal(errResp.Message)
+	}
+	if _, errResp := mem.Books.CreateBook(ctx, hobbit); errResp != nil {
+		t.Fatal(errResp.Message)
+	}
+
+	cfg := config.Default()
+	cfg.JWT.Secret = "0123456789abcdef0123456789abcdef"
+	stores := Stores{
+		Books:     mem.Books,
+		Authors:   mem.Authors,
+		Customers: mem.Customers,
+		Orders:    mem.Orders,
+		Processor: mem.Orders,
+		Reviews:   mem.Reviews,
+		Reports:   mem.Reports,
+		Tokens:    mem.Tokens,
+	}
+	return New(cfg, stores, log.New(io.Discard, "", 0)), mem
+}
+
+// tokenFor returns an access token for customer, or "" for no customer.
+func tokenFor(t *testing.T, a *App, customer *data.Customer) string {
+	t.Helper()
+	if customer == nil {
+		return ""
+	}
+	token, err := a.Auth.Issuer.GenerateJWT(customer.ID, customer.Email, customer.Username, customer.Role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// serve sends a request through the App as customer, who may be nil.
+func serve(t *testing.T, a *App, customer *data.Customer, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token := tokenFor(t, a, customer); token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	a.Handler().ServeHTTP(w, r)
+	return w
+}
+
+// placeOrder places an order of quantity copies of hobbit as customer, failing the
+// test if it is refused.
+func placeOrder(t *testing.T, a *App, customer *data.Customer, quantity int) data.Order {
+	t.Helper()
+	body := `{"items":[{"book":{"id":1},"quantity":` + strconv.Itoa(quantity) + `}]}`
+	w := serve(t, a, customer, http.MethodPost, "/orders", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("placing an order: status %d: %s", w.Code, w.Body)
+	}
+	var order data.Order
+	if err := json.NewDecoder(w.Body).Decode(&order); err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+func TestPolicies(t *testing.T) {
+	a, _ := newTestApp(t)
+	order := placeOrder(t, a, &alice, 1)
+	orderPath := "/orders/" + strconv.Itoa(order.ID)
+
+	tests := []struct {
+		name     string
+		customer *data.Customer
+		method   string
+		path     string
+		body     string
+		want     int
+	}{
+		// adminOnly
+		{"no token", nil, http.MethodGet, "/customers", "", http.StatusUnauthorized},
+		{"customer lists customers", &alice, http.MethodGet, "/customers", "", http.StatusForbidden},
+		{"admin lists customers", &admin, http.MethodGet, "/customers", "", http.StatusOK},
+		{"customer creates an author", &alice, http.MethodPost, "/authors", `{"first_name":"A","last_name":"B"}`, http.StatusForbidden},
+		{"customer searches orders", &alice, http.MethodPost, "/orders/search", `{}`, http.StatusForbidden},
+		{"admin searches orders", &admin, http.MethodPost, "/orders/search", `{}`, http.StatusOK},
+
+		// SelfOrAdmin
+		{"customer reads themselves", &alice, http.MethodGet, "/customers/2", "", http.StatusOK},
+		{"customer reads another customer", &bob, http.MethodGet, "/customers/2", "", http.StatusForbidden},
+		{"admin reads a customer", &admin, http.MethodGet, "/customers/2", "", http.StatusOK},
+		{"admin reads a missing customer", &admin, http.MethodGet, "/customers/999", "", http.StatusNotFound},
+
+		// OwnerOrAdmin
+		{"owner reads their order", &alice, http.MethodGet, orderPath, "", http.StatusOK},
+		{"customer reads another's order", &bob, http.MethodGet, orderPath, "", http.StatusForbidden},
+		{"customer reads a missing order", &bob, http.MethodGet, "/orders/999", "", http.StatusForbidden},
+		{"admin reads an order", &admin, http.MethodGet, orderPath, "", http.StatusOK},
+		{"customer reads another's history", &bob, http.MethodGet, orderPath + "/history", "", http.StatusForbidden},
+		{"owner reads their history", &alice, http.MethodGet, orderPath + "/history", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := serve(t, a, tt.customer, tt.method, tt.path, tt.body)
+		if w.Code != tt.want {
+			t.Errorf("%s: %s %s = %d, want %d: %s", tt.name, tt.method, tt.path, w.Code, tt.want, w.Body)
+		}
+	}
+}
+
+func TestCreateOrder(t *testing.T) {
+	a, mem := newTestApp(t)
+	ctx := context.Background()
+
+	// The order is Alice's, whatever customer the body names.
+	w := serve(t, a, &alice, http.MethodPost, "/orders", `{"customer":{"id":3},"items":[{"book":{"id":1},"quantity":2}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var order data.Order
+	if err := json.NewDecoder(w.Body).Decode(&order); err != nil {
+		t.Fatal(err)
+	}
+	if order.Customer.ID != alice.ID || order.Status != data.OrderStatusPending || order.TotalPrice != 20 {
+		t.Errorf("order = customer %d, status %s, total %g; want %d, pending, 20", order.Customer.ID, order.Status, order.TotalPrice, alice.ID)
+	}
+	if book, _ := mem.Books.GetBook(ctx, hobbit.ID); book.Stock != 3 {
+		t.Errorf("stock after the order = %d, want 3", book.Stock)
+	}
+
+	// More copies than are in stock are refused, and take no stock.
+	w = serve(t, a, &alice, http.MethodPost, "/orders", `{"items":[{"book":{"id":1},"quantity":4}]}`)
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), `"reason":"insufficient_stock"`) {
+		t.Errorf("ordering too many copies: status %d, want %d: %s", w.Code, http.StatusConflict, w.Body)
+	}
+	if book, _ := mem.Books.GetBook(ctx, hobbit.ID); book.Stock != 3 {
+		t.Errorf("stock after a refused order = %d, want 3", book.Stock)
+	}
+
+	// Admins may order for another customer.
+	w = serve(t, a, &admin, http.MethodPost, "/orders", `{"customer":{"id":3},"items":[{"book":{"id":1},"quantity":1}]}`)
+	if err := json.NewDecoder(w.Body).Decode(&order); err != nil || order.Customer.ID != bob.ID {
+		t.Errorf("admin ordering for Bob: status %d, customer %d", w.Code, order.Customer.ID)
+	}
+
+	// Ordering for a customer who does not exist is a validation error.
+	w = serve(t, a, &admin, http.MethodPost, "/orders", `{"customer":{"id":999},"items":[{"book":{"id":1},"quantity":1}]}`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"field":"customer.id"`) {
+		t.Errorf("ordering for a missing customer: status %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body)
+	}
+}
+
+func TestUpdateOrder(t *testing.T) {
+	a, _ := newTestApp(t)
+	order := placeOrder(t, a, &alice, 1)
+	path := "/orders/" + strconv.Itoa(order.ID)
+
+	// An admin who names no customer leaves the order with its customer.
+	w := serve(t, a, &admin, http.MethodPut, path, `{"items":[{"book":{"id":1},"quantity":2}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var updated data.Order
+	if err := json.NewDecoder(w.Body).Decode(&updated); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Customer.ID != alice.ID {
+		t.Errorf("customer after an admin update = %d, want %d", updated.Customer.ID, alice.ID)
+	}
+
+	// The owner stays the customer, whatever customer the body names.
+	w = serve(t, a, &alice, http.MethodPut, path, `{"customer":{"id":3},"items":[{"book":{"id":1},"quantity":1}]}`)
+	if err := json.NewDecoder(w.Body).Decode(&updated); err != nil || updated.Customer.ID != alice.ID {
+		t.Errorf("owner naming Bob: status %d, customer %d", w.Code, updated.Customer.ID)
+	}
+}
+
+func TestTransitionOrder(t *testing.T) {
+	a, mem := newTestApp(t)
+	ctx := context.Background()
+	order := placeOrder(t, a, &alice, 2)
+	path := "/orders/" + strconv.Itoa(order.ID) + "/transitions"
+
+	steps := []struct {
+		name     string
+		customer *data.Customer
+		status   string
+		want     int
+	}{
+		{"unknown status", &admin, "lost", http.StatusBadRequest},
+		{"owner pays", &alice, data.OrderStatusPaid, http.StatusForbidden},
+		{"other customer cancels", &bob, data.OrderStatusCancelled, http.StatusForbidden},
+		{"admin skips to delivered", &admin, data.OrderStatusDelivered, http.StatusConflict},
+		{"admin marks paid", &admin, data.OrderStatusPaid, http.StatusOK},
+		{"owner cancels", &alice, data.OrderStatusCancelled, http.StatusOK},
+		{"admin pays a cancelled order", &admin, data.OrderStatusPaid, http.StatusConflict},
+	}
+	for _, step := range steps {
+		w := serve(t, a, step.customer, http.MethodPost, path, `{"status":"`+step.status+`"}`)
+		if w.Code != step.want {
+			t.Errorf("%s: status %d, want %d: %s", step.name, w.Code, step.want, w.Body)
+		}
+	}
+
+	// Cancelling returned the items to stock.
+	if book, _ := mem.Books.GetBook(ctx, hobbit.ID); book.Stock != hobbit.Stock {
+		t.Errorf("stock after cancelling = %d, want %d", book.Stock, hobbit.Stock)
+	}
+	w := serve(t, a, &alice, http.MethodGet, "/orders/"+strconv.Itoa(order.ID)+"/history", "")
+	var history []data.OrderStatusChange
+	if err := json.NewDecoder(w.Body).Decode(&history); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, change := range history {
+		got = append(got, change.ToStatus)
+	}
+	if want := []string{data.OrderStatusPaid, data.OrderStatusCancelled}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("history = %v, want %v", got, want)
+	}
+}
+
+func TestCreateBook(t *testing.T) {
+	a, _ := newTestApp(t)
+
+	// A book by an author who does not exist is a validation error.
+	w := serve(t, a, &admin, http.MethodPost, "/books", `{"title":"Dune","author_id":999,"price":9.99,"stock":1}`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"field":"author_id"`) {
+		t.Errorf("book by a missing author: status %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body)
+	}
+}
+
+func TestCreateReview(t *testing.T) {
+	a, mem := newTestApp(t)
+
+	for _, rating := range []int{0, -3, 6, 9} {
+		body := `{"book_id":1,"rating":` + strconv.Itoa(rating) + `,"review_text":"Hm."}`
+		w := serve(t, a, &alice, http.MethodPost, "/reviews", body)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"field":"rating"`) {
+			t.Errorf("rating %d: status %d, want %d: %s", rating, w.Code, http.StatusBadRequest, w.Body)
+		}
+	}
+	if reviews, _ := mem.Reviews.GetReviewsByBookID(context.Background(), hobbit.ID); len(reviews) != 0 {
+		t.Errorf("%d reviews stored for out of range ratings, want 0", len(reviews))
+	}
+
+	w := serve(t, a, &alice, http.MethodPost, "/reviews", `{"book_id":1,"rating":5,"review_text":"Lovely."}`)
+	if w.Code != http.StatusOK {
+		t.Errorf("rating 5: status %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestBookSearchRoute(t *testing.T) {
+	a, _ := newTestApp(t)
+
+	// GET /books/search is served by the /books/:id route.
+	w := serve(t, a, nil, http.MethodGet, "/books/search?q=hob", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("search: status %d: %s", w.Code, w.Body)
+	}
+	var result struct {
+		Data []data.BookSearchHit `json:"data"`
+		Meta data.ListMeta        `json:"meta"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Data) != 1 || result.Data[0].Book.ID != hobbit.ID || result.Meta.Total != 1 {
+		t.Errorf("search = %+v, want just book %d", result, hobbit.ID)
+	}
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/books/search", http.StatusBadRequest},
+		{"/books/1", http.StatusOK},
+		{"/books/999", http.StatusNotFound},
+		{"/books/searching", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if w := serve(t, a, nil, http.MethodGet, tt.path, ""); w.Code != tt.want {
+			t.Errorf("GET %s = %d, want %d: %s", tt.path, w.Code, tt.want, w.Body)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package app
+
+import (
+	"net/http"
+
+	"finalProject/StructureData"
+	"finalProject/middlewares"
+	"finalProject/utils"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// routes registers every endpoint of the API on a.Router.
+func (a *App) routes() {
+	router := a.Router
+	// Unknown paths get a not_found problem like every other missing resource.
+	router.NotFound = http.HandlerFunc(routeNotFound)
+
+	// Authorization policies shared by the routes below.
+	adminOnly := middlewares.RequireRole(StructureData.RoleAdmin)
+	authenticated := middlewares.Authenticated()
+	customerSelfOrAdmin := middlewares.SelfOrAdmin("id")
+	orderOwnerOrAdmin := middlewares.OwnerOrAdmin("id", a.Orders.OrderOwnerID)
+	reviewOwnerOrAdmin := middlewares.OwnerOrAdmin("id", a.Reviews.ReviewOwnerID)
+
+	router.POST("/login", a.Tokens.GenerateToken)
+	router.POST("/token/refresh", a.Tokens.RefreshToken)
+	router.POST("/logout", a.Auth.Authorize(authenticated, a.Tokens.Logout))
+
+	// Health Routes
+	router.GET("/health/db", a.Auth.Authorize(adminOnly, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		a.Health.GetDatabaseHealth(w, r)
+	}))
+	router.GET("/health/cache", a.Auth.Authorize(adminOnly, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		a.Health.GetCacheStats(w, r)
+	}))
+
+	// Customer Routes
+	router.GET("/customers", a.Auth.Authorize(adminOnly, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		a.Customers.GetAllCustomers(w, r)
+	}))
+	router.GET("/customers/:id", a.Auth.Authorize(customerSelfOrAdmin, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/customers/" + ps.ByName("id")
+		a.Customers.GetCustomerByID(w, r)
+	}))
+	router.POST("/customers", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		a.Customers.CreateCustomer(w, r)
+	})
+	router.PUT("/customers/:id", a.Auth.Authorize(customerSelfOrAdmin, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/customers/" + ps.ByName("id")
+		a.Customers.UpdateCustomer(w, r)
+	}))
+	router.DELETE("/customers/:id", a.Auth.Authorize(customerSelfOrAdmin, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/customers/" + ps.ByName("id")
+		a.Customers.DeleteCustomer(w, r)
+	}))
+	router.POST("/customers/search", a.Auth.Authorize(adminOnly, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		a.Customers.SearchCustomers(w, r)
+	}))
+
+	// Author Routes
+	router.GET("/authors", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		a.Authors.GetAllAuthors(w, r)
+	})
+	router.GET("/authors/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/authors/" + ps.ByName("id")
+		a.Authors.GetAuthorByID(w, r)
+	})
+	router.POST("/authors", a.Auth.Authorize(adminOnly, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		a.Authors.CreateAuthor(w, r)
+	}))
+	router.PUT("/authors/:id", a.Auth.Authorize(adminOnly, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/authors/" + ps.ByName("id")
+		a.Authors.UpdateAuthor(w, r)
+	}))
+	router.DELETE("/authors/:id", a.Auth.Authorize(adminOnly, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/authors/" + ps.ByName("id")
+		a.Authors.DeleteAuthor(w, r)
+	}))
+	router.POST("/authors/search", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		a.Authors.SearchAuthors(w, r)
+	})
+
+	// Book Routes
+	router.GET("/books", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		a.Books.GetAllBooks(w, r)
+	})
+	// GET /books/search shares the :id wildcard; httprouter cannot register both.
+	router.GET("/books/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if ps.ByName("id") == "search" {
+			a.Books.TextSearchBooks(w, r)
+			return
+		}
+		r.URL.Path = "/books/" + ps.ByName("id")
+		a.Books.GetBookByID(w, r)
+	})
+	router.POST("/books", a.Auth.Authorize(adminOnly, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		a.Books.CreateBook(w, r)
+	}))
+	router.PUT("/books/:id", a.Auth.Authorize(adminOnly, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/books/" + ps.ByName("id")
+		a.Books.UpdateBook(w, r)
+	}))
+	router.DELETE("/books/:id", a.Auth.Authorize(adminOnly, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/books/" + ps.ByName("id")
+		a.Books.DeleteBook(w, r)
+	}))
+	router.POST("/books/search", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		a.Books.SearchBooks(w, r)
+	})
+
+	// Order Routes
+	router.GET("/orders", a.Auth.Authorize(adminOnly, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		a.Orders.GetAllOrders(w, r)
+	}))
+	router.GET("/orders/:id", a.Auth.Authorize(orderOwnerOrAdmin, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/orders/" + ps.ByName("id")
+		a.Orders.GetOrderByID(w, r)
+	}))
+	router.POST("/orders", a.Auth.Authorize(authenticated, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		a.Orders.CreateOrder(w, r)
+	}))
+	router.PUT("/orders/:id", a.Auth.Authorize(orderOwnerOrAdmin, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/orders/" + ps.ByName("id")
+		a.Orders.UpdateOrder(w, r)
+	}))
+	router.DELETE("/orders/:id", a.Auth.Authorize(orderOwnerOrAdmin, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/orders/" + ps.ByName("id")
+		a.Orders.DeleteOrder(w, r)
+	}))
+	router.POST("/orders/:id/transitions", a.Auth.Authorize(orderOwnerOrAdmin, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/orders/" + ps.ByName("id")
+		a.Orders.TransitionOrder(w, r)
+	}))
+	router.GET("/orders/:id/history", a.Auth.Authorize(orderOwnerOrAdmin, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/orders/" + ps.ByName("id")
+		a.Orders.GetOrderHistory(w, r)
+	}))
+	// httprouter cannot register /orders/search next to /orders/:id/transitions, so
+	// search is served through the :id wildcard.
+	searchOrders := a.Auth.Authorize(adminOnly, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		a.Orders.SearchOrders(w, r)
+	})
+	router.POST("/orders/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if ps.ByName("id") != "search" {
+			routeNotFound(w, r)
+			return
+		}
+		searchOrders(w, r, ps)
+	})
+
+	// Reports Routes
+	router.GET("/reports/sales", a.Auth.Authorize(adminOnly, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		a.Reports.GetSalesReport(r.Context(), w, r)
+	}))
+	router.POST("/reports/sales/generate", a.Auth.Authorize(adminOnly, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		a.Reports.GenerateSalesReport(r.Context())
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Sales report generated successfully"))
+	}))
+
+	// Review Routes
+	router.POST("/reviews", a.Auth.Authorize(authenticated, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		a.Reviews.CreateReview(w, r)
+	}))
+	// Reviews of a book are listed by its ID in the query string (e.g., /reviews?book_id=1).
+	router.GET("/reviews", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		a.Reviews.GetReviewsByBook(w, r)
+	})
+	router.DELETE("/reviews/:id", a.Auth.Authorize(reviewOwnerOrAdmin, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		r.URL.Path = "/reviews/" + ps.ByName("id")
+		a.Reviews.DeleteReview(w, r)
+	}))
+}
+
+// routeNotFound answers requests for paths the API does not serve.
+func routeNotFound(w http.ResponseWriter, r *http.Request) {
+	utils.WriteError(w, r, StructureData.NotFoundError("No route for %s %s", r.Method, r.URL.Path))
+}
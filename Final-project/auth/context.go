@@ -0,0 +1,53 @@
+package auth
+
+import "context"
+
+// claimsContextKey is the context key under which validated claims are stored.
+type claimsContextKey struct{}
+
+// NewContext returns a copy of ctx carrying the validated claims of the caller.
+func NewContext(ctx context.Context, claims *JWTClaim) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims stored by NewContext, if any.
+func ClaimsFromContext(ctx context.Context) (*JWTClaim, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(*JWTClaim)
+	return claims, ok && claims != nil
+}
+
+// CustomerIDFromContext returns the ID of the authenticated customer.
+func CustomerIDFromContext(ctx context.Context) (int, bool) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	return claims.ID, true
+}
+
+// UsernameFromContext returns the username of the authenticated customer.
+func UsernameFromContext(ctx context.Context) (string, bool) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return claims.Username, true
+}
+
+// EmailFromContext returns the email of the authenticated customer.
+func EmailFromContext(ctx context.Context) (string, bool) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return claims.Email, true
+}
+
+// RoleFromContext returns the role of the authenticated customer.
+func RoleFromContext(ctx context.Context) (string, bool) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return claims.Role, true
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// TokenPair is a short-lived access token together with the opaque refresh token
+// that renews it. Only the refresh token's hash is ever persisted.
+type TokenPair struct {
+	AccessToken      string    `json:"token"`
+	RefreshToken     string    `json:"refresh_token"`
+	AccessJTI        string    `json:"-"`
+	AccessExpiresAt  time.Time `json:"expires_at"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// GenerateTokenPair issues a new access token and a new refresh token for a customer.
+func (issuer *Issuer) GenerateTokenPair(id int, email string, username string, role string) (TokenPair, error) {
+	accessToken, claims, err := issuer.generateJWT(id, email, username, role)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		AccessJTI:        claims.Id,
+		AccessExpiresAt:  time.Unix(claims.ExpiresAt, 0),
+		RefreshExpiresAt: time.Now().Add(issuer.RefreshTokenTTL),
+	}, nil
+}
+
+// HashRefreshToken returns the digest under which a refresh token is stored.
+func HashRefreshToken(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomToken returns n cryptographically random bytes, base64url encoded.
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
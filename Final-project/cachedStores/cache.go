@@ -0,0 +1,228 @@
+// Package cachedStores puts a cache in front of the stores of record. Each cached
+// store implements the same Interfaces store as the store it wraps: reads of a
+// single record are answered from a cache store (the in-memory stores) and loaded
+// from the backing store on a miss, writes go to the backing store and invalidate
+// the cached copy, and queries over many records always go to the backing store.
+package cachedStores
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	data "finalProject/StructureData"
+	"finalProject/config"
+)
+
+// Options bound what a cached store keeps.
+type Options struct {
+	// TTL is how long a record is served from the cache before it is loaded again;
+	// zero keeps records until they are evicted or invalidated.
+	TTL time.Duration
+	// MaxEntries is the most records kept at once; the least recently used record
+	// is evicted to make room. Zero means no limit.
+	MaxEntries int
+}
+
+// NewOptions returns the options set by the cache section of the configuration.
+func NewOptions(cfg config.CacheConfig) Options {
+	return Options{TTL: cfg.TTL.Duration, MaxEntries: cfg.MaxEntries}
+}
+
+// Stats counts how a cached store has served reads of single records.
+type Stats struct {
+	Entries       int    `json:"entries"`
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Evictions     uint64 `json:"evictions"`
+	Expirations   uint64 `json:"expirations"`
+	Invalidations uint64 `json:"invalidations"`
+}
+
+// cacheEntry tracks one record held by the cache store.
+type cacheEntry struct {
+	id      int
+	expires time.Time
+}
+
+// layer keeps the bookkeeping shared by the cached stores: which records the cache
+// store holds, when they expire and in what order they were last used. The records
+// themselves live in the cache store, reached through get, put and remove.
+type layer[T any] struct {
+	opts   Options
+	get    func(ctx context.Context, id int) (T, *data.ErrorResponse)
+	put    func(ctx context.Context, value T)
+	remove func(ctx context.Context, id int) *data.ErrorResponse
+
+	mu      sync.Mutex
+	entries map[int]*list.Element
+	recency *list.List // front is the most recently used
+	// writes is bumped by every write, so a load that raced with one is not cached.
+	writes uint64
+	stats  Stats
+}
+
+func newLayer[T any](opts Options,
+	get func(ctx context.Context, id int) (T, *data.ErrorResponse),
+	put func(ctx context.Context, value T),
+	remove func(ctx context.Context, id int) *data.ErrorResponse) *layer[T] {
+	return &layer[T]{
+		opts:    opts,
+		get:     get,
+		put:     put,
+		remove:  remove,
+		entries: make(map[int]*list.Element),
+		recency: list.New(),
+	}
+}
+
+// read returns the record with the given ID from the cache store, or loads it
+// with load and caches it.
+func (l *layer[T]) read(ctx context.Context, id int, load func(ctx context.Context, id int) (T, *data.ErrorResponse)) (T, *data.ErrorResponse) {
+	l.mu.Lock()
+	if l.fresh(ctx, id) {
+		if value, errResp := l.get(ctx, id); errResp == nil {
+			l.stats.Hits++
+			l.mu.Unlock()
+			return value, nil
+		}
+		l.drop(ctx, id)
+	}
+	l.stats.Misses++
+	writes := l.writes
+	l.mu.Unlock()
+
+	value, errResp := load(ctx, id)
+	if errResp != nil {
+		return value, errResp
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.writes == writes {
+		l.store(ctx, id, value)
+	}
+	return value, nil
+}
+
+// add caches a record just written to the backing store.
+func (l *layer[T]) add(ctx context.Context, id int, value T) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.writes++
+	l.store(ctx, id, value)
+}
+
+// warm caches records read from the backing store, up to the size bound, and
+// returns how many it cached.
+func (l *layer[T]) warm(ctx context.Context, values []T, idOf func(T) int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, value := range values {
+		if l.opts.MaxEntries > 0 && n >= l.opts.MaxEntries {
+			break
+		}
+		l.store(ctx, idOf(value), value)
+		n++
+	}
+	return n
+}
+
+// invalidate drops the records with the given IDs.
+func (l *layer[T]) invalidate(ctx context.Context, ids ...int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.writes++
+	for _, id := range ids {
+		if _, ok := l.entries[id]; ok {
+			l.drop(ctx, id)
+			l.stats.Invalidations++
+		}
+	}
+}
+
+// invalidateFunc drops the records for which match reports true.
+func (l *layer[T]) invalidateFunc(ctx context.Context, match func(value T) bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.writes++
+	for id := range l.entries {
+		if value, errResp := l.get(ctx, id); errResp == nil && match(value) {
+			l.drop(ctx, id)
+			l.stats.Invalidations++
+		}
+	}
+}
+
+// clear drops every record.
+func (l *layer[T]) clear(ctx context.Context) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.writes++
+	for id := range l.entries {
+		l.drop(ctx, id)
+		l.stats.Invalidations++
+	}
+}
+
+// snapshot returns the current statistics.
+func (l *layer[T]) snapshot() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats := l.stats
+	stats.Entries = len(l.entries)
+	return stats
+}
+
+// fresh reports whether the record with the given ID is cached and has not
+// expired, marking it as used. An expired record is dropped. l.mu must be held.
+func (l *layer[T]) fresh(ctx context.Context, id int) bool {
+	elem, ok := l.entries[id]
+	if !ok {
+		return false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		l.drop(ctx, id)
+		l.stats.Expirations++
+		return false
+	}
+	l.recency.MoveToFront(elem)
+	return true
+}
+
+// store puts a record into the cache store, evicting the least recently used
+// records beyond the size bound. l.mu must be held.
+func (l *layer[T]) store(ctx context.Context, id int, value T) {
+	// Stores outlive the request that fills them.
+	ctx = context.WithoutCancel(ctx)
+	l.put(ctx, value)
+
+	var expires time.Time
+	if l.opts.TTL > 0 {
+		expires = time.Now().Add(l.opts.TTL)
+	}
+	if elem, ok := l.entries[id]; ok {
+		elem.Value.(*cacheEntry).expires = expires
+		l.recency.MoveToFront(elem)
+	} else {
+		l.entries[id] = l.recency.PushFront(&cacheEntry{id: id, expires: expires})
+	}
+
+	for l.opts.MaxEntries > 0 && len(l.entries) > l.opts.MaxEntries {
+		l.drop(ctx, l.recency.Back().Value.(*cacheEntry).id)
+		l.stats.Evictions++
+	}
+}
+
+// drop removes a record from the cache store and the bookkeeping. l.mu must be
+// held.
+func (l *layer[T]) drop(ctx context.Context, id int) {
+	if elem, ok := l.entries[id]; ok {
+		l.recency.Remove(elem)
+		delete(l.entries, id)
+	}
+	l.remove(context.WithoutCancel(ctx), id)
+}
@@ -0,0 +1,103 @@
+package cachedStores
+
+import (
+	"context"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+// AuthorCache is the part of a store a CachedAuthorStore keeps its authors in.
+type AuthorCache interface {
+	GetAuthor(ctx context.Context, id int) (data.Author, *data.ErrorResponse)
+	DeleteAuthor(ctx context.Context, id int) *data.ErrorResponse
+	AddAuthorDirectly(ctx context.Context, author data.Author)
+}
+
+// CachedAuthorStore implements the AuthorStore interface over a backing store,
+// caching the authors read by ID. Deleting an author deletes its books too, so
+// their cached copies are dropped from books.
+type CachedAuthorStore struct {
+	backing interfaces.AuthorStore
+	layer   *layer[data.Author]
+	books   *CachedBookStore
+}
+
+// NewCachedAuthorStore returns a AuthorStore that reads through cache to backing
+// and keeps the book cache in step with it.
+func NewCachedAuthorStore(backing interfaces.AuthorStore, cache AuthorCache, books *CachedBookStore, opts Options) *CachedAuthorStore {
+	return &CachedAuthorStore{
+		backing: backing,
+		layer:   newLayer(opts, cache.GetAuthor, cache.AddAuthorDirectly, cache.DeleteAuthor),
+		books:   books,
+	}
+}
+
+// Warm caches the authors of the backing store, up to the size bound, and returns
+// how many it cached.
+func (store *CachedAuthorStore) Warm(ctx context.Context) int {
+	return store.layer.warm(ctx, store.backing.GetAllAuthors(ctx), func(author data.Author) int { return author.ID })
+}
+
+// Invalidate drops the cached copies of authors changed other than through this
+// store.
+func (store *CachedAuthorStore) Invalidate(ctx context.Context, ids ...int) {
+	store.layer.invalidate(ctx, ids...)
+}
+
+// Stats returns the cache statistics of the store.
+func (store *CachedAuthorStore) Stats() Stats {
+	return store.layer.snapshot()
+}
+
+// CreateAuthor creates the author in the backing store and caches it.
+func (store *CachedAuthorStore) CreateAuthor(ctx context.Context, author data.Author) (data.Author, *data.ErrorResponse) {
+	created, errResp := store.backing.CreateAuthor(ctx, author)
+	if errResp != nil {
+		return created, errResp
+	}
+	store.layer.add(ctx, created.ID, created)
+	return created, nil
+}
+
+// GetAuthor returns the cached author, loading it from the backing store on a miss.
+func (store *CachedAuthorStore) GetAuthor(ctx context.Context, id int) (data.Author, *data.ErrorResponse) {
+	return store.layer.read(ctx, id, store.backing.GetAuthor)
+}
+
+// GetAuthorByDetails looks the author up in the backing store.
+func (store *CachedAuthorStore) GetAuthorByDetails(ctx context.Context, firstName, lastName, bio string) (data.Author, *data.ErrorResponse) {
+	return store.backing.GetAuthorByDetails(ctx, firstName, lastName, bio)
+}
+
+// UpdateAuthor updates the author in the backing store and drops the cached copy.
+func (store *CachedAuthorStore) UpdateAuthor(ctx context.Context, id int, author data.Author) (data.Author, *data.ErrorResponse) {
+	defer store.layer.invalidate(ctx, id)
+	return store.backing.UpdateAuthor(ctx, id, author)
+}
+
+// DeleteAuthor deletes the author and its books from the backing store and the
+// caches.
+func (store *CachedAuthorStore) DeleteAuthor(ctx context.Context, id int) *data.ErrorResponse {
+	defer store.layer.invalidate(ctx, id)
+	if errResp := store.backing.DeleteAuthor(ctx, id); errResp != nil {
+		return errResp
+	}
+	store.books.invalidateAuthor(ctx, id)
+	return nil
+}
+
+// GetAllAuthors returns every author of the backing store.
+func (store *CachedAuthorStore) GetAllAuthors(ctx context.Context) []data.Author {
+	return store.backing.GetAllAuthors(ctx)
+}
+
+// ListAuthors returns a page of authors from the backing store.
+func (store *CachedAuthorStore) ListAuthors(ctx context.Context, opts data.ListOptions) ([]data.Author, data.ListMeta, *data.ErrorResponse) {
+	return store.backing.ListAuthors(ctx, opts)
+}
+
+// SearchAuthors searches the backing store.
+func (store *CachedAuthorStore) SearchAuthors(ctx context.Context, criteria data.AuthorSearchCriteria) ([]data.Author, *data.ErrorResponse) {
+	return store.backing.SearchAuthors(ctx, criteria)
+}
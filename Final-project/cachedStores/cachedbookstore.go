@@ -0,0 +1,105 @@
+package cachedStores
+
+import (
+	"context"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+// BookCache is the part of a store a CachedBookStore keeps its books in.
+type BookCache interface {
+	GetBook(ctx context.Context, id int) (data.Book, *data.ErrorResponse)
+	DeleteBook(ctx context.Context, id int) *data.ErrorResponse
+	AddBookDirectly(ctx context.Context, book data.Book)
+}
+
+// CachedBookStore implements the BookStore interface over a backing store,
+// caching the books read by ID.
+type CachedBookStore struct {
+	backing interfaces.BookStore
+	layer   *layer[data.Book]
+}
+
+// NewCachedBookStore returns a BookStore that reads through cache to backing.
+func NewCachedBookStore(backing interfaces.BookStore, cache BookCache, opts Options) *CachedBookStore {
+	return &CachedBookStore{
+		backing: backing,
+		layer:   newLayer(opts, cache.GetBook, cache.AddBookDirectly, cache.DeleteBook),
+	}
+}
+
+// Warm caches the books of the backing store, up to the size bound, and returns
+// how many it cached.
+func (store *CachedBookStore) Warm(ctx context.Context) int {
+	return store.layer.warm(ctx, store.backing.GetAllBooks(ctx), func(book data.Book) int { return book.ID })
+}
+
+// Invalidate drops the cached copies of books changed other than through this
+// store, such as the stock taken by an order or the rating left by a review.
+func (store *CachedBookStore) Invalidate(ctx context.Context, ids ...int) {
+	store.layer.invalidate(ctx, ids...)
+}
+
+// invalidateAuthor drops the cached books of an author.
+func (store *CachedBookStore) invalidateAuthor(ctx context.Context, authorID int) {
+	store.layer.invalidateFunc(ctx, func(book data.Book) bool { return book.Author.ID == authorID })
+}
+
+// Stats returns the cache statistics of the store.
+func (store *CachedBookStore) Stats() Stats {
+	return store.layer.snapshot()
+}
+
+// CreateBook creates the book in the backing store and caches it.
+func (store *CachedBookStore) CreateBook(ctx context.Context, book data.Book) (data.Book, *data.ErrorResponse) {
+	created, errResp := store.backing.CreateBook(ctx, book)
+	if errResp != nil {
+		return created, errResp
+	}
+	store.layer.add(ctx, created.ID, created)
+	return created, nil
+}
+
+// GetBook returns the cached book, loading it from the backing store on a miss.
+func (store *CachedBookStore) GetBook(ctx context.Context, id int) (data.Book, *data.ErrorResponse) {
+	return store.layer.read(ctx, id, store.backing.GetBook)
+}
+
+// UpdateBook updates the book in the backing store and drops the cached copy, so
+// the next read picks up anything the backing store derives, like review stats.
+func (store *CachedBookStore) UpdateBook(ctx context.Context, id int, book data.Book) (data.Book, *data.ErrorResponse) {
+	defer store.layer.invalidate(ctx, id)
+	return store.backing.UpdateBook(ctx, id, book)
+}
+
+// DeleteBook deletes the book from the backing store and the cache.
+func (store *CachedBookStore) DeleteBook(ctx context.Context, id int) *data.ErrorResponse {
+	defer store.layer.invalidate(ctx, id)
+	return store.backing.DeleteBook(ctx, id)
+}
+
+// GetAllBooks returns every book of the backing store.
+func (store *CachedBookStore) GetAllBooks(ctx context.Context) []data.Book {
+	return store.backing.GetAllBooks(ctx)
+}
+
+// ListBooks returns a page of books from the backing store.
+func (store *CachedBookStore) ListBooks(ctx context.Context, opts data.ListOptions) ([]data.Book, data.ListMeta, *data.ErrorResponse) {
+	return store.backing.ListBooks(ctx, opts)
+}
+
+// SearchBooks searches the backing store.
+func (store *CachedBookStore) SearchBooks(ctx context.Context, criteria data.BookSearchCriteria) ([]data.Book, *data.ErrorResponse) {
+	return store.backing.SearchBooks(ctx, criteria)
+}
+
+// SearchBookFacets counts the facets of a search in the backing store.
+func (store *CachedBookStore) SearchBookFacets(ctx context.Context, criteria data.BookSearchCriteria) (data.BookFacets, *data.ErrorResponse) {
+	return store.backing.SearchBookFacets(ctx, criteria)
+}
+
+// TextSearchBooks runs a full-text search in the backing store.
+func (store *CachedBookStore) TextSearchBooks(ctx context.Context, query data.BookTextQuery) ([]data.BookSearchHit, data.ListMeta, *data.ErrorResponse) {
+	return store.backing.TextSearchBooks(ctx, query)
+}
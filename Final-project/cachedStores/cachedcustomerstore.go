@@ -0,0 +1,95 @@
+package cachedStores
+
+import (
+	"context"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+// CustomerCache is the part of a store a CachedCustomerStore keeps its customers in.
+type CustomerCache interface {
+	GetCustomer(ctx context.Context, id int) (data.Customer, *data.ErrorResponse)
+	DeleteCustomer(ctx context.Context, id int) *data.ErrorResponse
+	AddCustomerDirectly(ctx context.Context, customer data.Customer)
+}
+
+// CachedCustomerStore implements the CustomerStore interface over a backing store,
+// caching the customers read by ID.
+type CachedCustomerStore struct {
+	backing interfaces.CustomerStore
+	layer   *layer[data.Customer]
+}
+
+// NewCachedCustomerStore returns a CustomerStore that reads through cache to backing.
+func NewCachedCustomerStore(backing interfaces.CustomerStore, cache CustomerCache, opts Options) *CachedCustomerStore {
+	return &CachedCustomerStore{
+		backing: backing,
+		layer:   newLayer(opts, cache.GetCustomer, cache.AddCustomerDirectly, cache.DeleteCustomer),
+	}
+}
+
+// Warm caches the customers of the backing store, up to the size bound, and returns
+// how many it cached.
+func (store *CachedCustomerStore) Warm(ctx context.Context) int {
+	return store.layer.warm(ctx, store.backing.GetAllCustomers(ctx), func(customer data.Customer) int { return customer.ID })
+}
+
+// Invalidate drops the cached copies of customers changed other than through this
+// store.
+func (store *CachedCustomerStore) Invalidate(ctx context.Context, ids ...int) {
+	store.layer.invalidate(ctx, ids...)
+}
+
+// Stats returns the cache statistics of the store.
+func (store *CachedCustomerStore) Stats() Stats {
+	return store.layer.snapshot()
+}
+
+// CreateCustomer creates the customer in the backing store and caches it.
+func (store *CachedCustomerStore) CreateCustomer(ctx context.Context, customer data.Customer) (data.Customer, *data.ErrorResponse) {
+	created, errResp := store.backing.CreateCustomer(ctx, customer)
+	if errResp != nil {
+		return created, errResp
+	}
+	store.layer.add(ctx, created.ID, created)
+	return created, nil
+}
+
+// GetCustomer returns the cached customer, loading it from the backing store on a miss.
+func (store *CachedCustomerStore) GetCustomer(ctx context.Context, id int) (data.Customer, *data.ErrorResponse) {
+	return store.layer.read(ctx, id, store.backing.GetCustomer)
+}
+
+// GetCustomerByEmail looks the customer up in the backing store; credentials are
+// never cached.
+func (store *CachedCustomerStore) GetCustomerByEmail(ctx context.Context, email string) (data.Customer, *data.ErrorResponse) {
+	return store.backing.GetCustomerByEmail(ctx, email)
+}
+
+// UpdateCustomer updates the customer in the backing store and drops the cached copy.
+func (store *CachedCustomerStore) UpdateCustomer(ctx context.Context, id int, customer data.Customer) (data.Customer, *data.ErrorResponse) {
+	defer store.layer.invalidate(ctx, id)
+	return store.backing.UpdateCustomer(ctx, id, customer)
+}
+
+// DeleteCustomer deletes the customer from the backing store and the cache.
+func (store *CachedCustomerStore) DeleteCustomer(ctx context.Context, id int) *data.ErrorResponse {
+	defer store.layer.invalidate(ctx, id)
+	return store.backing.DeleteCustomer(ctx, id)
+}
+
+// GetAllCustomers returns every customer of the backing store.
+func (store *CachedCustomerStore) GetAllCustomers(ctx context.Context) []data.Customer {
+	return store.backing.GetAllCustomers(ctx)
+}
+
+// ListCustomers returns a page of customers from the backing store.
+func (store *CachedCustomerStore) ListCustomers(ctx context.Context, opts data.ListOptions) ([]data.Customer, data.ListMeta, *data.ErrorResponse) {
+	return store.backing.ListCustomers(ctx, opts)
+}
+
+// SearchCustomers searches the backing store.
+func (store *CachedCustomerStore) SearchCustomers(ctx context.Context, criteria data.CustomerSearchCriteria) ([]data.Customer, *data.ErrorResponse) {
+	return store.backing.SearchCustomers(ctx, criteria)
+}
@@ -0,0 +1,163 @@
+package cachedStores
+
+import (
+	"context"
+	"time"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+// OrderCache is the part of a store a CachedOrderStore keeps its orders in.
+type OrderCache interface {
+	GetOrder(ctx context.Context, id int) (data.Order, *data.ErrorResponse)
+	DeleteOrder(ctx context.Context, id int) *data.ErrorResponse
+	AddOrderDirectly(ctx context.Context, order data.Order)
+}
+
+// CachedOrderStore implements the OrderStore interface over a backing store,
+// caching the orders read by ID.
+type CachedOrderStore struct {
+	backing interfaces.OrderStore
+	layer   *layer[data.Order]
+}
+
+// NewCachedOrderStore returns a OrderStore that reads through cache to backing.
+func NewCachedOrderStore(backing interfaces.OrderStore, cache OrderCache, opts Options) *CachedOrderStore {
+	return &CachedOrderStore{
+		backing: backing,
+		layer:   newLayer(opts, cache.GetOrder, cache.AddOrderDirectly, cache.DeleteOrder),
+	}
+}
+
+// Warm caches the orders of the backing store, up to the size bound, and returns
+// how many it cached.
+func (store *CachedOrderStore) Warm(ctx context.Context) int {
+	return store.layer.warm(ctx, store.backing.GetAllOrders(ctx), func(order data.Order) int { return order.ID })
+}
+
+// Invalidate drops the cached copies of orders changed other than through this
+// store.
+func (store *CachedOrderStore) Invalidate(ctx context.Context, ids ...int) {
+	store.layer.invalidate(ctx, ids...)
+}
+
+// Stats returns the cache statistics of the store.
+func (store *CachedOrderStore) Stats() Stats {
+	return store.layer.snapshot()
+}
+
+// CreateOrder creates the order in the backing store and caches it.
+func (store *CachedOrderStore) CreateOrder(ctx context.Context, order data.Order) (data.Order, *data.ErrorResponse) {
+	created, errResp := store.backing.CreateOrder(ctx, order)
+	if errResp != nil {
+		return created, errResp
+	}
+	store.layer.add(ctx, created.ID, created)
+	return created, nil
+}
+
+// GetOrder returns the cached order, loading it from the backing store on a miss.
+func (store *CachedOrderStore) GetOrder(ctx context.Context, id int) (data.Order, *data.ErrorResponse) {
+	return store.layer.read(ctx, id, store.backing.GetOrder)
+}
+
+// UpdateOrder updates the order in the backing store and drops the cached copy.
+func (store *CachedOrderStore) UpdateOrder(ctx context.Context, id int, order data.Order) (data.Order, *data.ErrorResponse) {
+	defer store.layer.invalidate(ctx, id)
+	return store.backing.UpdateOrder(ctx, id, order)
+}
+
+// DeleteOrder deletes the order from the backing store and the cache.
+func (store *CachedOrderStore) DeleteOrder(ctx context.Context, id int) *data.ErrorResponse {
+	defer store.layer.invalidate(ctx, id)
+	return store.backing.DeleteOrder(ctx, id)
+}
+
+// GetAllOrders returns every order of the backing store.
+func (store *CachedOrderStore) GetAllOrders(ctx context.Context) []data.Order {
+	return store.backing.GetAllOrders(ctx)
+}
+
+// GetOrdersInTimeRange returns the orders of the backing store created in a time
+// range.
+func (store *CachedOrderStore) GetOrdersInTimeRange(ctx context.Context, start, end time.Time) ([]data.Order, error) {
+	return store.backing.GetOrdersInTimeRange(ctx, start, end)
+}
+
+// ListOrders returns a page of orders from the backing store.
+func (store *CachedOrderStore) ListOrders(ctx context.Context, opts data.ListOptions) ([]data.Order, data.ListMeta, *data.ErrorResponse) {
+	return store.backing.ListOrders(ctx, opts)
+}
+
+// SearchOrders searches the backing store.
+func (store *CachedOrderStore) SearchOrders(ctx context.Context, criteria data.OrderSearchCriteria) ([]data.Order, *data.ErrorResponse) {
+	return store.backing.SearchOrders(ctx, criteria)
+}
+
+// CachedOrderProcessor implements the OrderProcessor interface over a backing
+// processor, dropping the cached copies of the orders and books each change touches
+// once the backing processor has made it.
+type CachedOrderProcessor struct {
+	backing interfaces.OrderProcessor
+	orders  *CachedOrderStore
+	books   *CachedBookStore
+}
+
+// NewCachedOrderProcessor returns an OrderProcessor that keeps the caches of orders
+// and books in step with backing.
+func NewCachedOrderProcessor(backing interfaces.OrderProcessor, orders *CachedOrderStore, books *CachedBookStore) *CachedOrderProcessor {
+	return &CachedOrderProcessor{backing: backing, orders: orders, books: books}
+}
+
+// PlaceOrder places the order through the backing processor.
+func (p *CachedOrderProcessor) PlaceOrder(ctx context.Context, order data.Order, allowPartial bool) (data.Order, data.StockLevels, *data.ErrorResponse) {
+	placed, levels, errResp := p.backing.PlaceOrder(ctx, order, allowPartial)
+	if errResp == nil {
+		p.invalidate(ctx, placed.ID, levels)
+	}
+	return placed, levels, errResp
+}
+
+// ReplaceOrder replaces the order through the backing processor.
+func (p *CachedOrderProcessor) ReplaceOrder(ctx context.Context, id int, order data.Order, allowPartial bool) (data.Order, data.StockLevels, *data.ErrorResponse) {
+	replaced, levels, errResp := p.backing.ReplaceOrder(ctx, id, order, allowPartial)
+	if errResp == nil {
+		p.invalidate(ctx, id, levels)
+	}
+	return replaced, levels, errResp
+}
+
+// RemoveOrder removes the order through the backing processor.
+func (p *CachedOrderProcessor) RemoveOrder(ctx context.Context, id int) (data.StockLevels, *data.ErrorResponse) {
+	levels, errResp := p.backing.RemoveOrder(ctx, id)
+	if errResp == nil {
+		p.invalidate(ctx, id, levels)
+	}
+	return levels, errResp
+}
+
+// TransitionOrder moves the order to a new status through the backing processor.
+func (p *CachedOrderProcessor) TransitionOrder(ctx context.Context, id int, to string, actorID int, note string) (data.OrderStatusChange, data.StockLevels, *data.ErrorResponse) {
+	change, levels, errResp := p.backing.TransitionOrder(ctx, id, to, actorID, note)
+	if errResp == nil {
+		p.invalidate(ctx, id, levels)
+	}
+	return change, levels, errResp
+}
+
+// GetOrderHistory returns the status history from the backing processor.
+func (p *CachedOrderProcessor) GetOrderHistory(ctx context.Context, id int) ([]data.OrderStatusChange, *data.ErrorResponse) {
+	return p.backing.GetOrderHistory(ctx, id)
+}
+
+// invalidate drops the cached copies of an order and of the books whose stock a
+// change to it moved.
+func (p *CachedOrderProcessor) invalidate(ctx context.Context, orderID int, levels data.StockLevels) {
+	bookIDs := make([]int, 0, len(levels))
+	for bookID := range levels {
+		bookIDs = append(bookIDs, bookID)
+	}
+	p.books.Invalidate(ctx, bookIDs...)
+	p.orders.Invalidate(ctx, orderID)
+}
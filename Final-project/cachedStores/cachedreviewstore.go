@@ -0,0 +1,54 @@
+package cachedStores
+
+import (
+	"context"
+
+	interfaces "finalProject/Interfaces"
+	data "finalProject/StructureData"
+)
+
+// CachedReviewStore implements the ReviewStore interface over a backing store.
+// Reviews themselves are not cached, but every new or deleted review changes the
+// review stats of its book, so the cached copy of the book is dropped.
+type CachedReviewStore struct {
+	backing interfaces.ReviewStore
+	books   *CachedBookStore
+}
+
+// NewCachedReviewStore returns a ReviewStore that keeps the book cache in step
+// with backing.
+func NewCachedReviewStore(backing interfaces.ReviewStore, books *CachedBookStore) *CachedReviewStore {
+	return &CachedReviewStore{backing: backing, books: books}
+}
+
+// CreateReview creates the review in the backing store.
+func (store *CachedReviewStore) CreateReview(ctx context.Context, review data.Review) (data.Review, *data.ErrorResponse) {
+	created, errResp := store.backing.CreateReview(ctx, review)
+	if errResp == nil {
+		store.books.Invalidate(ctx, created.BookID)
+	}
+	return created, errResp
+}
+
+// GetReview returns the review from the backing store.
+func (store *CachedReviewStore) GetReview(ctx context.Context, id int) (data.Review, *data.ErrorResponse) {
+	return store.backing.GetReview(ctx, id)
+}
+
+// GetReviewsByBookID returns the reviews of a book from the backing store.
+func (store *CachedReviewStore) GetReviewsByBookID(ctx context.Context, bookID int) ([]data.Review, *data.ErrorResponse) {
+	return store.backing.GetReviewsByBookID(ctx, bookID)
+}
+
+// DeleteReview deletes the review from the backing store.
+func (store *CachedReviewStore) DeleteReview(ctx context.Context, id int) *data.ErrorResponse {
+	review, errResp := store.backing.GetReview(ctx, id)
+	if errResp != nil {
+		return errResp
+	}
+	if errResp := store.backing.DeleteReview(ctx, id); errResp != nil {
+		return errResp
+	}
+	store.books.Invalidate(ctx, review.BookID)
+	return nil
+}
@@ -0,0 +1,33 @@
+package cachedStores
+
+import (
+	"context"
+	"log"
+)
+
+// Caches are the cached stores of one application, so they can be warmed,
+// inspected and invalidated together.
+type Caches struct {
+	Books     *CachedBookStore
+	Authors   *CachedAuthorStore
+	Customers *CachedCustomerStore
+	Orders    *CachedOrderStore
+}
+
+// Warm fills every cache from its backing store.
+func (c Caches) Warm(ctx context.Context) {
+	log.Printf("Loaded %d customers into the cache", c.Customers.Warm(ctx))
+	log.Printf("Loaded %d authors into the cache", c.Authors.Warm(ctx))
+	log.Printf("Loaded %d books into the cache", c.Books.Warm(ctx))
+	log.Printf("Loaded %d orders into the cache", c.Orders.Warm(ctx))
+}
+
+// Stats returns the statistics of every cache, keyed by the table it caches.
+func (c Caches) Stats() map[string]Stats {
+	return map[string]Stats{
+		"books":     c.Books.Stats(),
+		"authors":   c.Authors.Stats(),
+		"customers": c.Customers.Stats(),
+		"orders":    c.Orders.Stats(),
+	}
+}
@@ -0,0 +1,41 @@
+package cachedStores
+
+import (
+	"context"
+	"log"
+
+	"finalProject/postgresStores"
+)
+
+// ListenForChanges keeps the caches in step with changes made to the database by
+// other API instances or by hand: every changed book, author, customer or order
+// announced by the database triggers is dropped from its cache, and everything is
+// dropped when notifications may have been missed. It returns when ctx is done, or
+// with an error if it cannot start listening.
+func (c Caches) ListenForChanges(ctx context.Context) error {
+	return postgresStores.ListenForChanges(ctx, c.invalidateChanged, c.invalidateAll)
+}
+
+// invalidateChanged drops the cached copy of a row changed in table.
+func (c Caches) invalidateChanged(ctx context.Context, table string, id int) {
+	switch table {
+	case "books":
+		c.Books.Invalidate(ctx, id)
+	case "authors":
+		c.Authors.Invalidate(ctx, id)
+	case "customers":
+		c.Customers.Invalidate(ctx, id)
+	case "orders":
+		c.Orders.Invalidate(ctx, id)
+	default:
+		log.Printf("Ignoring change notification for table %q", table)
+	}
+}
+
+// invalidateAll empties every cache.
+func (c Caches) invalidateAll(ctx context.Context) {
+	c.Books.layer.clear(ctx)
+	c.Authors.layer.clear(ctx)
+	c.Customers.layer.clear(ctx)
+	c.Orders.layer.clear(ctx)
+}
@@ -0,0 +1,463 @@
+// Package config loads the application settings from defaults, an optional YAML or
+// JSON file, a .env file and the process environment, in increasing order of
+// precedence, and validates them once at startup.
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the complete application configuration.
+type Config struct {
+	Server   ServerConfig   `json:"server" yaml:"server"`
+	Database DatabaseConfig `json:"database" yaml:"database"`
+	JWT      JWTConfig      `json:"jwt" yaml:"jwt"`
+	Reports  ReportsConfig  `json:"reports" yaml:"reports"`
+	Cache    CacheConfig    `json:"cache" yaml:"cache"`
+	Storage  StorageConfig  `json:"storage" yaml:"storage"`
+}
+
+// ServerConfig configures the HTTP server.
+type ServerConfig struct {
+	ListenAddr      string   `json:"listen_addr" yaml:"listen_addr"`
+	ShutdownTimeout Duration `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+	// RequestTimeout is the deadline applied to each request's context; zero disables it.
+	RequestTimeout Duration `json:"request_timeout" yaml:"request_timeout"`
+}
+
+// DatabaseConfig configures the PostgreSQL connection. DSN, when set, takes
+// precedence over the individual connection fields.
+type DatabaseConfig struct {
+	DSN             string   `json:"dsn" yaml:"dsn"`
+	Host            string   `json:"host" yaml:"host"`
+	Port            int      `json:"port" yaml:"port"`
+	User            string   `json:"user" yaml:"user"`
+	Password        string   `json:"password" yaml:"password"`
+	Name            string   `json:"name" yaml:"name"`
+	SSLMode         string   `json:"sslmode" yaml:"sslmode"`
+	MaxOpenConns    int      `json:"max_open_conns" yaml:"max_open_conns"`
+	MaxIdleConns    int      `json:"max_idle_conns" yaml:"max_idle_conns"`
+	ConnMaxLifetime Duration `json:"conn_max_lifetime" yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime Duration `json:"conn_max_idle_time" yaml:"conn_max_idle_time"`
+	// ConnectTimeout bounds how long startup keeps retrying an unreachable database.
+	ConnectTimeout Duration `json:"connect_timeout" yaml:"connect_timeout"`
+	// AutoMigrate applies pending schema migrations when the server starts.
+	AutoMigrate bool `json:"auto_migrate" yaml:"auto_migrate"`
+}
+
+// JWTConfig configures token signing and lifetimes.
+type JWTConfig struct {
+	Secret          string   `json:"secret" yaml:"secret"`
+	AccessTokenTTL  Duration `json:"access_token_ttl" yaml:"access_token_ttl"`
+	RefreshTokenTTL Duration `json:"refresh_token_ttl" yaml:"refresh_token_ttl"`
+}
+
+// ReportsConfig configures the periodic sales report generation.
+type ReportsConfig struct {
+	Interval Duration `json:"interval" yaml:"interval"`
+}
+
+// CacheConfig bounds the in-memory cache in front of the database.
+type CacheConfig struct {
+	// TTL is how long a cached record is served before it is reloaded; zero
+	// keeps records until they are evicted or changed.
+	TTL Duration `json:"ttl" yaml:"ttl"`
+	// MaxEntries is the most records cached per store; zero means no limit.
+	MaxEntries int `json:"max_entries" yaml:"max_entries"`
+}
+
+// Storage backends the API can keep its data in.
+const (
+	// StoragePostgres keeps data in PostgreSQL, behind the cache.
+	StoragePostgres = "postgres"
+	// StorageSQLite keeps data in a single SQLite database file.
+	StorageSQLite = "sqlite"
+	// StorageMemory keeps data in the in-memory stores, which are lost on exit
+	// unless given a data directory.
+	StorageMemory = "memory"
+)
+
+// StorageConfig selects where the API keeps its data.
+type StorageConfig struct {
+	// Backend is StoragePostgres, StorageSQLite or StorageMemory.
+	Backend string `json:"backend" yaml:"backend"`
+	// SQLitePath is the database file of the SQLite backend, created if missing.
+	SQLitePath string `json:"sqlite_path" yaml:"sqlite_path"`
+	// Fixtures is an optional directory of JSON files the memory backend is
+	// seeded from at startup.
+	Fixtures string `json:"fixtures" yaml:"fixtures"`
+	// DataDir is an optional directory the memory backend keeps its write-ahead
+	// log and snapshots in, so that its data survives restarts.
+	DataDir string `json:"data_dir" yaml:"data_dir"`
+	// SnapshotInterval is how often the memory backend snapshots its data to
+	// DataDir, after which the log starts over.
+	SnapshotInterval Duration `json:"snapshot_interval" yaml:"snapshot_interval"`
+}
+
+// Duration is a time.Duration that reads from strings such as "90s" or "24h" in
+// configuration files.
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.Duration.String()), nil
+}
+
+// minJWTSecretLength is the shortest HS256 secret accepted.
+const minJWTSecretLength = 32
+
+// placeholderSecretMarker marks the example secrets of documentation and sample
+// files, which must never sign real tokens.
+const placeholderSecretMarker = "change-me"
+
+// Default returns the configuration used when nothing overrides it.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			ListenAddr:      ":8080",
+			ShutdownTimeout: Duration{10 * time.Second},
+			RequestTimeout:  Duration{30 * time.Second},
+		},
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            5432,
+			User:            "postgres",
+			Name:            "booklibrary",
+			SSLMode:         "disable",
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: Duration{30 * time.Minute},
+			ConnMaxIdleTime: Duration{5 * time.Minute},
+			ConnectTimeout:  Duration{30 * time.Second},
+		},
+		JWT: JWTConfig{
+			AccessTokenTTL:  Duration{1 * time.Hour},
+			RefreshTokenTTL: Duration{30 * 24 * time.Hour},
+		},
+		Reports: ReportsConfig{
+			Interval: Duration{24 * time.Hour},
+		},
+		Cache: CacheConfig{
+			TTL:        Duration{5 * time.Minute},
+			MaxEntries: 10000,
+		},
+		Storage: StorageConfig{
+			Backend:          StoragePostgres,
+			SQLitePath:       "bookstore.db",
+			SnapshotInterval: Duration{5 * time.Minute},
+		},
+	}
+}
+
+// Load builds the configuration. path names an optional YAML (.yaml, .yml) or JSON
+// (.json) file; when empty, the CONFIG_FILE environment variable is consulted.
+// Variables from envFile (typically ".env") fill in anything not already set in
+// the process environment; a missing envFile is not an error.
+func Load(path string, envFile string) (Config, error) {
+	cfg := Default()
+
+	if envFile != "" {
+		if err := loadDotEnv(envFile); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if err := applyEnv(&cfg); err != nil {
+		return Config{}, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// loadFile decodes a YAML or JSON file over cfg, keeping defaults for absent keys.
+func loadFile(path string, cfg *Config) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, cfg)
+	case ".json":
+		err = json.Unmarshal(raw, cfg)
+	default:
+		return fmt.Errorf("config file %s: unsupported extension (use .yaml, .yml or .json)", path)
+	}
+	if err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadDotEnv sets KEY=VALUE pairs from path into the process environment unless
+// the variable is already set.
+func loadDotEnv(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNo)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		if _, exists := os.LookupEnv(key); !exists {
+			os.Setenv(key, value)
+		}
+	}
+	return scanner.Err()
+}
+
+// applyEnv overrides cfg with any of the supported environment variables.
+func applyEnv(cfg *Config) error {
+	strVars := map[string]*string{
+		"LISTEN_ADDR":  &cfg.Server.ListenAddr,
+		"DATABASE_URL": &cfg.Database.DSN,
+		"DB_HOST":      &cfg.Database.Host,
+		"DB_USER":      &cfg.Database.User,
+		"DB_PASSWORD":  &cfg.Database.Password,
+		"DB_NAME":      &cfg.Database.Name,
+		"DB_SSLMODE":   &cfg.Database.SSLMode,
+		"JWT_SECRET":   &cfg.JWT.Secret,
+		"STORAGE":      &cfg.Storage.Backend,
+		"FIXTURES_DIR": &cfg.Storage.Fixtures,
+		"SQLITE_PATH":  &cfg.Storage.SQLitePath,
+		"DATA_DIR":     &cfg.Storage.DataDir,
+	}
+	for name, target := range strVars {
+		if value, ok := os.LookupEnv(name); ok {
+			*target = value
+		}
+	}
+
+	intVars := map[string]*int{
+		"DB_PORT":           &cfg.Database.Port,
+		"DB_MAX_OPEN_CONNS": &cfg.Database.MaxOpenConns,
+		"DB_MAX_IDLE_CONNS": &cfg.Database.MaxIdleConns,
+		"CACHE_MAX_ENTRIES": &cfg.Cache.MaxEntries,
+	}
+	for name, target := range intVars {
+		if value, ok := os.LookupEnv(name); ok {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			*target = parsed
+		}
+	}
+
+	boolVars := map[string]*bool{
+		"DB_AUTO_MIGRATE": &cfg.Database.AutoMigrate,
+	}
+	for name, target := range boolVars {
+		if value, ok := os.LookupEnv(name); ok {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			*target = parsed
+		}
+	}
+
+	durationVars := map[string]*Duration{
+		"SHUTDOWN_TIMEOUT":      &cfg.Server.ShutdownTimeout,
+		"REQUEST_TIMEOUT":       &cfg.Server.RequestTimeout,
+		"DB_CONN_MAX_LIFETIME":  &cfg.Database.ConnMaxLifetime,
+		"DB_CONN_MAX_IDLE_TIME": &cfg.Database.ConnMaxIdleTime,
+		"DB_CONNECT_TIMEOUT":    &cfg.Database.ConnectTimeout,
+		"JWT_ACCESS_TTL":        &cfg.JWT.AccessTokenTTL,
+		"JWT_REFRESH_TTL":       &cfg.JWT.RefreshTokenTTL,
+		"REPORT_INTERVAL":       &cfg.Reports.Interval,
+		"CACHE_TTL":             &cfg.Cache.TTL,
+		"SNAPSHOT_INTERVAL":     &cfg.Storage.SnapshotInterval,
+	}
+	for name, target := range durationVars {
+		if value, ok := os.LookupEnv(name); ok {
+			if err := target.UnmarshalText([]byte(value)); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once.
+func (cfg Config) Validate() error {
+	var problems []string
+	if cfg.Server.ListenAddr == "" {
+		problems = append(problems, "server.listen_addr is required")
+	}
+	if cfg.Server.ShutdownTimeout.Duration <= 0 {
+		problems = append(problems, "server.shutdown_timeout must be positive")
+	}
+	if cfg.Server.RequestTimeout.Duration < 0 {
+		problems = append(problems, "server.request_timeout cannot be negative")
+	}
+
+	db := cfg.Database
+	if db.DSN == "" {
+		if db.User == "" || db.Name == "" {
+			problems = append(problems, "database.user and database.name are required when database.dsn is not set")
+		}
+		if db.Port <= 0 || db.Port > 65535 {
+			problems = append(problems, "database.port must be between 1 and 65535")
+		}
+		switch db.SSLMode {
+		case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+		default:
+			problems = append(problems, fmt.Sprintf("database.sslmode %q is not a valid PostgreSQL sslmode", db.SSLMode))
+		}
+	}
+	if db.MaxOpenConns < 0 || db.MaxIdleConns < 0 {
+		problems = append(problems, "database pool sizes cannot be negative")
+	}
+	if db.MaxOpenConns > 0 && db.MaxIdleConns > db.MaxOpenConns {
+		problems = append(problems, "database.max_idle_conns cannot exceed database.max_open_conns")
+	}
+	if db.ConnMaxLifetime.Duration < 0 || db.ConnMaxIdleTime.Duration < 0 || db.ConnectTimeout.Duration < 0 {
+		problems = append(problems, "database connection lifetimes and timeouts cannot be negative")
+	}
+
+	if len(cfg.JWT.Secret) < minJWTSecretLength {
+		problems = append(problems, fmt.Sprintf("jwt.secret (JWT_SECRET) must be at least %d characters", minJWTSecretLength))
+	} else if strings.Contains(strings.ToLower(cfg.JWT.Secret), placeholderSecretMarker) {
+		problems = append(problems, "jwt.secret (JWT_SECRET) is a placeholder; set a random secret")
+	}
+	if cfg.JWT.AccessTokenTTL.Duration <= 0 || cfg.JWT.RefreshTokenTTL.Duration <= 0 {
+		problems = append(problems, "jwt token lifetimes must be positive")
+	}
+	if cfg.JWT.RefreshTokenTTL.Duration < cfg.JWT.AccessTokenTTL.Duration {
+		problems = append(problems, "jwt.refresh_token_ttl cannot be shorter than jwt.access_token_ttl")
+	}
+
+	if cfg.Reports.Interval.Duration <= 0 {
+		problems = append(problems, "reports.interval must be positive")
+	}
+
+	if cfg.Cache.TTL.Duration < 0 || cfg.Cache.MaxEntries < 0 {
+		problems = append(problems, "cache.ttl and cache.max_entries cannot be negative")
+	}
+
+	switch cfg.Storage.Backend {
+	case StoragePostgres, StorageSQLite:
+		if cfg.Storage.Fixtures != "" {
+			problems = append(problems, "storage.fixtures is only supported by the memory backend")
+		}
+		if cfg.Storage.DataDir != "" {
+			problems = append(problems, "storage.data_dir is only supported by the memory backend")
+		}
+		if cfg.Storage.Backend == StorageSQLite && cfg.Storage.SQLitePath == "" {
+			problems = append(problems, "storage.sqlite_path is required by the sqlite backend")
+		}
+	case StorageMemory:
+		if cfg.Storage.DataDir != "" && cfg.Storage.SnapshotInterval.Duration <= 0 {
+			problems = append(problems, "storage.snapshot_interval must be positive")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("storage.backend %q must be %q, %q or %q", cfg.Storage.Backend, StoragePostgres, StorageSQLite, StorageMemory))
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ConnectionString returns the DSN to hand to the PostgreSQL driver.
+func (db DatabaseConfig) ConnectionString() string {
+	if db.DSN != "" {
+		return db.DSN
+	}
+	parts := []string{
+		"host=" + quoteDSNValue(db.Host),
+		"port=" + strconv.Itoa(db.Port),
+		"user=" + quoteDSNValue(db.User),
+		"dbname=" + quoteDSNValue(db.Name),
+		"sslmode=" + db.SSLMode,
+	}
+	if db.Password != "" {
+		parts = append(parts, "password="+quoteDSNValue(db.Password))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Redacted returns the connection string with any password masked, for logging.
+func (db DatabaseConfig) Redacted() string {
+	if db.DSN != "" {
+		if u, err := url.Parse(db.DSN); err == nil && u.User != nil {
+			if _, hasPassword := u.User.Password(); hasPassword {
+				u.User = url.UserPassword(u.User.Username(), "xxxxx")
+			}
+			return u.String()
+		}
+		return "(dsn)"
+	}
+	masked := db
+	if masked.Password != "" {
+		masked.Password = "xxxxx"
+	}
+	return masked.ConnectionString()
+}
+
+// quoteDSNValue quotes a key/value connection string value when needed.
+func quoteDSNValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
@@ -2,208 +2,275 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"flag"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	controllers "finalProject/Controllers"
+	inmemoryStores "finalProject/InmemoryStores"
+	"finalProject/app"
+	"finalProject/cachedStores"
+	"finalProject/config"
 	"finalProject/postgresStores" // Ensure this import path matches your project structure
-
-	"github.com/julienschmidt/httprouter"
+	"finalProject/sqliteStores"
 )
 
-func initConfig() {
-	// In production, load your credentials from environment variables.
-	if os.Getenv("DB_USER") == "" || os.Getenv("DB_PASSWORD") == "" || os.Getenv("DB_NAME") == "" || os.Getenv("DB_SSLMODE") == "" {
-		log.Println("WARNING: DB configuration not set via environment variables. Falling back to hardcoded connection string.")
+// initConfig loads and validates the configuration, then hands it to the packages
+// that need it. Startup aborts on invalid configuration. It returns the
+// command-line arguments left after the flags.
+func initConfig() (config.Config, []string) {
+	configPath := flag.String("config", "", "optional YAML or JSON config file (defaults to $CONFIG_FILE)")
+	envFile := flag.String("env-file", ".env", "file of KEY=VALUE defaults for environment variables")
+	storage := flag.String("storage", "", "where data is kept: postgres, sqlite or memory (defaults to $STORAGE)")
+	fixtures := flag.String("fixtures", "", "directory of JSON fixtures to seed memory storage from (defaults to $FIXTURES_DIR)")
+	dataDir := flag.String("data-dir", "", "directory memory storage is saved to, so it survives restarts (defaults to $DATA_DIR)")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath, *envFile)
+	if err != nil {
+		log.Fatalf("Configuration error: %v", err)
+	}
+	if *storage != "" || *fixtures != "" || *dataDir != "" {
+		if *storage != "" {
+			cfg.Storage.Backend = *storage
+		}
+		if *fixtures != "" {
+			cfg.Storage.Fixtures = *fixtures
+		}
+		if *dataDir != "" {
+			cfg.Storage.DataDir = *dataDir
+		}
+		if err := cfg.Validate(); err != nil {
+			log.Fatalf("Configuration error: %v", err)
+		}
+	}
+
+	if cfg.Storage.Backend == config.StoragePostgres {
+		postgresStores.Configure(cfg.Database)
+		log.Printf("Using database %s", cfg.Database.Redacted())
 	}
+	return cfg, flag.Args()
 }
 
-func closePostgresConnections() {
-	// Use the public Close() method from each store.
-	if store := postgresStores.GetPostgresCustomerStoreInstance(); store != nil {
-		if err := store.Close(); err != nil {
-			log.Printf("Error closing customer Postgres connection: %v", err)
-		}
+// storage is where the API keeps its data.
+type storage struct {
+	stores app.Stores
+	// health hands the health handler what it reports on.
+	health func(h *controllers.HealthHandler)
+	// close releases the storage once the server has stopped.
+	close func()
+}
+
+// openStorage opens the storage backend selected by the configuration.
+func openStorage(cfg config.Config) storage {
+	switch cfg.Storage.Backend {
+	case config.StorageMemory:
+		return openMemory(cfg)
+	case config.StorageSQLite:
+		return openSQLite(cfg)
 	}
-	if store := postgresStores.GetPostgresAuthorStoreInstance(); store != nil {
-		if err := store.Close(); err != nil {
-			log.Printf("Error closing author Postgres connection: %v", err)
+	return openPostgres(cfg)
+}
+
+// openMemory returns the in-memory stores. With a data directory, they are
+// restored from it and save their changes to it; the configured fixtures are only
+// loaded while it is empty.
+func openMemory(cfg config.Config) storage {
+	mem := inmemoryStores.NewStores()
+	var journal *inmemoryStores.Journal
+	if cfg.Storage.DataDir != "" {
+		log.Printf("Using in-memory storage, saved to %s", cfg.Storage.DataDir)
+		var err error
+		journal, err = inmemoryStores.OpenJournal(cfg.Storage.DataDir, mem)
+		if err != nil {
+			log.Fatalf("Opening data directory: %v", err)
 		}
+	} else {
+		log.Println("Using in-memory storage; all data is lost on exit")
 	}
-	if store := postgresStores.GetPostgresBookStoreInstance(); store != nil {
-		if err := store.Close(); err != nil {
-			log.Printf("Error closing book Postgres connection: %v", err)
+	if cfg.Storage.Fixtures != "" {
+		if journal != nil && journal.Restored() {
+			log.Printf("Not loading fixtures: %s already holds data", cfg.Storage.DataDir)
+		} else if err := mem.LoadFixtures(context.Background(), cfg.Storage.Fixtures); err != nil {
+			log.Fatalf("Loading fixtures: %v", err)
 		}
 	}
-	if store := postgresStores.GetPostgresOrderStoreInstance(); store != nil {
-		if err := store.Close(); err != nil {
-			log.Printf("Error closing order Postgres connection: %v", err)
-		}
+
+	// Snapshot the stores regularly, so that the log replayed at startup stays short.
+	snapshotCtx, stopSnapshots := context.WithCancel(context.Background())
+	if journal != nil {
+		go journal.RunSnapshots(snapshotCtx, cfg.Storage.SnapshotInterval.Duration)
+	}
+
+	return storage{
+		stores: app.Stores{
+			Books:     mem.Books,
+			Authors:   mem.Authors,
+			Customers: mem.Customers,
+			Orders:    mem.Orders,
+			Processor: mem.Orders,
+			Reviews:   mem.Reviews,
+			Reports:   mem.Reports,
+			Tokens:    mem.Tokens,
+		},
+		health: func(h *controllers.HealthHandler) {},
+		close: func() {
+			stopSnapshots()
+			if journal != nil {
+				if err := journal.Close(); err != nil {
+					log.Printf("Error taking the final snapshot: %v", err)
+				}
+			}
+		},
 	}
 }
 
-func main() {
-	// Load configuration.
-	initConfig()
+// openSQLite opens the SQLite database file, creating it and bringing its schema
+// up to date as needed. No cache is put in front of it: reads are local.
+func openSQLite(cfg config.Config) storage {
+	ctx := context.Background()
+	db, err := sqliteStores.Open(ctx, cfg.Storage.SQLitePath)
+	if err != nil {
+		log.Fatalf("Database unavailable: %v", err)
+	}
+	// Only this process uses the file, so there is no one to coordinate a schema
+	// change with.
+	migrator, err := sqliteStores.NewMigrator(db)
+	if err != nil {
+		log.Fatalf("Migrations: %v", err)
+	}
+	if _, err := applyMigrations(ctx, migrator); err != nil {
+		log.Fatalf("Migrating the database: %v", err)
+	}
 
-	// Initialize JSON files and load data into in-memory and PostgreSQL stores.
-	controllers.InitializeCustomerFile()
-	controllers.InitializeAuthorFile()
-	controllers.InitializeBookFile()
-	controllers.InitializeOrderFile()
+	lite := sqliteStores.NewStores(db)
+	return storage{
+		stores: app.Stores{
+			Books:     lite.Books,
+			Authors:   lite.Authors,
+			Customers: lite.Customers,
+			Orders:    lite.Orders,
+			Processor: lite.Orders,
+			Reviews:   lite.Reviews,
+			Reports:   lite.Reports,
+			Tokens:    lite.Tokens,
+		},
+		health: func(h *controllers.HealthHandler) {
+			h.Ping = db.PingContext
+			h.PoolStats = func() (sql.DBStats, bool) { return db.Stats(), true }
+		},
+		close: func() {
+			if err := db.Close(); err != nil {
+				log.Printf("Error closing SQLite database: %v", err)
+			}
+		},
+	}
+}
+
+// openPostgres connects to PostgreSQL and returns its stores behind the caches,
+// which are kept in step with the database and warmed before it returns.
+func openPostgres(cfg config.Config) storage {
+	// Connect to Postgres, waiting for it to come up if necessary.
+	if err := postgresStores.Connect(context.Background()); err != nil {
+		log.Fatalf("Database unavailable: %v", err)
+	}
+	migrateOnStart(cfg)
+	stores, caches := newPostgresStores(cfg)
 
-	// Start periodic sales report generation.
+	// Drop cached rows as soon as anyone changes them in the database. Listening
+	// starts before the caches are warmed so no change slips in between.
+	listenCtx, stopListening := context.WithCancel(context.Background())
 	go func() {
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
-
-		ticker := time.NewTicker(24 * time.Hour)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				log.Println("Generating periodic sales report...")
-				controllers.GenerateSalesReport(ctx)
-			case <-ctx.Done():
-				log.Println("Stopped periodic sales report generation.")
-				return
-			}
+		if err := caches.ListenForChanges(listenCtx); err != nil {
+			log.Printf("Cache invalidation disabled: %v", err)
 		}
 	}()
 
-	// Create a new router.
-	router := httprouter.New()
-
-	router.POST("/login", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-		controllers.GenerateToken(w, r, p)
-	})
-
-	// Customer Routes
-	router.GET("/customers", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		controllers.GetAllCustomers(w, r)
-	})
-	router.GET("/customers/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		r.URL.Path = "/customers/" + ps.ByName("id")
-		controllers.GetCustomerByID(w, r)
-	})
-	router.POST("/customers", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		controllers.CreateCustomer(w, r)
-	})
-	router.PUT("/customers/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		r.URL.Path = "/customers/" + ps.ByName("id")
-		controllers.UpdateCustomer(w, r)
-	})
-	router.DELETE("/customers/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		r.URL.Path = "/customers/" + ps.ByName("id")
-		controllers.DeleteCustomer(w, r)
-	})
-	router.POST("/customers/search", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		controllers.SearchCustomers(w, r)
-	})
-
-	// Author Routes
-	router.GET("/authors", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		controllers.GetAllAuthors(w, r)
-	})
-	router.GET("/authors/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		r.URL.Path = "/authors/" + ps.ByName("id")
-		controllers.GetAuthorByID(w, r)
-	})
-	router.POST("/authors", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		controllers.CreateAuthor(w, r)
-	})
-	router.PUT("/authors/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		r.URL.Path = "/authors/" + ps.ByName("id")
-		controllers.UpdateAuthor(w, r)
-	})
-	router.DELETE("/authors/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		r.URL.Path = "/authors/" + ps.ByName("id")
-		controllers.DeleteAuthor(w, r)
-	})
-	router.POST("/authors/search", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		controllers.SearchAuthors(w, r)
-	})
-
-	// Book Routes
-	router.GET("/books", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		controllers.GetAllBooks(w, r)
-	})
-	router.GET("/books/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		r.URL.Path = "/books/" + ps.ByName("id")
-		controllers.GetBookByID(w, r)
-	})
-	router.POST("/books", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		controllers.CreateBook(w, r)
-	})
-	router.PUT("/books/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		r.URL.Path = "/books/" + ps.ByName("id")
-		controllers.UpdateBook(w, r)
-	})
-	router.DELETE("/books/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		r.URL.Path = "/books/" + ps.ByName("id")
-		controllers.DeleteBook(w, r)
-	})
-	router.POST("/books/search", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		controllers.SearchBooks(w, r)
-	})
-
-	// Order Routes
-	router.GET("/orders", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		controllers.GetAllOrders(w, r)
-	})
-	router.GET("/orders/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		r.URL.Path = "/orders/" + ps.ByName("id")
-		controllers.GetOrderByID(w, r)
-	})
-	router.POST("/orders", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		controllers.CreateOrder(w, r)
-	})
-	router.PUT("/orders/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		r.URL.Path = "/orders/" + ps.ByName("id")
-		controllers.UpdateOrder(w, r)
-	})
-	router.DELETE("/orders/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		r.URL.Path = "/orders/" + ps.ByName("id")
-		controllers.DeleteOrder(w, r)
-	})
-	router.POST("/orders/search", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		controllers.SearchOrders(w, r)
-	})
-
-	// Reports Routes
-	router.GET("/reports/sales", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		ctx := r.Context()
-		controllers.GetSalesReport(ctx, w, r)
-	})
-	router.POST("/reports/sales/generate", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		ctx := r.Context()
-		controllers.GenerateSalesReport(ctx)
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("Sales report generated successfully"))
-	})
-
-	//Review Routes
-	router.POST("/reviews", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		controllers.CreateReview(w, r)
-	})
-	// For getting reviews by book, we assume the book ID is passed as a query parameter (e.g., /reviews?book_id=1)
-	router.GET("/reviews", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		controllers.GetReviewsByBook(w, r)
-	})
-	router.DELETE("/reviews/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		r.URL.Path = "/reviews/" + ps.ByName("id")
-		controllers.DeleteReview(w, r)
-	})
+	// Warm the in-memory caches from PostgreSQL.
+	caches.Warm(context.Background())
+
+	return storage{
+		stores: stores,
+		health: func(h *controllers.HealthHandler) {
+			h.Ping = postgresStores.Ping
+			h.PoolStats = postgresStores.Stats
+			h.CacheStats = caches.Stats
+		},
+		close: func() {
+			stopListening()
+			closePostgresConnections()
+		},
+	}
+}
+
+// newPostgresStores returns the PostgreSQL stores, with reads of single books,
+// authors, customers and orders served from in-memory caches.
+func newPostgresStores(cfg config.Config) (app.Stores, cachedStores.Caches) {
+	opts := cachedStores.NewOptions(cfg.Cache)
+	cacheBooks := inmemoryStores.NewInMemoryBookStore()
+	books := cachedStores.NewCachedBookStore(postgresStores.GetPostgresBookStoreInstance(), cacheBooks, opts)
+	caches := cachedStores.Caches{
+		Books:     books,
+		Authors:   cachedStores.NewCachedAuthorStore(postgresStores.GetPostgresAuthorStoreInstance(), inmemoryStores.NewInMemoryAuthorStore(), books, opts),
+		Customers: cachedStores.NewCachedCustomerStore(postgresStores.GetPostgresCustomerStoreInstance(), inmemoryStores.NewInMemoryCustomerStore(), opts),
+		Orders:    cachedStores.NewCachedOrderStore(postgresStores.GetPostgresOrderStoreInstance(), inmemoryStores.NewInMemoryOrderStore(cacheBooks), opts),
+	}
+
+	stores := app.Stores{
+		Books:     caches.Books,
+		Authors:   caches.Authors,
+		Customers: caches.Customers,
+		Orders:    caches.Orders,
+		Processor: cachedStores.NewCachedOrderProcessor(postgresStores.GetPostgresOrderStoreInstance(), caches.Orders, caches.Books),
+		Reviews:   cachedStores.NewCachedReviewStore(postgresStores.GetPostgresReviewStoreInstance(), caches.Books),
+		Reports:   postgresStores.GetPostgresSalesReportStoreInstance(),
+		Tokens:    postgresStores.GetPostgresTokenStoreInstance(),
+	}
+	return stores, caches
+}
+
+func closePostgresConnections() {
+	// All stores share one pool, so closing it once releases every connection.
+	if err := postgresStores.Close(); err != nil {
+		log.Printf("Error closing Postgres connection pool: %v", err)
+	}
+}
+
+func main() {
+	// Load configuration.
+	cfg, args := initConfig()
+
+	// "migrate ..." manages the schema instead of serving.
+	if len(args) > 0 {
+		if args[0] != "migrate" {
+			log.Fatalf("Unknown command %q; the only command is migrate", args[0])
+		}
+		if err := runMigrate(cfg, args[1:]); err != nil {
+			log.Fatalf("Migrate: %v", err)
+		}
+		return
+	}
+
+	// Open the configured storage.
+	store := openStorage(cfg)
+
+	// Build the API over the stores.
+	application := app.New(cfg, store.stores, log.Default())
+	store.health(application.Health)
+
+	// Start periodic sales reports and token purges.
+	maintenanceCtx, stopMaintenance := context.WithCancel(context.Background())
+	defer stopMaintenance()
+	go application.RunMaintenance(maintenanceCtx)
 
 	// Create and start the HTTP server.
-	server := &http.Server{Addr: ":8080", Handler: router}
+	server := &http.Server{Addr: cfg.Server.ListenAddr, Handler: application.Handler()}
 	go func() {
-		log.Println("Starting server on :8080...")
+		log.Printf("Starting server on %s...", cfg.Server.ListenAddr)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Server failed to start: %v", err)
 		}
@@ -215,14 +282,15 @@ func main() {
 	<-stop
 
 	log.Println("Shutting down server...")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Duration)
 	defer cancel()
 	if err := server.Shutdown(ctx); err != nil {
 		log.Fatalf("Server shutdown failed: %v", err)
 	}
 
-	// Close PostgreSQL connections gracefully.
-	closePostgresConnections()
+	// Release the storage, closing PostgreSQL connections gracefully.
+	stopMaintenance()
+	store.close()
 
 	log.Println("Server exited gracefully.")
 }
@@ -1,32 +1,141 @@
 package middlewares
 
 import (
+	"context"
+	interfaces "finalProject/Interfaces"
+	"finalProject/StructureData"
 	"finalProject/auth"
-	"fmt"
+	"finalProject/utils"
 	"net/http"
+	"strconv"
 	"strings"
+
+	"github.com/julienschmidt/httprouter"
 )
 
-func Auth(next http.HandlerFunc) http.HandlerFunc {
+// Policy decides whether the authenticated caller described by claims may
+// access the route matched by r and ps.
+type Policy func(r *http.Request, ps httprouter.Params, claims *auth.JWTClaim) bool
+
+// OwnerLookup resolves the customer ID that owns the resource with the given ID.
+// The boolean result is false when the resource does not exist.
+type OwnerLookup func(ctx context.Context, id int) (int, bool)
+
+// Authenticator checks the bearer tokens of requests: their signature and expiry
+// against Issuer, and whether they were revoked against Tokens.
+type Authenticator struct {
+	Issuer *auth.Issuer
+	Tokens interfaces.TokenStore
+}
+
+func (a *Authenticator) Auth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		tokenString := r.Header.Get("Authorization")
-		if tokenString == "" {
-			http.Error(w, `{"error": "request does not contain an access token"}`, http.StatusUnauthorized)
+		claims, ok := a.authenticate(w, r)
+		if !ok {
 			return
 		}
 
-		// Remove "Bearer " prefix if it exists
-		tokenParts := strings.Split(tokenString, " ")
-		if len(tokenParts) == 2 {
-			tokenString = tokenParts[1]
+		next(w, r.WithContext(auth.NewContext(r.Context(), claims))) // Call the next handler
+	}
+}
+
+// Authorize authenticates the request and only calls next when policy allows
+// the caller through. Missing or invalid tokens yield 401, denied policies 403.
+// The validated claims are available to next through auth.ClaimsFromContext.
+func (a *Authenticator) Authorize(policy Policy, next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		claims, ok := a.authenticate(w, r)
+		if !ok {
+			return
 		}
 
-		err := auth.ValidateToken(tokenString)
-		if err != nil {
-			http.Error(w, fmt.Sprintf(`{"error": "%s"}`, err.Error()), http.StatusUnauthorized)
+		if !policy(r, ps, claims) {
+			utils.WriteError(w, r, StructureData.ForbiddenError("insufficient permissions"))
 			return
 		}
 
-		next(w, r) // Call the next handler
+		next(w, r.WithContext(auth.NewContext(r.Context(), claims)), ps)
+	}
+}
+
+// authenticate validates the bearer token on r, writing a 401 response when it is
+// missing, invalid or revoked.
+func (a *Authenticator) authenticate(w http.ResponseWriter, r *http.Request) (*auth.JWTClaim, bool) {
+	tokenString := r.Header.Get("Authorization")
+	if tokenString == "" {
+		utils.WriteError(w, r, StructureData.UnauthorizedError("request does not contain an access token"))
+		return nil, false
+	}
+
+	// Remove "Bearer " prefix if it exists
+	tokenParts := strings.Split(tokenString, " ")
+	if len(tokenParts) == 2 {
+		tokenString = tokenParts[1]
+	}
+
+	claims, err := a.Issuer.ValidateToken(tokenString)
+	if err != nil {
+		utils.WriteError(w, r, StructureData.UnauthorizedError(err.Error()))
+		return nil, false
+	}
+
+	// Tokens revoked by logout or refresh rotation are rejected before they expire.
+	if claims.Id != "" {
+		revoked, err := a.Tokens.IsAccessTokenRevoked(r.Context(), claims.Id)
+		if err != nil {
+			utils.WriteError(w, r, StructureData.InternalError("could not verify token"))
+			return nil, false
+		}
+		if revoked {
+			utils.WriteError(w, r, StructureData.UnauthorizedError("token has been revoked"))
+			return nil, false
+		}
+	}
+	return claims, true
+}
+
+// Authenticated allows any caller holding a valid token.
+func Authenticated() Policy {
+	return func(r *http.Request, ps httprouter.Params, claims *auth.JWTClaim) bool {
+		return true
+	}
+}
+
+// RequireRole allows callers whose role claim is one of roles.
+func RequireRole(roles ...string) Policy {
+	return func(r *http.Request, ps httprouter.Params, claims *auth.JWTClaim) bool {
+		for _, role := range roles {
+			if claims.Role == role {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// SelfOrAdmin allows admins, and customers whose ID matches the route parameter param.
+func SelfOrAdmin(param string) Policy {
+	return func(r *http.Request, ps httprouter.Params, claims *auth.JWTClaim) bool {
+		if claims.Role == StructureData.RoleAdmin {
+			return true
+		}
+		id, err := strconv.Atoi(ps.ByName(param))
+		return err == nil && id == claims.ID
+	}
+}
+
+// OwnerOrAdmin allows admins, and customers owning the resource identified by the
+// route parameter param according to owner.
+func OwnerOrAdmin(param string, owner OwnerLookup) Policy {
+	return func(r *http.Request, ps httprouter.Params, claims *auth.JWTClaim) bool {
+		if claims.Role == StructureData.RoleAdmin {
+			return true
+		}
+		id, err := strconv.Atoi(ps.ByName(param))
+		if err != nil {
+			return false
+		}
+		ownerID, found := owner(r.Context(), id)
+		return found && ownerID == claims.ID
 	}
 }
@@ -0,0 +1,21 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Timeout gives every request a deadline of d. Store calls made with the request
+// context are cancelled once the deadline passes or the client disconnects. A d of
+// zero disables the deadline.
+func Timeout(d time.Duration, next http.Handler) http.Handler {
+	if d <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"finalProject/config"
+	"finalProject/migrate"
+	"finalProject/postgresStores"
+	"finalProject/sqliteStores"
+)
+
+const migrateUsage = "usage: migrate up | migrate down [steps] | migrate status"
+
+// runMigrate runs the migrate command with args (up, down [steps] or status)
+// against the configured PostgreSQL or SQLite database.
+func runMigrate(cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	ctx := context.Background()
+	migrator, closeDB, err := openMigrator(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	switch args[0] {
+	case "up":
+		if len(args) != 1 {
+			return errors.New(migrateUsage)
+		}
+		applied, err := applyMigrations(ctx, migrator)
+		if err != nil {
+			return err
+		}
+		if applied == 0 {
+			log.Println("The schema is up to date.")
+		}
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) == 2 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("migrate down: steps must be a positive number")
+			}
+		} else if len(args) > 2 {
+			return errors.New(migrateUsage)
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			log.Printf("Reverted migration %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			log.Println("No migrations are applied.")
+		}
+		return nil
+
+	case "status":
+		if len(args) != 1 {
+			return errors.New(migrateUsage)
+		}
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printMigrationStatus(statuses)
+		return nil
+	}
+	return errors.New(migrateUsage)
+}
+
+// openMigrator connects to the database of the configured backend and returns its
+// Migrator, with a function that closes the connection again.
+func openMigrator(ctx context.Context, cfg config.Config) (*migrate.Migrator, func(), error) {
+	switch cfg.Storage.Backend {
+	case config.StoragePostgres:
+		if err := postgresStores.Connect(ctx); err != nil {
+			return nil, nil, err
+		}
+		migrator, err := postgresStores.NewMigrator()
+		if err != nil {
+			closePostgresConnections()
+			return nil, nil, err
+		}
+		return migrator, closePostgresConnections, nil
+	case config.StorageSQLite:
+		db, err := sqliteStores.Open(ctx, cfg.Storage.SQLitePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		migrator, err := sqliteStores.NewMigrator(db)
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		return migrator, func() { db.Close() }, nil
+	}
+	return nil, nil, fmt.Errorf("migrations only apply to %s and %s storage", config.StoragePostgres, config.StorageSQLite)
+}
+
+// applyMigrations applies the pending migrations, logging each one, and returns
+// how many were applied.
+func applyMigrations(ctx context.Context, migrator *migrate.Migrator) (int, error) {
+	applied, err := migrator.Up(ctx)
+	for _, m := range applied {
+		log.Printf("Applied migration %04d_%s", m.Version, m.Name)
+	}
+	return len(applied), err
+}
+
+// printMigrationStatus writes a table of migrations and their state to stdout.
+func printMigrationStatus(statuses []migrate.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		state, appliedAt := "pending", ""
+		if s.Applied {
+			state, appliedAt = "applied", s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if s.Missing {
+			state = "applied (no file)"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	w.Flush()
+}
+
+// migrateOnStart applies pending migrations when the configuration asks for it,
+// and otherwise warns about them.
+func migrateOnStart(cfg config.Config) {
+	migrator, err := postgresStores.NewMigrator()
+	if err != nil {
+		log.Fatalf("Migrations: %v", err)
+	}
+	ctx := context.Background()
+
+	if cfg.Database.AutoMigrate {
+		if _, err := applyMigrations(ctx, migrator); err != nil {
+			log.Fatalf("Migrating the database: %v", err)
+		}
+		return
+	}
+
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		log.Printf("Could not check for pending migrations: %v", err)
+		return
+	}
+	if len(pending) > 0 {
+		log.Printf("The database schema is %d migrations behind; run `migrate up` or set DB_AUTO_MIGRATE=true", len(pending))
+	}
+}
@@ -0,0 +1,326 @@
+// Package migrate brings a database schema up to date by applying numbered SQL
+// migrations, and can roll them back. Each migration is a pair of files named
+// NNNN_description.up.sql and NNNN_description.down.sql; the versions applied so
+// far are recorded in the schema_migrations table. Every migration runs in a
+// transaction of its own, and a Migrator holds a database lock for the whole run
+// so that several processes migrating at once do not interfere.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Migration is one step of the schema's history.
+type Migration struct {
+	Version int
+	Name    string
+	// Up applies the migration and Down reverts it.
+	Up   string
+	Down string
+}
+
+// Status is the state of one migration in a database.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	// Missing is set for versions recorded as applied that have no migration
+	// files, for example after switching to an older build.
+	Missing bool
+}
+
+// Dialect adapts a Migrator to a database.
+type Dialect struct {
+	// Placeholder returns the bind parameter for the nth argument, counting from 1.
+	Placeholder func(n int) string
+	// Lock blocks until conn holds the lock that keeps other migrators out, and
+	// Unlock releases it. Both are nil if the database needs no lock.
+	Lock   func(ctx context.Context, conn *sql.Conn) error
+	Unlock func(ctx context.Context, conn *sql.Conn) error
+}
+
+// fileName matches migration file names, capturing the version, the description
+// and the direction.
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Load reads the migrations in the root of fsys, ordered by version. Every
+// version needs both an up and a down file.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("reading migrations: %w", err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s: name must look like 0001_description.up.sql", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		if version <= 0 {
+			return nil, fmt.Errorf("migration %s: version must be positive", entry.Name())
+		}
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("reading migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies and reverts migrations on one database.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	dialect    Dialect
+}
+
+// New returns a Migrator for db. migrations must be ordered by version, as Load
+// returns them.
+func New(db *sql.DB, migrations []Migration, dialect Dialect) *Migrator {
+	return &Migrator{db: db, migrations: migrations, dialect: dialect}
+}
+
+// Up applies every pending migration in order and returns those it applied. It
+// refuses to run when a pending migration is older than one already applied, as
+// it would not run in the order it was written for.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		latest := 0
+		for version := range done {
+			if version > latest {
+				latest = version
+			}
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if migration.Version < latest {
+				return fmt.Errorf("migration %04d_%s is pending but the newer version %d is already applied", migration.Version, migration.Name, latest)
+			}
+			if err := m.run(ctx, conn, migration, migration.Up,
+				"INSERT INTO schema_migrations (version, name, applied_at) VALUES ("+m.placeholder(1)+", "+m.placeholder(2)+", "+m.placeholder(3)+")",
+				migration.Version, migration.Name, time.Now().UTC()); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the latest steps applied migrations, newest first, and returns
+// those it reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		versions := make([]int, 0, len(done))
+		for version := range done {
+			versions = append(versions, version)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+		if steps < len(versions) {
+			versions = versions[:steps]
+		}
+
+		for _, version := range versions {
+			migration, ok := m.find(version)
+			if !ok {
+				return fmt.Errorf("migration %d is applied but this build has no files for it", version)
+			}
+			if err := m.run(ctx, conn, migration, migration.Down,
+				"DELETE FROM schema_migrations WHERE version = "+m.placeholder(1), migration.Version); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration and every applied version, ordered by
+// version.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			status := Status{Migration: migration}
+			if record, ok := done[migration.Version]; ok {
+				status.Applied = true
+				status.AppliedAt = record.AppliedAt
+				delete(done, migration.Version)
+			}
+			statuses = append(statuses, status)
+		}
+		for _, record := range done {
+			statuses = append(statuses, record)
+		}
+		sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+		return nil
+	})
+	return statuses, err
+}
+
+// Pending returns the migrations that Up would apply.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// locked runs fn on a connection holding the migration lock, after making sure
+// the schema_migrations table exists.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("connecting: %w", err)
+	}
+	defer conn.Close()
+
+	if m.dialect.Lock != nil {
+		if err := m.dialect.Lock(ctx, conn); err != nil {
+			return fmt.Errorf("taking the migration lock: %w", err)
+		}
+		defer func() {
+			// Release the lock even if ctx has been cancelled meanwhile.
+			if unlockErr := m.dialect.Unlock(context.WithoutCancel(ctx), conn); unlockErr != nil && err == nil {
+				err = fmt.Errorf("releasing the migration lock: %w", unlockErr)
+			}
+		}()
+	}
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    bigint    NOT NULL PRIMARY KEY,
+			name       text      NOT NULL,
+			applied_at timestamp NOT NULL
+		)`); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+// applied returns the versions recorded in schema_migrations, marking those this
+// build has no files for as missing.
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int]Status, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("reading schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	done := map[int]Status{}
+	for rows.Next() {
+		var status Status
+		if err := rows.Scan(&status.Version, &status.Name, &status.AppliedAt); err != nil {
+			return nil, fmt.Errorf("reading schema_migrations: %w", err)
+		}
+		status.Applied = true
+		if _, ok := m.find(status.Version); !ok {
+			status.Missing = true
+		}
+		done[status.Version] = status
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading schema_migrations: %w", err)
+	}
+	return done, nil
+}
+
+// run executes the script of a migration and the statement recording it in one
+// transaction.
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, migration Migration, script string, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return fmt.Errorf("migration %04d_%s: recording it: %w", migration.Version, migration.Name, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// find returns the migration with the given version.
+func (m *Migrator) find(version int) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// placeholder returns the bind parameter for the nth argument.
+func (m *Migrator) placeholder(n int) string {
+	if m.dialect.Placeholder == nil {
+		return "?"
+	}
+	return m.dialect.Placeholder(n)
+}
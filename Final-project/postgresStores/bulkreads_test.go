@@ -0,0 +1,224 @@
+package postgresStores
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"finalProject/StructureData"
+
+	"github.com/lib/pq"
+)
+
+// testDatabaseVar names the environment variable holding the connection string of
+// the database the tests and benchmarks run against; they are skipped without it.
+// The database is migrated to the latest schema, and the records the tests add are
+// deleted again when they finish.
+const testDatabaseVar = "TEST_DATABASE_URL"
+
+// queries counts the statements run through the "postgres-counting" driver.
+var queries atomic.Int64
+
+func init() {
+	sql.Register("postgres-counting", countingDriver{&pq.Driver{}})
+}
+
+// countingDriver is the PostgreSQL driver, counting the statements its
+// connections run in queries.
+type countingDriver struct {
+	driver.Driver
+}
+
+func (d countingDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return countingConn{conn}, nil
+}
+
+// countingConn is a PostgreSQL connection that counts the statements it runs.
+type countingConn struct {
+	driver.Conn
+}
+
+func (c countingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queries.Add(1)
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func (c countingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	queries.Add(1)
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c countingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+// openTestDB opens the database named by TEST_DATABASE_URL through the counting
+// driver and migrates it, or skips tb if the variable is not set.
+func openTestDB(tb testing.TB) *sql.DB {
+	tb.Helper()
+	dsn := os.Getenv(testDatabaseVar)
+	if dsn == "" {
+		tb.Skipf("%s is not set", testDatabaseVar)
+	}
+	db, err := sql.Open("postgres-counting", dsn)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	migrator, err := newMigrator(db)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		tb.Fatalf("migrating the test database: %v", err)
+	}
+	return db
+}
+
+// seedOrders adds a customer and an author with n books, n orders of two books
+// each and n sales reports with one top selling book each, all deleted again when
+// tb finishes.
+func seedOrders(tb testing.TB, db *sql.DB, n int) {
+	tb.Helper()
+	ctx := context.Background()
+	authors := &PostgresAuthorStore{db: db}
+	books := &PostgresBookStore{db: db}
+	customers := &PostgresCustomerStore{DB: db}
+	orders := &PostgresOrderStore{db: db}
+
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	customer, errResp := customers.CreateCustomer(ctx, StructureData.Customer{
+		Name:      "Bulk Reader",
+		Username:  "bulk" + suffix,
+		Email:     "bulk" + suffix + "@example.com",
+		Password:  "not a hash",
+		CreatedAt: time.Now(),
+	})
+	if errResp != nil {
+		tb.Fatal(errResp.Message)
+	}
+	author, errResp := authors.CreateAuthor(ctx, StructureData.Author{FirstName: "Bulk", LastName: suffix})
+	if errResp != nil {
+		tb.Fatal(errResp.Message)
+	}
+	var orderIDs, reportIDs []int
+	tb.Cleanup(func() {
+		db.ExecContext(ctx, `DELETE FROM sales_reports WHERE id = ANY($1)`, intArray(reportIDs))
+		for _, id := range orderIDs {
+			orders.DeleteOrder(ctx, id)
+		}
+		authors.DeleteAuthor(ctx, author.ID)
+		customers.DeleteCustomer(ctx, customer.ID)
+	})
+
+	var items []StructureData.OrderItem
+	for i := 0; i < n; i++ {
+		book, errResp := books.CreateBook(ctx, StructureData.Book{
+			Title:       fmt.Sprintf("Bulk %d", i),
+			Author:      author,
+			Genres:      []string{"Test"},
+			PublishedAt: time.Now(),
+			Price:       10,
+			Stock:       100,
+		})
+		if errResp != nil {
+			tb.Fatal(errResp.Message)
+		}
+		items = append(items, StructureData.OrderItem{Book: book, Quantity: 1, UnitPrice: book.Price, BookTitle: book.Title})
+	}
+	for i := 0; i < n; i++ {
+		order, errResp := orders.CreateOrder(ctx, StructureData.Order{
+			Customer:   customer,
+			Items:      []StructureData.OrderItem{items[i], items[(i+1)%n]},
+			TotalPrice: 20,
+			CreatedAt:  time.Now(),
+			Status:     StructureData.OrderStatusPending,
+		})
+		if errResp != nil {
+			tb.Fatal(errResp.Message)
+		}
+		orderIDs = append(orderIDs, order.ID)
+
+		var reportID int
+		err := db.QueryRowContext(ctx, `
+			INSERT INTO sales_reports (timestamp, total_revenue, total_orders, successful_orders, pending_orders, status_counts)
+			VALUES (now(), 20, 1, 0, 1, '{}') RETURNING id`).Scan(&reportID)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		reportIDs = append(reportIDs, reportID)
+		_, err = db.ExecContext(ctx, `
+			INSERT INTO top_selling_books (sales_report_id, book_id, quantity_sold, total_revenue, book_title, book_price)
+			VALUES ($1, $2, 1, 10, $3, 10)`, reportID, items[i].Book.ID, items[i].BookTitle)
+		if err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+// bulkRead is one of the reads whose cost grows with the data: every book with its
+// author and review statistics, every order with its items, and every sales
+// report with its top selling books.
+type bulkRead struct {
+	name string
+	// queries is how many statements the read runs, however many records it reads.
+	queries int64
+	run     func(ctx context.Context, db *sql.DB) int
+}
+
+var bulkReads = []bulkRead{
+	{"GetAllBooks", 1, func(ctx context.Context, db *sql.DB) int {
+		return len((&PostgresBookStore{db: db}).GetAllBooks(ctx))
+	}},
+	{"GetAllOrders", 2, func(ctx context.Context, db *sql.DB) int {
+		return len((&PostgresOrderStore{db: db}).GetAllOrders(ctx))
+	}},
+	{"SearchOrders", 2, func(ctx context.Context, db *sql.DB) int {
+		orders, _ := (&PostgresOrderStore{db: db}).SearchOrders(ctx, StructureData.OrderSearchCriteria{})
+		return len(orders)
+	}},
+	{"GetAllSalesReports", 2, func(ctx context.Context, db *sql.DB) int {
+		reports, _ := (&PostgresSalesReportStore{db: db}).GetAllSalesReports(ctx)
+		return len(reports)
+	}},
+}
+
+// TestBulkReadsQueryCount checks that the bulk reads run the same number of
+// queries however many records they read, rather than one or more per record.
+func TestBulkReadsQueryCount(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	for _, n := range []int{2, 20} {
+		seedOrders(t, db, n)
+		for _, read := range bulkReads {
+			queries.Store(0)
+			records := read.run(ctx, db)
+			if got := queries.Load(); got != read.queries {
+				t.Errorf("%s ran %d queries for %d records, want %d", read.name, got, records, read.queries)
+			}
+		}
+	}
+}
+
+func BenchmarkBulkReads(b *testing.B) {
+	db := openTestDB(b)
+	seedOrders(b, db, 100)
+	ctx := context.Background()
+	for _, read := range bulkReads {
+		b.Run(read.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				read.run(ctx, db)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package postgresStores
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"finalProject/config"
+
+	_ "github.com/lib/pq"
+)
+
+// All PostgreSQL stores share one connection pool. It is opened by Connect at
+// startup and closed by Close at shutdown.
+var (
+	dbConfig *config.DatabaseConfig
+	sharedDB *sql.DB
+	dbMu     sync.Mutex
+)
+
+const (
+	initialConnectBackoff = 500 * time.Millisecond
+	maxConnectBackoff     = 5 * time.Second
+)
+
+// Configure sets the database settings used by all PostgreSQL stores.
+func Configure(cfg config.DatabaseConfig) {
+	dbConfig = &cfg
+}
+
+// Connect opens the shared connection pool and waits until Postgres answers a
+// ping, retrying with exponential backoff for up to the configured connect
+// timeout. It is a no-op once connected.
+func Connect(ctx context.Context) error {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+	if sharedDB != nil {
+		return nil
+	}
+	if dbConfig == nil {
+		return fmt.Errorf("postgresStores.Configure must be called before Connect")
+	}
+
+	db, err := sql.Open("postgres", dbConfig.ConnectionString())
+	if err != nil {
+		return fmt.Errorf("opening Postgres connection pool: %w", err)
+	}
+	db.SetMaxOpenConns(dbConfig.MaxOpenConns)
+	db.SetMaxIdleConns(dbConfig.MaxIdleConns)
+	db.SetConnMaxLifetime(dbConfig.ConnMaxLifetime.Duration)
+	db.SetConnMaxIdleTime(dbConfig.ConnMaxIdleTime.Duration)
+
+	if timeout := dbConfig.ConnectTimeout.Duration; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	backoff := initialConnectBackoff
+	for attempt := 1; ; attempt++ {
+		err = db.PingContext(ctx)
+		if err == nil {
+			break
+		}
+		log.Printf("Postgres at %s not reachable (attempt %d): %v; retrying in %s", dbConfig.Redacted(), attempt, err, backoff)
+		select {
+		case <-ctx.Done():
+			db.Close()
+			return fmt.Errorf("connecting to Postgres at %s: gave up after %d attempts: %w", dbConfig.Redacted(), attempt, err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxConnectBackoff {
+			backoff = maxConnectBackoff
+		}
+	}
+
+	sharedDB = db
+	log.Printf("Connected to Postgres (max open connections %d, max idle %d).", dbConfig.MaxOpenConns, dbConfig.MaxIdleConns)
+	return nil
+}
+
+// Close closes the shared connection pool.
+func Close() error {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+	if sharedDB == nil {
+		return nil
+	}
+	err := sharedDB.Close()
+	sharedDB = nil
+	return err
+}
+
+// Stats returns the shared pool's statistics, or false if it is not connected.
+func Stats() (sql.DBStats, bool) {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+	if sharedDB == nil {
+		return sql.DBStats{}, false
+	}
+	return sharedDB.Stats(), true
+}
+
+// Ping checks that the shared pool can still reach Postgres.
+func Ping(ctx context.Context) error {
+	dbMu.Lock()
+	db := sharedDB
+	dbMu.Unlock()
+	if db == nil {
+		return fmt.Errorf("not connected to Postgres")
+	}
+	return db.PingContext(ctx)
+}
+
+// getDB returns the shared pool for a store singleton, connecting first if Connect
+// has not been called yet.
+func getDB() *sql.DB {
+	if err := Connect(context.Background()); err != nil {
+		panic(err)
+	}
+	dbMu.Lock()
+	defer dbMu.Unlock()
+	return sharedDB
+}
@@ -0,0 +1,124 @@
+package postgresStores
+
+import (
+	"fmt"
+	"strings"
+
+	"finalProject/StructureData"
+
+	"github.com/lib/pq"
+)
+
+// Filter columns map each field of a StructureData filter field table to its SQL
+// expression, with %[1]s standing for the table alias. Nullable columns are
+// coalesced so that NOT behaves as it does in the in-memory stores.
+var (
+	bookFilterColumns = map[string]string{
+		"id":           "%[1]s.id",
+		"title":        "%[1]s.title",
+		"genres":       "COALESCE(%[1]s.genres, '{}')",
+		"published_at": "%[1]s.published_at",
+		"price":        "%[1]s.price",
+		"stock":        "%[1]s.stock",
+		"author_id":    "%[1]s.author_id",
+		"author_name":  "COALESCE((SELECT TRIM(fa.first_name || ' ' || fa.last_name) FROM authors fa WHERE fa.id = %[1]s.author_id), '')",
+	}
+	authorFilterColumns = map[string]string{
+		"id":         "%[1]s.id",
+		"first_name": "%[1]s.first_name",
+		"last_name":  "%[1]s.last_name",
+		"bio":        "COALESCE(%[1]s.bio, '')",
+	}
+	customerFilterColumns = map[string]string{
+		"id":          "%[1]s.id",
+		"name":        "%[1]s.name",
+		"email":       "%[1]s.email",
+		"username":    "COALESCE(%[1]s.username, '')",
+		"role":        "%[1]s.role",
+		"created_at":  "%[1]s.created_at",
+		"street":      "COALESCE(%[1]s.street, '')",
+		"city":        "COALESCE(%[1]s.city, '')",
+		"state":       "COALESCE(%[1]s.state, '')",
+		"postal_code": "COALESCE(%[1]s.postal_code, '')",
+		"country":     "COALESCE(%[1]s.country, '')",
+	}
+	orderFilterColumns = map[string]string{
+		"id":          "%[1]s.id",
+		"customer_id": "%[1]s.customer_id",
+		"total_price": "%[1]s.total_price",
+		"created_at":  "%[1]s.created_at",
+		"status":      "%[1]s.status",
+	}
+)
+
+// addFilter adds the condition expressed by filter, parsed with
+// utils.ParseFilter, on the row aliased alias. fields and columns are the filter
+// field table and filter columns of the row's table. A nil filter adds nothing.
+func (w *whereClause) addFilter(filter *StructureData.Filter, fields, columns map[string]string, alias string) {
+	if filter != nil {
+		w.conds = append(w.conds, w.filterSQL(filter, fields, columns, alias))
+	}
+}
+
+func (w *whereClause) filterSQL(f *StructureData.Filter, fields, columns map[string]string, alias string) string {
+	switch {
+	case len(f.And) > 0 || len(f.Or) > 0:
+		nodes, op := f.And, " AND "
+		if len(f.Or) > 0 {
+			nodes, op = f.Or, " OR "
+		}
+		parts := make([]string, len(nodes))
+		for i := range nodes {
+			parts[i] = w.filterSQL(&nodes[i], fields, columns, alias)
+		}
+		return "(" + strings.Join(parts, op) + ")"
+	case f.Not != nil:
+		return "NOT " + w.filterSQL(f.Not, fields, columns, alias)
+	}
+
+	column := fmt.Sprintf(columns[f.Field], alias)
+	fieldType := fields[f.Field]
+	if fieldType == StructureData.FilterStringList {
+		switch f.Op {
+		case StructureData.FilterOpEq:
+			return fmt.Sprintf("(%s = ANY(%s))", w.bind(f.Value), column)
+		case StructureData.FilterOpIn:
+			values := make([]string, len(f.Values))
+			for i, v := range f.Values {
+				values[i], _ = v.(string)
+			}
+			return fmt.Sprintf("(%s && %s)", column, w.bind(pq.Array(values)))
+		default: // contains
+			return fmt.Sprintf("EXISTS (SELECT 1 FROM unnest(%s) AS e WHERE e ILIKE %s)", column, w.bind(containsPattern(f.Value.(string))))
+		}
+	}
+
+	// Numbers are compared as numeric so that fractional bounds work on integer
+	// columns.
+	bindValue := func(v interface{}) string {
+		if fieldType == StructureData.FilterNumber {
+			return w.bind(v) + "::numeric"
+		}
+		return w.bind(v)
+	}
+	switch f.Op {
+	case StructureData.FilterOpEq:
+		return fmt.Sprintf("(%s = %s)", column, bindValue(f.Value))
+	case StructureData.FilterOpIn:
+		placeholders := make([]string, len(f.Values))
+		for i, v := range f.Values {
+			placeholders[i] = bindValue(v)
+		}
+		return fmt.Sprintf("(%s IN (%s))", column, strings.Join(placeholders, ", "))
+	case StructureData.FilterOpContains:
+		return fmt.Sprintf("(%s ILIKE %s)", column, w.bind(containsPattern(f.Value.(string))))
+	}
+	var bounds []string
+	if f.Min != nil {
+		bounds = append(bounds, fmt.Sprintf("%s >= %s", column, bindValue(f.Min)))
+	}
+	if f.Max != nil {
+		bounds = append(bounds, fmt.Sprintf("%s <= %s", column, bindValue(f.Max)))
+	}
+	return "(" + strings.Join(bounds, " AND ") + ")"
+}
@@ -0,0 +1,65 @@
+package postgresStores
+
+import (
+	"fmt"
+	"strings"
+
+	"finalProject/StructureData"
+)
+
+// pageClauses returns the SQL that selects the page described by opts: a keyset
+// condition continuing after opts.After ("" when the page starts at an offset)
+// and the ORDER BY, LIMIT and OFFSET tail. One row more than opts.Limit is
+// requested so the caller can tell whether another page follows.
+//
+// columns maps each sort field to its SQL expression. args holds the arguments
+// the query already uses; it is returned with the cursor values appended.
+func pageClauses(opts StructureData.ListOptions, columns map[string]string, args []interface{}) (string, string, []interface{}) {
+	var where string
+	if opts.After != nil {
+		placeholders := make([]string, len(opts.After))
+		for i, value := range opts.After {
+			args = append(args, value)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+
+		// (a > x) OR (a = x AND b > y) OR ..., with < for descending fields.
+		disjuncts := make([]string, len(opts.Sort))
+		for i, field := range opts.Sort {
+			conjuncts := make([]string, 0, i+1)
+			for j := 0; j < i; j++ {
+				conjuncts = append(conjuncts, columns[opts.Sort[j].Field]+" = "+placeholders[j])
+			}
+			op := " > "
+			if field.Desc {
+				op = " < "
+			}
+			conjuncts = append(conjuncts, columns[field.Field]+op+placeholders[i])
+			disjuncts[i] = "(" + strings.Join(conjuncts, " AND ") + ")"
+		}
+		where = "(" + strings.Join(disjuncts, " OR ") + ")"
+	}
+
+	order := make([]string, len(opts.Sort))
+	for i, field := range opts.Sort {
+		if field.Desc {
+			order[i] = columns[field.Field] + " DESC"
+		} else {
+			order[i] = columns[field.Field] + " ASC"
+		}
+	}
+	tail := fmt.Sprintf(" ORDER BY %s LIMIT %d", strings.Join(order, ", "), opts.Limit+1)
+	if opts.After == nil && opts.Offset > 0 {
+		tail += fmt.Sprintf(" OFFSET %d", opts.Offset)
+	}
+	return where, tail, args
+}
+
+// pageQuery appends the clauses from pageClauses to a SELECT without WHERE.
+func pageQuery(selectFrom string, opts StructureData.ListOptions, columns map[string]string) (string, []interface{}) {
+	where, tail, args := pageClauses(opts, columns, nil)
+	if where != "" {
+		selectFrom += " WHERE " + where
+	}
+	return selectFrom + tail, args
+}
@@ -0,0 +1,61 @@
+package postgresStores
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"strconv"
+
+	"finalProject/migrate"
+)
+
+// migrationFiles holds the schema's migrations, compiled into the binary.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockKey identifies the session-level advisory lock held while
+// migrating, so that instances starting together migrate one at a time.
+const migrationLockKey = 4207160021
+
+// Migrations returns the schema's migrations, ordered by version.
+func Migrations() ([]migrate.Migration, error) {
+	files, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.Load(files)
+}
+
+// NewMigrator returns a Migrator for the shared pool, which Connect must have
+// opened.
+func NewMigrator() (*migrate.Migrator, error) {
+	dbMu.Lock()
+	db := sharedDB
+	dbMu.Unlock()
+	if db == nil {
+		return nil, fmt.Errorf("not connected to Postgres")
+	}
+	return newMigrator(db)
+}
+
+// newMigrator returns a Migrator for db.
+func newMigrator(db *sql.DB) (*migrate.Migrator, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(db, migrations, migrate.Dialect{
+		Placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+		Lock: func(ctx context.Context, conn *sql.Conn) error {
+			_, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey)
+			return err
+		},
+		Unlock: func(ctx context.Context, conn *sql.Conn) error {
+			_, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+			return err
+		},
+	}), nil
+}
@@ -0,0 +1,18 @@
+-- Drops every table of the initial schema, and with them all their data.
+DROP TABLE IF EXISTS public.top_selling_books;
+DROP TABLE IF EXISTS public.sales_reports;
+DROP TABLE IF EXISTS public.reviews;
+DROP TABLE IF EXISTS public.order_items;
+DROP TABLE IF EXISTS public.orders;
+DROP TABLE IF EXISTS public.customers;
+DROP TABLE IF EXISTS public.books;
+DROP TABLE IF EXISTS public.authors;
+
+DROP SEQUENCE IF EXISTS public.authors_id_seq;
+DROP SEQUENCE IF EXISTS public.books_id_seq;
+DROP SEQUENCE IF EXISTS public.customers_id_seq;
+DROP SEQUENCE IF EXISTS public.orders_id_seq;
+DROP SEQUENCE IF EXISTS public.order_items_id_seq;
+DROP SEQUENCE IF EXISTS public.reviews_id_seq;
+DROP SEQUENCE IF EXISTS public.sales_reports_id_seq;
+DROP SEQUENCE IF EXISTS public.top_selling_books_id_seq;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS public.revoked_tokens;
+DROP TABLE IF EXISTS public.refresh_tokens;
+
+ALTER TABLE public.customers DROP CONSTRAINT IF EXISTS customers_role_check;
+ALTER TABLE public.customers DROP COLUMN IF EXISTS role;
@@ -0,0 +1,44 @@
+-- Customer roles for authorization, and the refresh token and access token
+-- revocation tables.
+
+ALTER TABLE public.customers ADD COLUMN IF NOT EXISTS role text NOT NULL DEFAULT 'user';
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'customers_role_check') THEN
+        ALTER TABLE public.customers
+            ADD CONSTRAINT customers_role_check CHECK (role IN ('user', 'admin'));
+    END IF;
+END
+$$;
+
+-- Table: public.refresh_tokens
+-- Opaque refresh tokens are stored by SHA-256 hash only and rotated on every use.
+CREATE TABLE IF NOT EXISTS public.refresh_tokens (
+    id           serial       NOT NULL,
+    customer_id  integer      NOT NULL,
+    token_hash   text         NOT NULL,
+    access_jti   text,
+    expires_at   timestamptz  NOT NULL,
+    created_at   timestamptz  NOT NULL DEFAULT now(),
+    revoked_at   timestamptz,
+    replaced_by  integer,
+    CONSTRAINT refresh_tokens_pkey PRIMARY KEY (id),
+    CONSTRAINT refresh_tokens_token_hash_key UNIQUE (token_hash),
+    CONSTRAINT refresh_tokens_customer_id_fkey FOREIGN KEY (customer_id)
+        REFERENCES public.customers (id) ON UPDATE NO ACTION ON DELETE CASCADE,
+    CONSTRAINT refresh_tokens_replaced_by_fkey FOREIGN KEY (replaced_by)
+        REFERENCES public.refresh_tokens (id) ON UPDATE NO ACTION ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_customer_id
+    ON public.refresh_tokens (customer_id);
+
+-- Table: public.revoked_tokens
+-- Access token IDs (jti) rejected by the auth middleware until they would have expired.
+CREATE TABLE IF NOT EXISTS public.revoked_tokens (
+    jti          text         NOT NULL,
+    expires_at   timestamptz  NOT NULL,
+    revoked_at   timestamptz  NOT NULL DEFAULT now(),
+    CONSTRAINT revoked_tokens_pkey PRIMARY KEY (jti)
+);
@@ -0,0 +1,6 @@
+ALTER TABLE public.sales_reports DROP COLUMN IF EXISTS status_counts;
+
+DROP TABLE IF EXISTS public.order_status_history;
+
+ALTER TABLE public.orders DROP CONSTRAINT IF EXISTS orders_status_check;
+ALTER TABLE public.books DROP CONSTRAINT IF EXISTS books_stock_check;
@@ -0,0 +1,53 @@
+-- Order statuses and their history, stock that cannot go negative, and the count
+-- of orders in each status kept with every sales report.
+--
+-- The checks are added without scanning existing rows first, then validated;
+-- rows that break them are reported with a warning rather than failing the
+-- migration, and the checks still hold for every row written from now on.
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'books_stock_check') THEN
+        ALTER TABLE public.books
+            ADD CONSTRAINT books_stock_check CHECK (stock >= 0) NOT VALID;
+    END IF;
+    BEGIN
+        ALTER TABLE public.books VALIDATE CONSTRAINT books_stock_check;
+    EXCEPTION WHEN check_violation THEN
+        RAISE WARNING 'books_stock_check is not validated: some books have negative stock';
+    END;
+
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'orders_status_check') THEN
+        ALTER TABLE public.orders
+            ADD CONSTRAINT orders_status_check CHECK (status IN
+                ('pending', 'paid', 'success', 'shipped', 'delivered', 'cancelled', 'refunded')) NOT VALID;
+    END IF;
+    BEGIN
+        ALTER TABLE public.orders VALIDATE CONSTRAINT orders_status_check;
+    EXCEPTION WHEN check_violation THEN
+        RAISE WARNING 'orders_status_check is not validated: some orders have an unknown status';
+    END;
+END
+$$;
+
+-- Table: public.order_status_history
+-- One row per status transition; changed_by is the customer who made it.
+CREATE TABLE IF NOT EXISTS public.order_status_history (
+    id           serial       NOT NULL,
+    order_id     integer      NOT NULL,
+    from_status  text         NOT NULL,
+    to_status    text         NOT NULL,
+    changed_at   timestamptz  NOT NULL DEFAULT now(),
+    changed_by   integer,
+    note         text         NOT NULL DEFAULT '',
+    CONSTRAINT order_status_history_pkey PRIMARY KEY (id),
+    CONSTRAINT order_status_history_order_id_fkey FOREIGN KEY (order_id)
+        REFERENCES public.orders (id) ON UPDATE NO ACTION ON DELETE CASCADE,
+    CONSTRAINT order_status_history_changed_by_fkey FOREIGN KEY (changed_by)
+        REFERENCES public.customers (id) ON UPDATE NO ACTION ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order_id
+    ON public.order_status_history (order_id);
+
+ALTER TABLE public.sales_reports ADD COLUMN IF NOT EXISTS status_counts jsonb NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+ALTER TABLE public.order_items DROP COLUMN IF EXISTS author_name;
+ALTER TABLE public.order_items DROP COLUMN IF EXISTS book_title;
+ALTER TABLE public.order_items DROP COLUMN IF EXISTS unit_price;
@@ -0,0 +1,22 @@
+-- Book details captured on each order item when the order is placed, so orders
+-- keep their prices when books change. Items already on record take the current
+-- details of their book; items whose book is gone are left at zero and blank.
+
+ALTER TABLE public.order_items ADD COLUMN IF NOT EXISTS unit_price  numeric(10,2);
+ALTER TABLE public.order_items ADD COLUMN IF NOT EXISTS book_title  text;
+ALTER TABLE public.order_items ADD COLUMN IF NOT EXISTS author_name text NOT NULL DEFAULT '';
+
+UPDATE public.order_items oi
+SET unit_price  = COALESCE(oi.unit_price, b.price),
+    book_title  = COALESCE(oi.book_title, b.title),
+    author_name = CASE WHEN oi.author_name = '' THEN COALESCE(trim(a.first_name || ' ' || a.last_name), '') ELSE oi.author_name END
+FROM public.books b
+LEFT JOIN public.authors a ON a.id = b.author_id
+WHERE b.id = oi.book_id
+  AND (oi.unit_price IS NULL OR oi.book_title IS NULL OR oi.author_name = '');
+
+UPDATE public.order_items SET unit_price = 0 WHERE unit_price IS NULL;
+UPDATE public.order_items SET book_title = '' WHERE book_title IS NULL;
+
+ALTER TABLE public.order_items ALTER COLUMN unit_price SET NOT NULL;
+ALTER TABLE public.order_items ALTER COLUMN book_title SET NOT NULL;
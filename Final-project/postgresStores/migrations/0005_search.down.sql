@@ -0,0 +1,27 @@
+-- pg_trgm is left installed; other schemas may use it.
+DROP TRIGGER IF EXISTS authors_search_vector_trigger ON public.authors;
+DROP FUNCTION IF EXISTS public.authors_search_vector_update();
+DROP TRIGGER IF EXISTS books_search_vector_trigger ON public.books;
+DROP FUNCTION IF EXISTS public.books_search_vector_update();
+
+DROP INDEX IF EXISTS public.idx_books_search_vector;
+ALTER TABLE public.books DROP COLUMN IF EXISTS search_vector;
+
+DROP INDEX IF EXISTS public.idx_reviews_book_id;
+DROP INDEX IF EXISTS public.idx_order_items_book_id;
+DROP INDEX IF EXISTS public.idx_order_items_order_id;
+DROP INDEX IF EXISTS public.idx_orders_created_at;
+DROP INDEX IF EXISTS public.idx_orders_status;
+DROP INDEX IF EXISTS public.idx_orders_customer_id;
+DROP INDEX IF EXISTS public.idx_customers_address;
+DROP INDEX IF EXISTS public.idx_customers_created_at;
+DROP INDEX IF EXISTS public.idx_customers_name_trgm;
+DROP INDEX IF EXISTS public.idx_customers_name;
+DROP INDEX IF EXISTS public.idx_books_price;
+DROP INDEX IF EXISTS public.idx_books_published_at;
+DROP INDEX IF EXISTS public.idx_books_genres;
+DROP INDEX IF EXISTS public.idx_books_title;
+DROP INDEX IF EXISTS public.idx_books_author_id;
+DROP INDEX IF EXISTS public.idx_authors_keywords_trgm;
+DROP INDEX IF EXISTS public.idx_authors_last_name;
+DROP INDEX IF EXISTS public.idx_authors_first_name;
@@ -0,0 +1,91 @@
+-- Indexes behind listing, searching and filtering, trigram indexes for the
+-- case-insensitive substring and fuzzy searches, and the full-text search vector
+-- of books.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_authors_first_name
+    ON public.authors (first_name);
+CREATE INDEX IF NOT EXISTS idx_authors_last_name
+    ON public.authors (last_name);
+CREATE INDEX IF NOT EXISTS idx_authors_keywords_trgm
+    ON public.authors USING gin (first_name gin_trgm_ops, last_name gin_trgm_ops, bio gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_books_author_id
+    ON public.books (author_id);
+CREATE INDEX IF NOT EXISTS idx_books_title
+    ON public.books (title);
+CREATE INDEX IF NOT EXISTS idx_books_genres
+    ON public.books USING gin (genres);
+CREATE INDEX IF NOT EXISTS idx_books_published_at
+    ON public.books (published_at);
+CREATE INDEX IF NOT EXISTS idx_books_price
+    ON public.books (price);
+
+CREATE INDEX IF NOT EXISTS idx_customers_name
+    ON public.customers (name);
+CREATE INDEX IF NOT EXISTS idx_customers_name_trgm
+    ON public.customers USING gin (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_customers_created_at
+    ON public.customers (created_at);
+CREATE INDEX IF NOT EXISTS idx_customers_address
+    ON public.customers (country, state, city, postal_code);
+
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id
+    ON public.orders (customer_id);
+CREATE INDEX IF NOT EXISTS idx_orders_status
+    ON public.orders (status);
+CREATE INDEX IF NOT EXISTS idx_orders_created_at
+    ON public.orders (created_at);
+
+CREATE INDEX IF NOT EXISTS idx_order_items_order_id
+    ON public.order_items (order_id);
+CREATE INDEX IF NOT EXISTS idx_order_items_book_id
+    ON public.order_items (book_id);
+
+CREATE INDEX IF NOT EXISTS idx_reviews_book_id
+    ON public.reviews (book_id);
+
+-- Full-text search: the title is weighted A, author name and genres B, and the
+-- author bio C.
+ALTER TABLE public.books ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+CREATE INDEX IF NOT EXISTS idx_books_search_vector
+    ON public.books USING gin (search_vector);
+
+CREATE OR REPLACE FUNCTION public.books_search_vector_update() RETURNS trigger AS $$
+DECLARE
+    author_name text;
+    author_bio  text;
+BEGIN
+    SELECT first_name || ' ' || last_name, bio INTO author_name, author_bio
+    FROM public.authors WHERE id = NEW.author_id;
+    NEW.search_vector :=
+        setweight(to_tsvector('english', COALESCE(NEW.title, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(author_name, '')), 'B') ||
+        setweight(to_tsvector('english', COALESCE(array_to_string(NEW.genres, ' '), '')), 'B') ||
+        setweight(to_tsvector('english', COALESCE(author_bio, '')), 'C');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS books_search_vector_trigger ON public.books;
+CREATE TRIGGER books_search_vector_trigger
+    BEFORE INSERT OR UPDATE OF title, author_id, genres ON public.books
+    FOR EACH ROW EXECUTE FUNCTION public.books_search_vector_update();
+
+-- Re-index an author's books when the name or bio changes.
+CREATE OR REPLACE FUNCTION public.authors_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    UPDATE public.books SET title = title WHERE author_id = NEW.id;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS authors_search_vector_trigger ON public.authors;
+CREATE TRIGGER authors_search_vector_trigger
+    AFTER UPDATE OF first_name, last_name, bio ON public.authors
+    FOR EACH ROW EXECUTE FUNCTION public.authors_search_vector_update();
+
+-- Index the books already on record.
+UPDATE public.books SET title = title WHERE search_vector IS NULL;
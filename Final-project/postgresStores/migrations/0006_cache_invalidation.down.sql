@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS order_items_cache_invalidation ON public.order_items;
+DROP TRIGGER IF EXISTS orders_cache_invalidation ON public.orders;
+DROP TRIGGER IF EXISTS customers_cache_invalidation ON public.customers;
+DROP TRIGGER IF EXISTS authors_cache_invalidation ON public.authors;
+DROP TRIGGER IF EXISTS books_cache_invalidation ON public.books;
+DROP FUNCTION IF EXISTS public.notify_cache_invalidation();
@@ -0,0 +1,43 @@
+-- Cache invalidation: every change to a row cached by the API is announced on the
+-- cache_invalidation channel as "<table>:<id>", so each running instance drops its
+-- copy. The trigger arguments name the table to announce and the column holding
+-- the ID; order items announce their order. Renaming an author rewrites the
+-- author's books through authors_search_vector_trigger, which announces them too.
+CREATE OR REPLACE FUNCTION public.notify_cache_invalidation() RETURNS trigger AS $$
+DECLARE
+    changed jsonb;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := to_jsonb(OLD);
+    ELSE
+        changed := to_jsonb(NEW);
+    END IF;
+    PERFORM pg_notify('cache_invalidation', TG_ARGV[0] || ':' || (changed ->> TG_ARGV[1]));
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS books_cache_invalidation ON public.books;
+CREATE TRIGGER books_cache_invalidation
+    AFTER INSERT OR UPDATE OR DELETE ON public.books
+    FOR EACH ROW EXECUTE FUNCTION public.notify_cache_invalidation('books', 'id');
+
+DROP TRIGGER IF EXISTS authors_cache_invalidation ON public.authors;
+CREATE TRIGGER authors_cache_invalidation
+    AFTER INSERT OR UPDATE OR DELETE ON public.authors
+    FOR EACH ROW EXECUTE FUNCTION public.notify_cache_invalidation('authors', 'id');
+
+DROP TRIGGER IF EXISTS customers_cache_invalidation ON public.customers;
+CREATE TRIGGER customers_cache_invalidation
+    AFTER INSERT OR UPDATE OR DELETE ON public.customers
+    FOR EACH ROW EXECUTE FUNCTION public.notify_cache_invalidation('customers', 'id');
+
+DROP TRIGGER IF EXISTS orders_cache_invalidation ON public.orders;
+CREATE TRIGGER orders_cache_invalidation
+    AFTER INSERT OR UPDATE OR DELETE ON public.orders
+    FOR EACH ROW EXECUTE FUNCTION public.notify_cache_invalidation('orders', 'id');
+
+DROP TRIGGER IF EXISTS order_items_cache_invalidation ON public.order_items;
+CREATE TRIGGER order_items_cache_invalidation
+    AFTER INSERT OR UPDATE OR DELETE ON public.order_items
+    FOR EACH ROW EXECUTE FUNCTION public.notify_cache_invalidation('orders', 'order_id');
@@ -0,0 +1,3 @@
+ALTER TABLE public.order_items DROP CONSTRAINT IF EXISTS order_items_book_id_fkey;
+ALTER TABLE public.orders DROP CONSTRAINT IF EXISTS orders_customer_id_fkey;
+ALTER TABLE public.books DROP CONSTRAINT IF EXISTS books_author_id_fkey;
@@ -0,0 +1,32 @@
+-- Foreign keys the schema was missing: a book's author, an order's customer and
+-- an order item's book must exist. They are RESTRICT, matching the API, which
+-- refuses to delete authors, customers and books that orders still refer to.
+--
+-- Like the checks of 0003, the keys are added without scanning existing rows,
+-- then validated; dangling references already on record are reported with a
+-- warning rather than failing the migration, and the keys hold for every row
+-- written from now on.
+DO $$
+DECLARE
+    fk record;
+BEGIN
+    FOR fk IN
+        SELECT * FROM (VALUES
+            ('books',       'books_author_id_fkey',     'author_id',   'authors'),
+            ('orders',      'orders_customer_id_fkey',  'customer_id', 'customers'),
+            ('order_items', 'order_items_book_id_fkey', 'book_id',     'books')
+        ) AS keys (tbl, name, col, ref)
+    LOOP
+        IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = fk.name) THEN
+            EXECUTE format('ALTER TABLE public.%I ADD CONSTRAINT %I FOREIGN KEY (%I) '
+                           'REFERENCES public.%I (id) ON UPDATE NO ACTION ON DELETE RESTRICT NOT VALID',
+                           fk.tbl, fk.name, fk.col, fk.ref);
+        END IF;
+        BEGIN
+            EXECUTE format('ALTER TABLE public.%I VALIDATE CONSTRAINT %I', fk.tbl, fk.name);
+        EXCEPTION WHEN foreign_key_violation THEN
+            RAISE WARNING '% is not validated: some rows of % refer to missing %', fk.name, fk.tbl, fk.ref;
+        END;
+    END LOOP;
+END
+$$;
@@ -0,0 +1,82 @@
+package postgresStores
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ChangeChannel is the channel the notify_cache_invalidation trigger (migration 0006)
+// announces changed rows on, with payloads of the form "books:42".
+const ChangeChannel = "cache_invalidation"
+
+const (
+	minListenerReconnect = 1 * time.Second
+	maxListenerReconnect = 30 * time.Second
+	// listenerPingInterval is how long an idle listener waits before checking that
+	// its connection is still alive.
+	listenerPingInterval = 90 * time.Second
+)
+
+// ListenForChanges listens on ChangeChannel over a connection of its own and calls
+// onChange with the table and ID of every changed row until ctx is done. Rows
+// changed while the connection was down are never announced, so onReconnect is
+// called once the connection is back. It returns an error if it cannot start
+// listening.
+func ListenForChanges(ctx context.Context, onChange func(ctx context.Context, table string, id int), onReconnect func(ctx context.Context)) error {
+	if dbConfig == nil {
+		return fmt.Errorf("postgresStores.Configure must be called before ListenForChanges")
+	}
+
+	listener := pq.NewListener(dbConfig.ConnectionString(), minListenerReconnect, maxListenerReconnect,
+		func(event pq.ListenerEventType, err error) {
+			if err != nil {
+				log.Printf("Change listener connection: %v", err)
+			}
+		})
+	defer listener.Close()
+	if err := listener.Listen(ChangeChannel); err != nil {
+		return fmt.Errorf("listening on %s: %w", ChangeChannel, err)
+	}
+	log.Printf("Listening for database changes on %s", ChangeChannel)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-listener.Notify:
+			// The listener sends nil after it has reconnected.
+			if notification == nil {
+				log.Printf("Change listener reconnected; changes made meanwhile may have been missed")
+				onReconnect(ctx)
+				continue
+			}
+			table, id, ok := parseChange(notification.Extra)
+			if !ok {
+				log.Printf("Ignoring malformed change notification %q", notification.Extra)
+				continue
+			}
+			onChange(ctx, table, id)
+		case <-time.After(listenerPingInterval):
+			go listener.Ping()
+		}
+	}
+}
+
+// parseChange splits a ChangeChannel payload into its table and row ID.
+func parseChange(payload string) (string, int, bool) {
+	table, idStr, found := strings.Cut(payload, ":")
+	if !found {
+		return "", 0, false
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return "", 0, false
+	}
+	return table, id, true
+}
@@ -1,44 +1,34 @@
 package postgresStores
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"finalProject/StructureData"
+	"finalProject/utils"
 	"fmt"
 	_ "log"
-	"strings"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type PostgresAuthorStore struct {
 	db *sql.DB
 }
 
-// Close gracefully closes the underlying DB connection.
-func (store *PostgresAuthorStore) Close() error {
-	return store.db.Close()
-}
-
 var postgresAuthorStoreInstance *PostgresAuthorStore
 
 // GetPostgresAuthorStoreInstance returns a singleton instance of PostgresAuthorStore.
 func GetPostgresAuthorStoreInstance() *PostgresAuthorStore {
 	if postgresAuthorStoreInstance == nil {
-		connStr := "user=postgres password=root dbname=booklibrary sslmode=disable"
-		db, err := sql.Open("postgres", connStr)
-		if err != nil {
-			panic(fmt.Sprintf("Failed to connect to Postgres: %v", err))
-		}
-		if err := db.Ping(); err != nil {
-			panic(fmt.Sprintf("Failed to ping Postgres: %v", err))
-		}
+		db := getDB()
 		postgresAuthorStoreInstance = &PostgresAuthorStore{db: db}
 	}
 	return postgresAuthorStoreInstance
 }
 
 // CreateAuthor inserts a new author into the database.
-func (store *PostgresAuthorStore) CreateAuthor(author StructureData.Author) (StructureData.Author, *StructureData.ErrorResponse) {
+func (store *PostgresAuthorStore) CreateAuthor(ctx context.Context, author StructureData.Author) (StructureData.Author, *StructureData.ErrorResponse) {
 	var query string
 	var args []interface{}
 	if author.ID != 0 {
@@ -57,7 +47,7 @@ func (store *PostgresAuthorStore) CreateAuthor(author StructureData.Author) (Str
 			author.Bio,
 		}
 	}
-	err := store.db.QueryRow(query, args...).Scan(&author.ID)
+	err := store.db.QueryRowContext(ctx, query, args...).Scan(&author.ID)
 	if err != nil {
 		return StructureData.Author{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to insert author: %v", err)}
 	}
@@ -65,22 +55,22 @@ func (store *PostgresAuthorStore) CreateAuthor(author StructureData.Author) (Str
 }
 
 // GetAuthor retrieves an author by its ID.
-func (store *PostgresAuthorStore) GetAuthor(id int) (StructureData.Author, *StructureData.ErrorResponse) {
+func (store *PostgresAuthorStore) GetAuthor(ctx context.Context, id int) (StructureData.Author, *StructureData.ErrorResponse) {
 	var author StructureData.Author
 	query := `SELECT id, first_name, last_name, bio FROM authors WHERE id=$1`
-	row := store.db.QueryRow(query, id)
+	row := store.db.QueryRowContext(ctx, query, id)
 	err := row.Scan(&author.ID, &author.FirstName, &author.LastName, &author.Bio)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return StructureData.Author{}, &StructureData.ErrorResponse{Message: "Author not found"}
+			return StructureData.Author{}, StructureData.NotFoundError("Author not found")
 		}
 		return StructureData.Author{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching author: %v", err)}
 	}
 	return author, nil
 }
-func (s *PostgresAuthorStore) GetAuthorByDetails(firstName, lastName, bio string) (StructureData.Author, *StructureData.ErrorResponse) {
+func (s *PostgresAuthorStore) GetAuthorByDetails(ctx context.Context, firstName, lastName, bio string) (StructureData.Author, *StructureData.ErrorResponse) {
 	var author StructureData.Author
-	err := s.db.QueryRow(
+	err := s.db.QueryRowContext(ctx,
 		"SELECT id, first_name, last_name, bio FROM authors WHERE first_name = $1 AND last_name = $2 AND bio = $3",
 		firstName,
 		lastName,
@@ -89,9 +79,7 @@ func (s *PostgresAuthorStore) GetAuthorByDetails(firstName, lastName, bio string
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return StructureData.Author{}, &StructureData.ErrorResponse{
-				Message: "Author not found",
-			}
+			return StructureData.Author{}, StructureData.NotFoundError("Author not found")
 		}
 		return StructureData.Author{}, &StructureData.ErrorResponse{
 			Message: fmt.Sprintf("Database error: %v", err),
@@ -100,39 +88,68 @@ func (s *PostgresAuthorStore) GetAuthorByDetails(firstName, lastName, bio string
 	return author, nil
 }
 // UpdateAuthor updates an existing author in the database.
-func (store *PostgresAuthorStore) UpdateAuthor(id int, author StructureData.Author) (StructureData.Author, *StructureData.ErrorResponse) {
+func (store *PostgresAuthorStore) UpdateAuthor(ctx context.Context, id int, author StructureData.Author) (StructureData.Author, *StructureData.ErrorResponse) {
 	query := `UPDATE authors SET first_name=$1, last_name=$2, bio=$3 WHERE id=$4`
-	res, err := store.db.Exec(query, author.FirstName, author.LastName, author.Bio, id)
+	res, err := store.db.ExecContext(ctx, query, author.FirstName, author.LastName, author.Bio, id)
 	if err != nil {
 		return StructureData.Author{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to update author: %v", err)}
 	}
 	rowsAffected, _ := res.RowsAffected()
 	if rowsAffected == 0 {
-		return StructureData.Author{}, &StructureData.ErrorResponse{Message: "Author not found"}
+		return StructureData.Author{}, StructureData.NotFoundError("Author not found")
 	}
 	author.ID = id
 	return author, nil
 }
 
-// DeleteAuthor removes an author from the database.
-func (store *PostgresAuthorStore) DeleteAuthor(id int) *StructureData.ErrorResponse {
-	query := `DELETE FROM authors WHERE id=$1`
-	res, err := store.db.Exec(query, id)
+// DeleteAuthor removes an author and its books from the database in one
+// transaction, refusing with a conflict if an order item refers to any of the
+// books. The foreign key from order items to books catches an order placed after
+// the check.
+func (store *PostgresAuthorStore) DeleteAuthor(ctx context.Context, id int) *StructureData.ErrorResponse {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to begin transaction: %v", err)}
+	}
+	defer tx.Rollback()
+
+	var ordered bool
+	query := `SELECT EXISTS (SELECT 1 FROM order_items oi JOIN books b ON b.id = oi.book_id WHERE b.author_id = $1)`
+	if err := tx.QueryRowContext(ctx, query, id).Scan(&ordered); err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to check the author's books: %v", err)}
+	}
+	if ordered {
+		return StructureData.ConflictError("Author has books linked to orders")
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM books WHERE author_id=$1`, id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Constraint == "order_items_book_id_fkey" {
+		return StructureData.ConflictError("Author has books linked to orders")
+	}
+	if err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to delete the author's books: %v", err)}
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM authors WHERE id=$1`, id)
 	if err != nil {
 		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to delete author: %v", err)}
 	}
 	rowsAffected, _ := res.RowsAffected()
 	if rowsAffected == 0 {
-		return &StructureData.ErrorResponse{Message: "Author not found"}
+		return StructureData.NotFoundError("Author not found")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to commit transaction: %v", err)}
 	}
 	return nil
 }
 
 // GetAllAuthors retrieves all authors from the database.
-func (store *PostgresAuthorStore) GetAllAuthors() []StructureData.Author {
+func (store *PostgresAuthorStore) GetAllAuthors(ctx context.Context) []StructureData.Author {
 	authors := []StructureData.Author{}
 	query := `SELECT id, first_name, last_name, bio FROM authors`
-	rows, err := store.db.Query(query)
+	rows, err := store.db.QueryContext(ctx, query)
 	if err != nil {
 		return authors
 	}
@@ -148,67 +165,65 @@ func (store *PostgresAuthorStore) GetAllAuthors() []StructureData.Author {
 	return authors
 }
 
-// SearchAuthors filters authors based on the search criteria.
-func (store *PostgresAuthorStore) SearchAuthors(criteria StructureData.AuthorSearchCriteria) ([]StructureData.Author, *StructureData.ErrorResponse) {
-	allAuthors := store.GetAllAuthors()
-	var result []StructureData.Author
-	for _, author := range allAuthors {
-		if len(criteria.IDs) > 0 {
-			found := false
-			for _, id := range criteria.IDs {
-				if author.ID == id {
-					found = true
-					break
-				}
-			}
-			if !found {
-				continue
-			}
-		}
-		if len(criteria.FirstNames) > 0 {
-			found := false
-			for _, fn := range criteria.FirstNames {
-				if author.FirstName == fn {
-					found = true
-					break
-				}
-			}
-			if !found {
-				continue
-			}
-		}
-		if len(criteria.LastNames) > 0 {
-			found := false
-			for _, ln := range criteria.LastNames {
-				if author.LastName == ln {
-					found = true
-					break
-				}
-			}
-			if !found {
-				continue
-			}
-		}
-		if len(criteria.Keywords) > 0 {
-			matched := false
-			for _, keyword := range criteria.Keywords {
-				if containsIgnoreCase(author.FirstName, keyword) ||
-					containsIgnoreCase(author.LastName, keyword) ||
-					containsIgnoreCase(author.Bio, keyword) {
-					matched = true
-					break
-				}
-			}
-			if !matched {
-				continue
-			}
+// authorSortColumns maps StructureData.AuthorSortFields to columns of authors.
+var authorSortColumns = map[string]string{
+	"id":         "id",
+	"first_name": "first_name",
+	"last_name":  "last_name",
+}
+
+// ListAuthors returns the page of authors selected by opts.
+func (store *PostgresAuthorStore) ListAuthors(ctx context.Context, opts StructureData.ListOptions) ([]StructureData.Author, StructureData.ListMeta, *StructureData.ErrorResponse) {
+	var total int
+	if err := store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM authors`).Scan(&total); err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to count authors: %v", err)}
+	}
+
+	query, args := pageQuery(`SELECT id, first_name, last_name, bio FROM authors`, opts, authorSortColumns)
+	rows, err := store.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to list authors: %v", err)}
+	}
+	defer rows.Close()
+
+	authors := []StructureData.Author{}
+	for rows.Next() {
+		var author StructureData.Author
+		if err := rows.Scan(&author.ID, &author.FirstName, &author.LastName, &author.Bio); err != nil {
+			return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error scanning author: %v", err)}
 		}
-		result = append(result, author)
+		authors = append(authors, author)
 	}
-	return result, nil
+	if err := rows.Err(); err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to list authors: %v", err)}
+	}
+
+	authors, meta := utils.FinishPage(authors, opts, total)
+	return authors, meta, nil
 }
 
-// Helper function: case-insensitive substring match.
-func containsIgnoreCase(text, substr string) bool {
-	return strings.Contains(strings.ToLower(text), strings.ToLower(substr))
+// SearchAuthors returns the authors matching criteria, filtered in SQL. Results are
+// ordered by similarity for fuzzy criteria, and by ID otherwise.
+func (store *PostgresAuthorStore) SearchAuthors(ctx context.Context, criteria StructureData.AuthorSearchCriteria) ([]StructureData.Author, *StructureData.ErrorResponse) {
+	where := newWhereClause()
+	score := authorConditions(where, "a", criteria)
+	query := `SELECT a.id, a.first_name, a.last_name, a.bio FROM authors a` + where.String() + orderByScore(score, "a.id")
+	rows, err := store.db.QueryContext(ctx, query, where.Args()...)
+	if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to search authors: %v", err)}
+	}
+	defer rows.Close()
+
+	authors := []StructureData.Author{}
+	for rows.Next() {
+		var author StructureData.Author
+		if err := rows.Scan(&author.ID, &author.FirstName, &author.LastName, &author.Bio); err != nil {
+			return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error scanning author: %v", err)}
+		}
+		authors = append(authors, author)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to search authors: %v", err)}
+	}
+	return authors, nil
 }
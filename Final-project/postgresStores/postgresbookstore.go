@@ -1,10 +1,14 @@
 package postgresStores
 
 import (
+	"context"
 	"database/sql"
 	"finalProject/StructureData"
+	"finalProject/utils"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 
 	"github.com/lib/pq"
 )
@@ -14,31 +18,77 @@ type PostgresBookStore struct {
 	db *sql.DB
 }
 
-// Close gracefully closes the underlying DB connection.
-func (store *PostgresBookStore) Close() error {
-	return store.db.Close()
-}
-
 var postgresBookStoreInstance *PostgresBookStore
 
 // GetPostgresBookStoreInstance returns a singleton instance of PostgresBookStore.
 func GetPostgresBookStoreInstance() *PostgresBookStore {
 	if postgresBookStoreInstance == nil {
-		connStr := "user=postgres password=root dbname=booklibrary sslmode=disable"
-		db, err := sql.Open("postgres", connStr)
-		if err != nil {
-			panic(fmt.Sprintf("Failed to connect to Postgres: %v", err))
-		}
-		if err := db.Ping(); err != nil {
-			panic(fmt.Sprintf("Failed to ping Postgres: %v", err))
-		}
+		db := getDB()
 		postgresBookStoreInstance = &PostgresBookStore{db: db}
 	}
 	return postgresBookStoreInstance
 }
 
+// bookColumns are the columns of books b, their authors a and their review
+// statistics r, in the order scanBook reads them. A book whose author row is
+// missing keeps the author's ID with empty details.
+const bookColumns = `b.id, b.title, b.genres, b.published_at, b.price, b.stock,
+	b.author_id, COALESCE(a.first_name, ''), COALESCE(a.last_name, ''), COALESCE(a.bio, ''),
+	r.average_rating, r.review_count`
+
+// bookJoins joins each book b to its author a and its review statistics r. The
+// statistics are a LATERAL aggregate over the book's reviews, found through
+// idx_reviews_book_id.
+const bookJoins = `
+	LEFT JOIN authors a ON a.id = b.author_id
+	CROSS JOIN LATERAL (SELECT COALESCE(AVG(rating), 0) AS average_rating, COUNT(*) AS review_count
+		FROM reviews WHERE book_id = b.id) r`
+
+// selectBooks selects bookColumns. Queries add their own WHERE and ORDER BY, so
+// a book comes with its author and review statistics in one round trip.
+const selectBooks = `SELECT ` + bookColumns + ` FROM books b` + bookJoins
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanBook reads a row selected by selectBooks, followed by the columns of extra.
+func scanBook(row rowScanner, extra ...interface{}) (StructureData.Book, error) {
+	var book StructureData.Book
+	var genres []string
+	var stats StructureData.BookReviewAggregate
+	dest := []interface{}{&book.ID, &book.Title, pq.Array(&genres), &book.PublishedAt, &book.Price, &book.Stock,
+		&book.Author.ID, &book.Author.FirstName, &book.Author.LastName, &book.Author.Bio,
+		&stats.AverageRating, &stats.ReviewCount}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return StructureData.Book{}, err
+	}
+	book.Genres = genres
+	book.ReviewStats = &stats
+	return book, nil
+}
+
+// queryBooks runs a query built on selectBooks.
+func (store *PostgresBookStore) queryBooks(ctx context.Context, query string, args ...interface{}) ([]StructureData.Book, error) {
+	rows, err := store.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	books := []StructureData.Book{}
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, err
+		}
+		books = append(books, book)
+	}
+	return books, rows.Err()
+}
+
 // CreateBook inserts a new book into the database.
-func (store *PostgresBookStore) CreateBook(book StructureData.Book) (StructureData.Book, *StructureData.ErrorResponse) {
+func (store *PostgresBookStore) CreateBook(ctx context.Context, book StructureData.Book) (StructureData.Book, *StructureData.ErrorResponse) {
 	var query string
 	var args []interface{}
 	if book.ID != 0 {
@@ -65,56 +115,29 @@ func (store *PostgresBookStore) CreateBook(book StructureData.Book) (StructureDa
 			book.Stock,
 		}
 	}
-	err := store.db.QueryRow(query, args...).Scan(&book.ID)
+	err := store.db.QueryRowContext(ctx, query, args...).Scan(&book.ID)
 	if err != nil {
 		return StructureData.Book{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to insert book: %v", err)}
 	}
 	return book, nil
 }
 
-func (store *PostgresBookStore) GetBook(id int) (StructureData.Book, *StructureData.ErrorResponse) {
-	var book StructureData.Book
-	var genres []string
-	var authorID int
-
-	query := `SELECT id, title, author_id, genres, published_at, price, stock FROM books WHERE id=$1`
-	row := store.db.QueryRow(query, id)
-	err := row.Scan(&book.ID, &book.Title, &authorID, pq.Array(&genres), &book.PublishedAt, &book.Price, &book.Stock)
+// GetBook retrieves a book by its ID, with its author and review statistics.
+func (store *PostgresBookStore) GetBook(ctx context.Context, id int) (StructureData.Book, *StructureData.ErrorResponse) {
+	book, err := scanBook(store.db.QueryRowContext(ctx, selectBooks+` WHERE b.id = $1`, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return StructureData.Book{}, &StructureData.ErrorResponse{Message: "Book not found"}
+			return StructureData.Book{}, StructureData.NotFoundError("Book not found")
 		}
 		return StructureData.Book{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching book: %v", err)}
 	}
-	book.Genres = genres
-
-	// Now fetch the author from PostgresAuthorStore to get full details.
-	authorStore := GetPostgresAuthorStoreInstance()
-	author, authErr := authorStore.GetAuthor(authorID)
-	if authErr != nil {
-		// If the author isn't found, you might decide to return an error
-		// or keep a partial author. Let's just keep a partial author for now.
-		// Or you can do:
-		// return StructureData.Book{}, authErr
-		log.Printf("Warning: Author ID %d not found for book %d: %v", authorID, book.ID, authErr)
-	} else {
-		book.Author = author
-	}
-
-	// Retrieve and set review stats, if available.
-	reviewStore := GetPostgresReviewStoreInstance()
-	stats, err := reviewStore.GetBookReviewStats(id)
-	if err == nil {
-		book.ReviewStats = &stats
-	}
-
 	return book, nil
 }
 
 // UpdateBook updates an existing book in the database.
-func (store *PostgresBookStore) UpdateBook(id int, book StructureData.Book) (StructureData.Book, *StructureData.ErrorResponse) {
+func (store *PostgresBookStore) UpdateBook(ctx context.Context, id int, book StructureData.Book) (StructureData.Book, *StructureData.ErrorResponse) {
 	query := `UPDATE books SET title=$1, author_id=$2, genres=$3, published_at=$4, price=$5, stock=$6 WHERE id=$7`
-	res, err := store.db.Exec(query,
+	res, err := store.db.ExecContext(ctx, query,
 		book.Title,
 		book.Author.ID,
 		pq.Array(book.Genres),
@@ -128,132 +151,204 @@ func (store *PostgresBookStore) UpdateBook(id int, book StructureData.Book) (Str
 	}
 	rowsAffected, _ := res.RowsAffected()
 	if rowsAffected == 0 {
-		return StructureData.Book{}, &StructureData.ErrorResponse{Message: "Book not found"}
+		return StructureData.Book{}, StructureData.NotFoundError("Book not found")
 	}
 	book.ID = id
 	return book, nil
 }
 
 // DeleteBook removes a book from the database.
-func (store *PostgresBookStore) DeleteBook(id int) *StructureData.ErrorResponse {
+func (store *PostgresBookStore) DeleteBook(ctx context.Context, id int) *StructureData.ErrorResponse {
 	query := `DELETE FROM books WHERE id=$1`
-	res, err := store.db.Exec(query, id)
+	res, err := store.db.ExecContext(ctx, query, id)
 	if err != nil {
 		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to delete book: %v", err)}
 	}
 	rowsAffected, _ := res.RowsAffected()
 	if rowsAffected == 0 {
-		return &StructureData.ErrorResponse{Message: "Book not found"}
+		return StructureData.NotFoundError("Book not found")
 	}
 	return nil
 }
 
-func (store *PostgresBookStore) GetAllBooks() []StructureData.Book {
-	books := []StructureData.Book{}
-	query := `SELECT id, title, author_id, genres, published_at, price, stock FROM books`
-	rows, err := store.db.Query(query)
+// GetAllBooks retrieves all books, with their authors and review statistics, in
+// a single query.
+func (store *PostgresBookStore) GetAllBooks(ctx context.Context) []StructureData.Book {
+	books, err := store.queryBooks(ctx, selectBooks+` ORDER BY b.id`)
 	if err != nil {
-		return books
+		log.Printf("Error querying books: %v", err)
+		return []StructureData.Book{}
 	}
-	defer rows.Close()
+	return books
+}
 
-	authorStore := GetPostgresAuthorStoreInstance()
+// bookSortColumns maps StructureData.BookSortFields to columns of books b.
+var bookSortColumns = map[string]string{
+	"id":           "b.id",
+	"title":        "b.title",
+	"price":        "b.price",
+	"stock":        "b.stock",
+	"published_at": "b.published_at",
+}
 
-	for rows.Next() {
-		var book StructureData.Book
-		var genres []string
-		var authorID int
-		err := rows.Scan(&book.ID, &book.Title, &authorID, pq.Array(&genres), &book.PublishedAt, &book.Price, &book.Stock)
-		if err != nil {
-			continue
-		}
-		book.Genres = genres
+// ListBooks returns the page of books selected by opts. Author details and review
+// statistics are only returned when those fields are wanted.
+func (store *PostgresBookStore) ListBooks(ctx context.Context, opts StructureData.ListOptions) ([]StructureData.Book, StructureData.ListMeta, *StructureData.ErrorResponse) {
+	var total int
+	if err := store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM books`).Scan(&total); err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to count books: %v", err)}
+	}
 
-		// Retrieve full author details
-		author, authErr := authorStore.GetAuthor(authorID)
-		if authErr == nil {
-			book.Author = author
-		} else {
-			log.Printf("Warning: Author ID %d not found for book %d: %v", authorID, book.ID, authErr)
+	query, args := pageQuery(selectBooks, opts, bookSortColumns)
+	books, err := store.queryBooks(ctx, query, args...)
+	if err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to list books: %v", err)}
+	}
+	withAuthor, withStats := opts.Wants("author"), opts.Wants("review_stats")
+	for i := range books {
+		if !withAuthor {
+			books[i].Author = StructureData.Author{ID: books[i].Author.ID}
 		}
-
-		// Retrieve and set review stats
-		reviewStore := GetPostgresReviewStoreInstance()
-		stats, statsErr := reviewStore.GetBookReviewStats(book.ID)
-		if statsErr == nil {
-			book.ReviewStats = &stats
+		if !withStats {
+			books[i].ReviewStats = nil
 		}
+	}
+	books, meta := utils.FinishPage(books, opts, total)
+	return books, meta, nil
+}
 
-		books = append(books, book)
+// SearchBooks returns the books matching criteria, filtered in SQL.
+func (store *PostgresBookStore) SearchBooks(ctx context.Context, criteria StructureData.BookSearchCriteria) ([]StructureData.Book, *StructureData.ErrorResponse) {
+	where := newWhereClause()
+	bookConditions(where, "b", criteria)
+	books, err := store.queryBooks(ctx, selectBooks+where.String()+` ORDER BY b.id`, where.Args()...)
+	if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to search books: %v", err)}
 	}
-	return books
+	return books, nil
 }
 
-// SearchBooks retrieves all books and filters them in memory based on search criteria.
-func (store *PostgresBookStore) SearchBooks(criteria StructureData.BookSearchCriteria) ([]StructureData.Book, *StructureData.ErrorResponse) {
-	// For simplicity, we retrieve all books and apply in-memory filtering.
-	allBooks := store.GetAllBooks()
-	var result []StructureData.Book
-	for _, book := range allBooks {
-		if len(criteria.IDs) > 0 && !containsInt(criteria.IDs, book.ID) {
-			continue
-		}
-		if len(criteria.Titles) > 0 && !containsString(criteria.Titles, book.Title) {
-			continue
-		}
-		if len(criteria.Genres) > 0 && !containsAnyString(criteria.Genres, book.Genres) {
-			continue
-		}
-		if !criteria.MinPublishedAt.IsZero() && book.PublishedAt.Before(criteria.MinPublishedAt) {
-			continue
-		}
-		if !criteria.MaxPublishedAt.IsZero() && book.PublishedAt.After(criteria.MaxPublishedAt) {
-			continue
-		}
-		if criteria.MinPrice > 0 && book.Price < criteria.MinPrice {
-			continue
-		}
-		if criteria.MaxPrice > 0 && book.Price > criteria.MaxPrice {
-			continue
+// bookFacetQueries holds, for each facet, a query counting the books selected by
+// a WHERE clause on books b, substituted for %s. Each row holds the key, a label
+// and the count.
+var bookFacetQueries = map[string]string{
+	StructureData.FacetGenre: `SELECT g, '', COUNT(*) FROM books b CROSS JOIN LATERAL unnest(b.genres) AS g%s
+		GROUP BY g ORDER BY COUNT(*) DESC, g`,
+	StructureData.FacetAuthor: `SELECT b.author_id::text, COALESCE(MAX(TRIM(a.first_name || ' ' || a.last_name)), ''), COUNT(*)
+		FROM books b LEFT JOIN authors a ON a.id = b.author_id%s
+		GROUP BY b.author_id ORDER BY COUNT(*) DESC, b.author_id::text`,
+	// The band number is turned into its key by SearchBookFacets.
+	StructureData.FacetPrice: `SELECT width_bucket(b.price, %[2]s::numeric[]), '', COUNT(*) FROM books b%[1]s
+		GROUP BY 1 ORDER BY 1`,
+	StructureData.FacetRating: `SELECT LEAST(FLOOR(COALESCE(r.average, 0))::int, 5), '', COUNT(*)
+		FROM books b LEFT JOIN (SELECT book_id, AVG(rating) AS average FROM reviews GROUP BY book_id) r ON r.book_id = b.id%s
+		GROUP BY 1 ORDER BY 1`,
+}
+
+// SearchBookFacets counts the books matching criteria by each facet named in
+// criteria.Facets, with one GROUP BY query per facet.
+func (store *PostgresBookStore) SearchBookFacets(ctx context.Context, criteria StructureData.BookSearchCriteria) (StructureData.BookFacets, *StructureData.ErrorResponse) {
+	var facets StructureData.BookFacets
+	for _, facet := range criteria.Facets {
+		where := newWhereClause()
+		bookConditions(where, "b", criteria)
+		var query string
+		if facet == StructureData.FacetPrice {
+			query = fmt.Sprintf(bookFacetQueries[facet], where.String(), where.bind(pq.Array(StructureData.PriceBandLimits)))
+		} else {
+			query = fmt.Sprintf(bookFacetQueries[facet], where.String())
 		}
-		if criteria.MinStock > 0 && book.Stock < criteria.MinStock {
-			continue
+		counts, err := store.facetCounts(ctx, query, where.Args())
+		if err != nil {
+			return StructureData.BookFacets{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to count %s facet: %v", facet, err)}
 		}
-		if criteria.MaxStock > 0 && book.Stock > criteria.MaxStock {
-			continue
+
+		switch facet {
+		case StructureData.FacetGenre:
+			facets.Genres = counts
+		case StructureData.FacetAuthor:
+			facets.Authors = counts
+		case StructureData.FacetPrice:
+			for i := range counts {
+				band, _ := strconv.Atoi(counts[i].Key)
+				counts[i].Key = StructureData.PriceBand(band)
+			}
+			facets.PriceBands = counts
+		case StructureData.FacetRating:
+			facets.Ratings = counts
 		}
-		// (Optional: match AuthorCriteria if needed.)
-		result = append(result, book)
 	}
-	return result, nil
+	return facets, nil
 }
 
-// Helper functions for in-memory filtering.
-func containsInt(slice []int, val int) bool {
-	for _, v := range slice {
-		if v == val {
-			return true
+func (store *PostgresBookStore) facetCounts(ctx context.Context, query string, args []interface{}) ([]StructureData.FacetCount, error) {
+	rows, err := store.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []StructureData.FacetCount{}
+	for rows.Next() {
+		var count StructureData.FacetCount
+		if err := rows.Scan(&count.Key, &count.Label, &count.Count); err != nil {
+			return nil, err
 		}
+		counts = append(counts, count)
 	}
-	return false
+	return counts, rows.Err()
 }
 
-func containsString(slice []string, val string) bool {
-	for _, v := range slice {
-		if v == val {
-			return true
-		}
+// prefixTSQuery turns search terms into a to_tsquery expression that requires
+// every term as a word prefix. SearchTerms only yields letters and digits, so the
+// terms need no escaping.
+func prefixTSQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = term + ":*"
 	}
-	return false
+	return strings.Join(parts, " & ")
 }
 
-func containsAnyString(target []string, source []string) bool {
-	for _, t := range target {
-		for _, s := range source {
-			if t == s {
-				return true
-			}
+// TextSearchBooks ranks the books whose search_vector matches every word of
+// query.Query as a prefix, highlighting the matches in the title and author bio.
+func (store *PostgresBookStore) TextSearchBooks(ctx context.Context, query StructureData.BookTextQuery) ([]StructureData.BookSearchHit, StructureData.ListMeta, *StructureData.ErrorResponse) {
+	tsquery := prefixTSQuery(utils.SearchTerms(query.Query))
+	meta := StructureData.ListMeta{Limit: query.Limit, Offset: query.Offset}
+
+	err := store.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM books WHERE search_vector @@ to_tsquery('english', $1)`, tsquery).Scan(&meta.Total)
+	if err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to search books: %v", err)}
+	}
+
+	headline := fmt.Sprintf("StartSel=%s, StopSel=%s", StructureData.HighlightStart, StructureData.HighlightStop)
+	rows, err := store.db.QueryContext(ctx, `
+		SELECT `+bookColumns+`,
+		       ts_rank(b.search_vector, q.query) AS score,
+		       ts_headline('english', b.title, q.query, $2 || ', HighlightAll=true'),
+		       ts_headline('english', COALESCE(a.bio, ''), q.query, $2 || ', MinWords=10, MaxWords=30')
+		FROM books b`+bookJoins+`
+		CROSS JOIN to_tsquery('english', $1) AS q(query)
+		WHERE b.search_vector @@ q.query
+		ORDER BY score DESC, b.id
+		LIMIT $3 OFFSET $4`, tsquery, headline, query.Limit, query.Offset)
+	if err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to search books: %v", err)}
+	}
+	defer rows.Close()
+
+	hits := []StructureData.BookSearchHit{}
+	for rows.Next() {
+		var hit StructureData.BookSearchHit
+		hit.Book, err = scanBook(rows, &hit.Score, &hit.Highlights.Title, &hit.Highlights.Snippet)
+		if err != nil {
+			return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error scanning book: %v", err)}
 		}
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to search books: %v", err)}
 	}
-	return false
+	return hits, meta, nil
 }
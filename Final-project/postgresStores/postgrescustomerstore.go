@@ -1,8 +1,10 @@
 package postgresStores
 
 import (
+	"context"
 	"database/sql"
 	"finalProject/StructureData"
+	"finalProject/utils"
 	"fmt"
 	"log"
 	"sync"
@@ -20,21 +22,10 @@ var (
 	once                          sync.Once
 )
 
-func (store *PostgresCustomerStore) Close() error {
-	return store.DB.Close()
-}
-
 // GetPostgresCustomerStoreInstance returns a singleton instance.
 func GetPostgresCustomerStoreInstance() *PostgresCustomerStore {
 	once.Do(func() { // Ensures it runs only once
-		connStr := "user=postgres password=root dbname=booklibrary sslmode=disable"
-		DB, err := sql.Open("postgres", connStr)
-		if err != nil {
-			panic(fmt.Sprintf("Failed to connect to Postgres: %v", err))
-		}
-		if err := DB.Ping(); err != nil {
-			panic(fmt.Sprintf("Failed to ping Postgres: %v", err))
-		}
+		DB := getDB()
 		postgresCustomerStoreInstance = &PostgresCustomerStore{DB: DB}
 	})
 	return postgresCustomerStoreInstance
@@ -42,13 +33,17 @@ func GetPostgresCustomerStoreInstance() *PostgresCustomerStore {
 
 // CreateCustomer inserts a new customer into PostgreSQL.
 // If customer.ID is nonzero, it will be inserted explicitly.
-func (store *PostgresCustomerStore) CreateCustomer(customer StructureData.Customer) (StructureData.Customer, *StructureData.ErrorResponse) {
+func (store *PostgresCustomerStore) CreateCustomer(ctx context.Context, customer StructureData.Customer) (StructureData.Customer, *StructureData.ErrorResponse) {
 	var query string
 	var args []interface{}
 
+	if customer.Role == "" {
+		customer.Role = StructureData.RoleUser
+	}
+
 	if customer.ID != 0 {
-		query = `INSERT INTO customers (id, name, username, email, password, street, city, state, postal_code, country, created_at)
-		          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
+		query = `INSERT INTO customers (id, name, username, email, password, street, city, state, postal_code, country, role, created_at)
+		          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`
 		args = []interface{}{
 			customer.ID,
 			customer.Name,
@@ -60,11 +55,12 @@ func (store *PostgresCustomerStore) CreateCustomer(customer StructureData.Custom
 			customer.Address.State,
 			customer.Address.PostalCode,
 			customer.Address.Country,
+			customer.Role,
 			customer.CreatedAt,
 		}
 	} else {
-		query = `INSERT INTO customers (name, username, email, password, street, city, state, postal_code, country, created_at)
-		          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
+		query = `INSERT INTO customers (name, username, email, password, street, city, state, postal_code, country, role, created_at)
+		          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
 		args = []interface{}{
 			customer.Name,
 			customer.Username,
@@ -75,11 +71,12 @@ func (store *PostgresCustomerStore) CreateCustomer(customer StructureData.Custom
 			customer.Address.State,
 			customer.Address.PostalCode,
 			customer.Address.Country,
+			customer.Role,
 			customer.CreatedAt,
 		}
 	}
 
-	err := store.DB.QueryRow(query, args...).Scan(&customer.ID)
+	err := store.DB.QueryRowContext(ctx, query, args...).Scan(&customer.ID)
 	if err != nil {
 		return StructureData.Customer{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to insert customer: %v", err)}
 	}
@@ -87,11 +84,11 @@ func (store *PostgresCustomerStore) CreateCustomer(customer StructureData.Custom
 }
 
 // GetCustomer retrieves a customer by its ID.
-func (store *PostgresCustomerStore) GetCustomer(id int) (StructureData.Customer, *StructureData.ErrorResponse) {
+func (store *PostgresCustomerStore) GetCustomer(ctx context.Context, id int) (StructureData.Customer, *StructureData.ErrorResponse) {
     var customer StructureData.Customer
     var street, city, state, postalCode, country string
-    query := `SELECT id, name, username, email, street, city, state, postal_code, country, created_at FROM customers WHERE id=$1`
-    row := store.DB.QueryRow(query, id)
+    query := `SELECT id, name, username, email, street, city, state, postal_code, country, role, created_at FROM customers WHERE id=$1`
+    row := store.DB.QueryRowContext(ctx, query, id)
     // Include &customer.Username in Scan
     err := row.Scan(
         &customer.ID,
@@ -103,11 +100,12 @@ func (store *PostgresCustomerStore) GetCustomer(id int) (StructureData.Customer,
         &state,
         &postalCode,
         &country,
+        &customer.Role,
         &customer.CreatedAt,
     )
     if err != nil {
         if err == sql.ErrNoRows {
-            return StructureData.Customer{}, &StructureData.ErrorResponse{Message: "Customer not found"}
+            return StructureData.Customer{}, StructureData.NotFoundError("Customer not found")
         }
         return StructureData.Customer{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching customer: %v", err)}
     }
@@ -121,12 +119,33 @@ func (store *PostgresCustomerStore) GetCustomer(id int) (StructureData.Customer,
     return customer, nil
 }
 
+// GetCustomerByEmail retrieves the customer with the given email, including the
+// password hash, for checking their credentials.
+func (store *PostgresCustomerStore) GetCustomerByEmail(ctx context.Context, email string) (StructureData.Customer, *StructureData.ErrorResponse) {
+	var customer StructureData.Customer
+	query := `SELECT id, name, username, email, password, role, created_at FROM customers WHERE email=$1`
+	err := store.DB.QueryRowContext(ctx, query, email).Scan(
+		&customer.ID,
+		&customer.Name,
+		&customer.Username,
+		&customer.Email,
+		&customer.Password,
+		&customer.Role,
+		&customer.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return StructureData.Customer{}, StructureData.NotFoundError("Customer not found")
+	} else if err != nil {
+		return StructureData.Customer{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching customer: %v", err)}
+	}
+	return customer, nil
+}
 
 // GetAllCustomers retrieves all customers from the database.
-func (store *PostgresCustomerStore) GetAllCustomers() []StructureData.Customer {
+func (store *PostgresCustomerStore) GetAllCustomers(ctx context.Context) []StructureData.Customer {
     customers := []StructureData.Customer{}
-    query := `SELECT id, name, username, email, street, city, state, postal_code, country, created_at FROM customers`
-    rows, err := store.DB.Query(query)
+    query := `SELECT id, name, username, email, street, city, state, postal_code, country, role, created_at FROM customers`
+    rows, err := store.DB.QueryContext(ctx, query)
     if err != nil {
         log.Printf("Error querying customers: %v", err) // Add logging
         return customers
@@ -146,6 +165,7 @@ func (store *PostgresCustomerStore) GetAllCustomers() []StructureData.Customer {
             &state,
             &postalCode,
             &country,
+            &customer.Role,
             &customer.CreatedAt,
         )
         if err != nil {
@@ -164,10 +184,42 @@ func (store *PostgresCustomerStore) GetAllCustomers() []StructureData.Customer {
     return customers
 }
 
+// customerSortColumns maps StructureData.CustomerSortFields to columns of customers.
+var customerSortColumns = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"email":      "email",
+	"created_at": "created_at",
+}
+
+// ListCustomers returns the page of customers selected by opts.
+func (store *PostgresCustomerStore) ListCustomers(ctx context.Context, opts StructureData.ListOptions) ([]StructureData.Customer, StructureData.ListMeta, *StructureData.ErrorResponse) {
+	var total int
+	if err := store.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM customers`).Scan(&total); err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to count customers: %v", err)}
+	}
+
+	query, args := pageQuery(`SELECT id, name, username, email, street, city, state, postal_code, country, role, created_at FROM customers`, opts, customerSortColumns)
+	rows, err := store.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to list customers: %v", err)}
+	}
+	defer rows.Close()
+
+	customers, err := scanCustomers(rows)
+	if err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to list customers: %v", err)}
+	}
+
+	customers, meta := utils.FinishPage(customers, opts, total)
+	return customers, meta, nil
+}
+
 // UpdateCustomer updates an existing customer in the database.
-func (store *PostgresCustomerStore) UpdateCustomer(id int, customer StructureData.Customer) (StructureData.Customer, *StructureData.ErrorResponse) {
-	query := `UPDATE customers SET name=$1, username=$2, email=$3, street=$4, city=$5, state=$6, postal_code=$7, country=$8 WHERE id=$9`
-	res, err := store.DB.Exec(query,
+// The role is never changed here; the stored role is returned on the updated customer.
+func (store *PostgresCustomerStore) UpdateCustomer(ctx context.Context, id int, customer StructureData.Customer) (StructureData.Customer, *StructureData.ErrorResponse) {
+	query := `UPDATE customers SET name=$1, username=$2, email=$3, street=$4, city=$5, state=$6, postal_code=$7, country=$8 WHERE id=$9 RETURNING role`
+	err := store.DB.QueryRowContext(ctx, query,
 		customer.Name,
 		customer.Username,
 		customer.Email,
@@ -177,159 +229,65 @@ func (store *PostgresCustomerStore) UpdateCustomer(id int, customer StructureDat
 		customer.Address.PostalCode,
 		customer.Address.Country,
 		id,
-	)
+	).Scan(&customer.Role)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return StructureData.Customer{}, StructureData.NotFoundError("Customer not found")
+		}
 		return StructureData.Customer{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to update customer: %v", err)}
 	}
-	rowsAffected, _ := res.RowsAffected()
-	if rowsAffected == 0 {
-		return StructureData.Customer{}, &StructureData.ErrorResponse{Message: "Customer not found"}
-	}
 	customer.ID = id
 	return customer, nil
 }
 
 // DeleteCustomer removes a customer from the database.
-func (store *PostgresCustomerStore) DeleteCustomer(id int) *StructureData.ErrorResponse {
+func (store *PostgresCustomerStore) DeleteCustomer(ctx context.Context, id int) *StructureData.ErrorResponse {
 	query := `DELETE FROM customers WHERE id=$1`
-	res, err := store.DB.Exec(query, id)
+	res, err := store.DB.ExecContext(ctx, query, id)
 	if err != nil {
 		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to delete customer: %v", err)}
 	}
 	rowsAffected, _ := res.RowsAffected()
 	if rowsAffected == 0 {
-		return &StructureData.ErrorResponse{Message: "Customer not found"}
+		return StructureData.NotFoundError("Customer not found")
 	}
 	return nil
 }
 
-// SearchCustomers filters customers based on the search criteria.
-// For simplicity, this implementation fetches all customers and then applies in-memory filtering.
-func (store *PostgresCustomerStore) SearchCustomers(criteria StructureData.CustomerSearchCriteria) ([]StructureData.Customer, *StructureData.ErrorResponse) {
-	allCustomers := store.GetAllCustomers()
-	var result []StructureData.Customer
-	for _, customer := range allCustomers {
-		// Filter by IDs.
-		if len(criteria.IDs) > 0 {
-			match := false
-			for _, id := range criteria.IDs {
-				if customer.ID == id {
-					match = true
-					break
-				}
-			}
-			if !match {
-				continue
-			}
-		}
-		// Filter by Names.
-		if len(criteria.Names) > 0 {
-			match := false
-			for _, name := range criteria.Names {
-				if customer.Name == name {
-					match = true
-					break
-				}
-			}
-			if !match {
-				continue
-			}
-		}
-		// Filter by Emails.
-		if len(criteria.Emails) > 0 {
-			match := false
-			for _, email := range criteria.Emails {
-				if customer.Email == email {
-					match = true
-					break
-				}
-			}
-			if !match {
-				continue
-			}
-		}
-		// Filter by creation time.
-		if !criteria.MinCreatedAt.IsZero() && customer.CreatedAt.Before(criteria.MinCreatedAt) {
-			continue
-		}
-		if !criteria.MaxCreatedAt.IsZero() && customer.CreatedAt.After(criteria.MaxCreatedAt) {
-			continue
-		}
-		// Filter by address criteria.
-		if !matchAddressCriteria(customer.Address, criteria.AddressCriteria) {
-			continue
-		}
-		result = append(result, customer)
+// SearchCustomers returns the customers matching criteria, filtered in SQL. Results
+// are ordered by name similarity for fuzzy criteria, and by ID otherwise.
+func (store *PostgresCustomerStore) SearchCustomers(ctx context.Context, criteria StructureData.CustomerSearchCriteria) ([]StructureData.Customer, *StructureData.ErrorResponse) {
+	where := newWhereClause()
+	score := customerConditions(where, "c", criteria)
+	query := `SELECT c.id, c.name, c.username, c.email, c.street, c.city, c.state, c.postal_code, c.country, c.role, c.created_at FROM customers c` +
+		where.String() + orderByScore(score, "c.id")
+	rows, err := store.DB.QueryContext(ctx, query, where.Args()...)
+	if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to search customers: %v", err)}
 	}
-	return result, nil
-}
+	defer rows.Close()
 
-// matchAddressCriteria matches a customer's address with the specified search criteria.
-func matchAddressCriteria(address StructureData.Address, criteria StructureData.AddressSearchCriteria) bool {
-	// If no criteria are provided, assume a match.
-	if len(criteria.Streets) == 0 && len(criteria.Cities) == 0 && len(criteria.States) == 0 &&
-		len(criteria.PostalCodes) == 0 && len(criteria.Countries) == 0 {
-		return true
-	}
-	if len(criteria.Streets) > 0 {
-		found := false
-		for _, s := range criteria.Streets {
-			if address.Street == s {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return false
-		}
-	}
-	if len(criteria.Cities) > 0 {
-		found := false
-		for _, city := range criteria.Cities {
-			if address.City == city {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return false
-		}
-	}
-	if len(criteria.States) > 0 {
-		found := false
-		for _, state := range criteria.States {
-			if address.State == state {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return false
-		}
-	}
-	if len(criteria.PostalCodes) > 0 {
-		found := false
-		for _, p := range criteria.PostalCodes {
-			if address.PostalCode == p {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return false
-		}
+	customers, err := scanCustomers(rows)
+	if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to search customers: %v", err)}
 	}
-	if len(criteria.Countries) > 0 {
-		found := false
-		for _, c := range criteria.Countries {
-			if address.Country == c {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return false
+	return customers, nil
+}
+
+// scanCustomers reads customers selected as id, name, username, email, the address
+// columns, role and created_at.
+func scanCustomers(rows *sql.Rows) ([]StructureData.Customer, error) {
+	customers := []StructureData.Customer{}
+	for rows.Next() {
+		var customer StructureData.Customer
+		address := &customer.Address
+		err := rows.Scan(&customer.ID, &customer.Name, &customer.Username, &customer.Email,
+			&address.Street, &address.City, &address.State, &address.PostalCode, &address.Country,
+			&customer.Role, &customer.CreatedAt)
+		if err != nil {
+			return nil, err
 		}
+		customers = append(customers, customer)
 	}
-	return true
+	return customers, rows.Err()
 }
@@ -1,13 +1,14 @@
 package postgresStores
 
 import (
+	"context"
 	"database/sql"
 	"finalProject/StructureData"
+	"finalProject/utils"
 	"fmt"
 	"log"
+	"sort"
 	"time"
-
-	_ "github.com/lib/pq"
 )
 
 // PostgresOrderStore implements the OrderStore interface using PostgreSQL.
@@ -15,39 +16,41 @@ type PostgresOrderStore struct {
 	db *sql.DB
 }
 
-// Close gracefully closes the underlying DB connection.
-func (store *PostgresOrderStore) Close() error {
-	return store.db.Close()
-}
-
 var postgresOrderStoreInstance *PostgresOrderStore
 
 // GetPostgresOrderStoreInstance returns a singleton instance of PostgresOrderStore.
 func GetPostgresOrderStoreInstance() *PostgresOrderStore {
 	if postgresOrderStoreInstance == nil {
-		connStr := "user=postgres password=root dbname=booklibrary sslmode=disable"
-		db, err := sql.Open("postgres", connStr)
-		if err != nil {
-			panic(fmt.Sprintf("Failed to connect to Postgres: %v", err))
-		}
-		if err := db.Ping(); err != nil {
-			panic(fmt.Sprintf("Failed to ping Postgres: %v", err))
-		}
+		db := getDB()
 		postgresOrderStoreInstance = &PostgresOrderStore{db: db}
-		log.Println("Connected to Postgres for orders.")
 	}
 	return postgresOrderStoreInstance
 }
 
 // CreateOrder inserts a new order and its items into the database.
-func (store *PostgresOrderStore) CreateOrder(order StructureData.Order) (StructureData.Order, *StructureData.ErrorResponse) {
-	tx, err := store.db.Begin()
+func (store *PostgresOrderStore) CreateOrder(ctx context.Context, order StructureData.Order) (StructureData.Order, *StructureData.ErrorResponse) {
+	tx, err := store.db.BeginTx(ctx, nil)
 	if err != nil {
 		log.Printf("Failed to begin transaction: %v", err)
 		return StructureData.Order{}, &StructureData.ErrorResponse{Message: "Failed to begin transaction"}
 	}
 	defer tx.Rollback()
 
+	order, errResp := insertOrder(ctx, tx, order)
+	if errResp != nil {
+		return StructureData.Order{}, errResp
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Failed to commit transaction for order ID %d: %v", order.ID, err)
+		return StructureData.Order{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to commit transaction: %v", err)}
+	}
+	log.Printf("Order ID %d committed successfully", order.ID)
+	return order, nil
+}
+
+// insertOrder inserts the order header and its items within tx.
+func insertOrder(ctx context.Context, tx *sql.Tx, order StructureData.Order) (StructureData.Order, *StructureData.ErrorResponse) {
 	var queryOrder string
 	var args []interface{}
 	if order.ID != 0 {
@@ -70,52 +73,52 @@ func (store *PostgresOrderStore) CreateOrder(order StructureData.Order) (Structu
 			order.Status,
 		}
 	}
-	err = tx.QueryRow(queryOrder, args...).Scan(&order.ID)
+	err := tx.QueryRowContext(ctx, queryOrder, args...).Scan(&order.ID)
 	if err != nil {
 		log.Printf("Error inserting order (customer_id=%d): %v", order.Customer.ID, err)
 		return StructureData.Order{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to insert order: %v", err)}
 	}
 	log.Printf("Inserted order with ID %d", order.ID)
 
-	// Insert each order item.
-	for _, item := range order.Items {
-		queryItem := `INSERT INTO order_items (order_id, book_id, quantity) VALUES ($1, $2, $3)`
-		_, err = tx.Exec(queryItem, order.ID, item.Book.ID, item.Quantity)
-		if err != nil {
-			log.Printf("Error inserting order item for order ID %d, book ID %d: %v", order.ID, item.Book.ID, err)
-			return StructureData.Order{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to insert order item: %v", err)}
-		}
-		log.Printf("Inserted order item for order ID %d, book ID %d, quantity %d", order.ID, item.Book.ID, item.Quantity)
-	}
-
-	if err = tx.Commit(); err != nil {
-		log.Printf("Failed to commit transaction for order ID %d: %v", order.ID, err)
-		return StructureData.Order{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to commit transaction: %v", err)}
+	if errResp := insertOrderItems(ctx, tx, order.ID, order.Items); errResp != nil {
+		return StructureData.Order{}, errResp
 	}
-	log.Printf("Order ID %d committed successfully", order.ID)
 	return order, nil
 }
 
-// (Other order methods remain unchanged.)
+// insertOrderItems inserts the line items of order orderID within tx.
+func insertOrderItems(ctx context.Context, tx *sql.Tx, orderID int, items []StructureData.OrderItem) *StructureData.ErrorResponse {
+	for _, item := range items {
+		queryItem := `
+			INSERT INTO order_items (order_id, book_id, quantity, unit_price, book_title, author_name)
+			VALUES ($1, $2, $3, $4, $5, $6)`
+		if _, err := tx.ExecContext(ctx, queryItem, orderID, item.Book.ID, item.Quantity, item.UnitPrice, item.BookTitle, item.AuthorName); err != nil {
+			log.Printf("Error inserting order item for order ID %d, book ID %d: %v", orderID, item.Book.ID, err)
+			return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to insert order item: %v", err)}
+		}
+		log.Printf("Inserted order item for order ID %d, book ID %d, quantity %d", orderID, item.Book.ID, item.Quantity)
+	}
+	return nil
+}
 
 // GetOrder retrieves an order (including its items) by ID.
-func (store *PostgresOrderStore) GetOrder(id int) (StructureData.Order, *StructureData.ErrorResponse) {
+func (store *PostgresOrderStore) GetOrder(ctx context.Context, id int) (StructureData.Order, *StructureData.ErrorResponse) {
 	var order StructureData.Order
 	queryOrder := `SELECT id, customer_id, total_price, created_at, status FROM orders WHERE id=$1`
-	row := store.db.QueryRow(queryOrder, id)
+	row := store.db.QueryRowContext(ctx, queryOrder, id)
 	err := row.Scan(&order.ID, &order.Customer.ID, &order.TotalPrice, &order.CreatedAt, &order.Status)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("Order with ID %d not found", id)
-			return StructureData.Order{}, &StructureData.ErrorResponse{Message: "Order not found"}
+			return StructureData.Order{}, StructureData.NotFoundError("Order not found")
 		}
 		log.Printf("Error fetching order with ID %d: %v", id, err)
 		return StructureData.Order{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching order: %v", err)}
 	}
 
 	// Fetch order items.
-	queryItems := `SELECT book_id, quantity FROM order_items WHERE order_id=$1`
-	rows, err := store.db.Query(queryItems, order.ID)
+	queryItems := `SELECT book_id, quantity, unit_price, book_title, author_name FROM order_items WHERE order_id=$1`
+	rows, err := store.db.QueryContext(ctx, queryItems, order.ID)
 	if err != nil {
 		log.Printf("Error fetching order items for order ID %d: %v", order.ID, err)
 		return StructureData.Order{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching order items: %v", err)}
@@ -123,7 +126,7 @@ func (store *PostgresOrderStore) GetOrder(id int) (StructureData.Order, *Structu
 	defer rows.Close()
 	for rows.Next() {
 		var item StructureData.OrderItem
-		err = rows.Scan(&item.Book.ID, &item.Quantity)
+		err = rows.Scan(&item.Book.ID, &item.Quantity, &item.UnitPrice, &item.BookTitle, &item.AuthorName)
 		if err != nil {
 			log.Printf("Error scanning order item for order ID %d: %v", order.ID, err)
 			return StructureData.Order{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error scanning order item: %v", err)}
@@ -135,40 +138,16 @@ func (store *PostgresOrderStore) GetOrder(id int) (StructureData.Order, *Structu
 }
 
 // UpdateOrder updates an existing order and its items.
-func (store *PostgresOrderStore) UpdateOrder(id int, order StructureData.Order) (StructureData.Order, *StructureData.ErrorResponse) {
-	tx, err := store.db.Begin()
+func (store *PostgresOrderStore) UpdateOrder(ctx context.Context, id int, order StructureData.Order) (StructureData.Order, *StructureData.ErrorResponse) {
+	tx, err := store.db.BeginTx(ctx, nil)
 	if err != nil {
 		log.Printf("Failed to begin update transaction: %v", err)
 		return StructureData.Order{}, &StructureData.ErrorResponse{Message: "Failed to begin transaction"}
 	}
 	defer tx.Rollback()
 
-	// Update order header.
-	queryUpdate := `UPDATE orders SET customer_id=$1, total_price=$2, created_at=$3, status=$4 WHERE id=$5`
-	_, err = tx.Exec(queryUpdate, order.Customer.ID, order.TotalPrice, order.CreatedAt, order.Status, id)
-	if err != nil {
-		log.Printf("Failed to update order ID %d: %v", id, err)
-		return StructureData.Order{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to update order: %v", err)}
-	}
-	log.Printf("Updated order header for order ID %d", id)
-
-	// Delete existing order items.
-	queryDeleteItems := `DELETE FROM order_items WHERE order_id=$1`
-	_, err = tx.Exec(queryDeleteItems, id)
-	if err != nil {
-		log.Printf("Failed to delete old order items for order ID %d: %v", id, err)
-		return StructureData.Order{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to delete old order items: %v", err)}
-	}
-
-	// Insert new order items.
-	for _, item := range order.Items {
-		queryInsertItem := `INSERT INTO order_items (order_id, book_id, quantity) VALUES ($1, $2, $3)`
-		_, err = tx.Exec(queryInsertItem, id, item.Book.ID, item.Quantity)
-		if err != nil {
-			log.Printf("Failed to insert order item for order ID %d, book ID %d: %v", id, item.Book.ID, err)
-			return StructureData.Order{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to insert order item: %v", err)}
-		}
-		log.Printf("Inserted order item for order ID %d, book ID %d, quantity %d", id, item.Book.ID, item.Quantity)
+	if errResp := updateOrderRows(ctx, tx, id, order); errResp != nil {
+		return StructureData.Order{}, errResp
 	}
 
 	if err = tx.Commit(); err != nil {
@@ -180,9 +159,27 @@ func (store *PostgresOrderStore) UpdateOrder(id int, order StructureData.Order)
 	return order, nil
 }
 
+// updateOrderRows overwrites the header and line items of order id within tx.
+func updateOrderRows(ctx context.Context, tx *sql.Tx, id int, order StructureData.Order) *StructureData.ErrorResponse {
+	queryUpdate := `UPDATE orders SET customer_id=$1, total_price=$2, created_at=$3, status=$4 WHERE id=$5`
+	if _, err := tx.ExecContext(ctx, queryUpdate, order.Customer.ID, order.TotalPrice, order.CreatedAt, order.Status, id); err != nil {
+		log.Printf("Failed to update order ID %d: %v", id, err)
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to update order: %v", err)}
+	}
+	log.Printf("Updated order header for order ID %d", id)
+
+	queryDeleteItems := `DELETE FROM order_items WHERE order_id=$1`
+	if _, err := tx.ExecContext(ctx, queryDeleteItems, id); err != nil {
+		log.Printf("Failed to delete old order items for order ID %d: %v", id, err)
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to delete old order items: %v", err)}
+	}
+
+	return insertOrderItems(ctx, tx, id, order.Items)
+}
+
 // DeleteOrder removes an order and its items from the database.
-func (store *PostgresOrderStore) DeleteOrder(id int) *StructureData.ErrorResponse {
-	tx, err := store.db.Begin()
+func (store *PostgresOrderStore) DeleteOrder(ctx context.Context, id int) *StructureData.ErrorResponse {
+	tx, err := store.db.BeginTx(ctx, nil)
 	if err != nil {
 		log.Printf("Failed to begin delete transaction: %v", err)
 		return &StructureData.ErrorResponse{Message: "Failed to begin transaction"}
@@ -191,7 +188,7 @@ func (store *PostgresOrderStore) DeleteOrder(id int) *StructureData.ErrorRespons
 
 	// Delete order items.
 	queryDeleteItems := `DELETE FROM order_items WHERE order_id=$1`
-	_, err = tx.Exec(queryDeleteItems, id)
+	_, err = tx.ExecContext(ctx, queryDeleteItems, id)
 	if err != nil {
 		log.Printf("Failed to delete order items for order ID %d: %v", id, err)
 		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to delete order items: %v", err)}
@@ -199,7 +196,7 @@ func (store *PostgresOrderStore) DeleteOrder(id int) *StructureData.ErrorRespons
 
 	// Delete order header.
 	queryDeleteOrder := `DELETE FROM orders WHERE id=$1`
-	res, err := tx.Exec(queryDeleteOrder, id)
+	res, err := tx.ExecContext(ctx, queryDeleteOrder, id)
 	if err != nil {
 		log.Printf("Failed to delete order ID %d: %v", id, err)
 		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to delete order: %v", err)}
@@ -207,7 +204,7 @@ func (store *PostgresOrderStore) DeleteOrder(id int) *StructureData.ErrorRespons
 	rowsAffected, _ := res.RowsAffected()
 	if rowsAffected == 0 {
 		log.Printf("Order ID %d not found for deletion", id)
-		return &StructureData.ErrorResponse{Message: "Order not found"}
+		return StructureData.NotFoundError("Order not found")
 	}
 
 	if err = tx.Commit(); err != nil {
@@ -218,17 +215,16 @@ func (store *PostgresOrderStore) DeleteOrder(id int) *StructureData.ErrorRespons
 	return nil
 }
 
-// GetAllOrders retrieves all orders (and their items) from the database.
-func (store *PostgresOrderStore) GetAllOrders() []StructureData.Order {
+// GetAllOrders retrieves all orders from the database, with their items loaded by
+// attachOrderItems: two queries however many orders there are.
+func (store *PostgresOrderStore) GetAllOrders(ctx context.Context) []StructureData.Order {
 	orders := []StructureData.Order{}
-	query := `SELECT id, customer_id, total_price, created_at, status FROM orders`
-	rows, err := store.db.Query(query)
+	query := `SELECT id, customer_id, total_price, created_at, status FROM orders ORDER BY id`
+	rows, err := store.db.QueryContext(ctx, query)
 	if err != nil {
 		log.Printf("Error querying orders: %v", err)
 		return orders
 	}
-	defer rows.Close()
-
 	for rows.Next() {
 		var order StructureData.Order
 		err = rows.Scan(&order.ID, &order.Customer.ID, &order.TotalPrice, &order.CreatedAt, &order.Status)
@@ -236,118 +232,149 @@ func (store *PostgresOrderStore) GetAllOrders() []StructureData.Order {
 			log.Printf("Error scanning order: %v", err)
 			continue
 		}
-
-		// Join order_items with books to get full book details for each item.
-		itemQuery := `
-			SELECT oi.book_id, oi.quantity, b.title, b.price, b.stock
-			FROM order_items oi
-			LEFT JOIN books b ON oi.book_id = b.id
-			WHERE oi.order_id = $1`
-		itemRows, err := store.db.Query(itemQuery, order.ID)
-		if err != nil {
-			log.Printf("Error querying order items for order ID %d: %v", order.ID, err)
-		} else {
-			for itemRows.Next() {
-				var item StructureData.OrderItem
-				// Scan additional book details (title, price, stock) into the Book sub-struct.
-				err = itemRows.Scan(&item.Book.ID, &item.Quantity, &item.Book.Title, &item.Book.Price, &item.Book.Stock)
-				if err != nil {
-					log.Printf("Error scanning order item for order ID %d: %v", order.ID, err)
-					continue
-				}
-				order.Items = append(order.Items, item)
-			}
-			itemRows.Close()
-		}
 		orders = append(orders, order)
 	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Printf("Error querying orders: %v", err)
+	}
+
+	if errResp := store.attachOrderItems(ctx, orders); errResp != nil {
+		log.Printf("Error querying order items: %v", errResp.Message)
+	}
 	log.Printf("Retrieved %d orders", len(orders))
 	return orders
 }
 
+// orderSortColumns maps StructureData.OrderSortFields to columns of orders.
+var orderSortColumns = map[string]string{
+	"id":          "id",
+	"created_at":  "created_at",
+	"total_price": "total_price",
+	"status":      "status",
+}
 
-// SearchOrders filters orders based on the provided criteria.
-func (store *PostgresOrderStore) SearchOrders(criteria StructureData.OrderSearchCriteria) ([]StructureData.Order, *StructureData.ErrorResponse) {
-	allOrders := store.GetAllOrders()
-	filteredOrders := []StructureData.Order{}
-	for _, order := range allOrders {
-		// Filter by order IDs.
-		if len(criteria.IDs) > 0 {
-			found := false
-			for _, id := range criteria.IDs {
-				if order.ID == id {
-					found = true
-					break
-				}
-			}
-			if !found {
-				continue
-			}
-		}
-		// Filter by customer IDs.
-		if len(criteria.CustomerIDs) > 0 {
-			found := false
-			for _, cid := range criteria.CustomerIDs {
-				if order.Customer.ID == cid {
-					found = true
-					break
-				}
-			}
-			if !found {
-				continue
-			}
-		}
-		// Filter by total price.
-		if criteria.MinTotalPrice > 0 && order.TotalPrice < criteria.MinTotalPrice {
-			continue
-		}
-		if criteria.MaxTotalPrice > 0 && order.TotalPrice > criteria.MaxTotalPrice {
-			continue
-		}
-		// Filter by creation time.
-		if !criteria.MinCreatedAt.IsZero() && order.CreatedAt.Before(criteria.MinCreatedAt) {
-			continue
-		}
-		if !criteria.MaxCreatedAt.IsZero() && order.CreatedAt.After(criteria.MaxCreatedAt) {
-			continue
-		}
-		// Filter by status.
-		if criteria.Status != "" && order.Status != criteria.Status {
-			continue
+// ListOrders returns the page of orders selected by opts, with their items.
+func (store *PostgresOrderStore) ListOrders(ctx context.Context, opts StructureData.ListOptions) ([]StructureData.Order, StructureData.ListMeta, *StructureData.ErrorResponse) {
+	var total int
+	if err := store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM orders`).Scan(&total); err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to count orders: %v", err)}
+	}
+
+	query, args := pageQuery(`SELECT id, customer_id, total_price, created_at, status FROM orders`, opts, orderSortColumns)
+	rows, err := store.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to list orders: %v", err)}
+	}
+	orders := []StructureData.Order{}
+	for rows.Next() {
+		var order StructureData.Order
+		if err := rows.Scan(&order.ID, &order.Customer.ID, &order.TotalPrice, &order.CreatedAt, &order.Status); err != nil {
+			rows.Close()
+			return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error scanning order: %v", err)}
 		}
-		// Filter by order items criteria.
-		if !matchOrderItems(order.Items, criteria.ItemCriteria) {
-			continue
+		orders = append(orders, order)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to list orders: %v", err)}
+	}
+	orders, meta := utils.FinishPage(orders, opts, total)
+
+	if opts.Wants("items") {
+		if errResp := store.attachOrderItems(ctx, orders); errResp != nil {
+			return nil, StructureData.ListMeta{}, errResp
 		}
-		filteredOrders = append(filteredOrders, order)
 	}
-	log.Printf("Search returned %d orders", len(filteredOrders))
-	return filteredOrders, nil
+	return orders, meta, nil
 }
 
-// Helper function to match order items based on search criteria.
-func matchOrderItems(items []StructureData.OrderItem, criteria StructureData.OrderItemSearchCriteria) bool {
-	// If no criteria are provided, consider it a match.
-	if criteria.MinQuantity == 0 && criteria.MaxQuantity == 0 {
-		return true
+// attachOrderItems loads the items of orders in one query.
+func (store *PostgresOrderStore) attachOrderItems(ctx context.Context, orders []StructureData.Order) *StructureData.ErrorResponse {
+	if len(orders) == 0 {
+		return nil
 	}
-	for _, item := range items {
-		if criteria.MinQuantity > 0 && item.Quantity < criteria.MinQuantity {
-			continue
+	ids := make([]int, len(orders))
+	for i, order := range orders {
+		ids[i] = order.ID
+	}
+	items, errResp := store.itemsForOrders(ctx, ids)
+	if errResp != nil {
+		return errResp
+	}
+	for i := range orders {
+		orders[i].Items = items[orders[i].ID]
+	}
+	return nil
+}
+
+// itemsForOrders loads the items of the given orders in one query, keyed by order ID.
+func (store *PostgresOrderStore) itemsForOrders(ctx context.Context, orderIDs []int) (map[int][]StructureData.OrderItem, *StructureData.ErrorResponse) {
+	rows, err := store.db.QueryContext(ctx, `
+		SELECT oi.order_id, oi.book_id, oi.quantity, oi.unit_price, oi.book_title, oi.author_name,
+		       COALESCE(b.title, oi.book_title), COALESCE(b.price, oi.unit_price), COALESCE(b.stock, 0)
+		FROM order_items oi
+		LEFT JOIN books b ON oi.book_id = b.id
+		WHERE oi.order_id = ANY($1)
+		ORDER BY oi.order_id, oi.id`, intArray(orderIDs))
+	if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching order items: %v", err)}
+	}
+	defer rows.Close()
+
+	items := map[int][]StructureData.OrderItem{}
+	for rows.Next() {
+		var orderID int
+		var item StructureData.OrderItem
+		err := rows.Scan(&orderID, &item.Book.ID, &item.Quantity, &item.UnitPrice, &item.BookTitle, &item.AuthorName,
+			&item.Book.Title, &item.Book.Price, &item.Book.Stock)
+		if err != nil {
+			return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error scanning order item: %v", err)}
 		}
-		if criteria.MaxQuantity > 0 && item.Quantity > criteria.MaxQuantity {
-			continue
+		items[orderID] = append(items[orderID], item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching order items: %v", err)}
+	}
+	return items, nil
+}
+
+// SearchOrders returns the orders matching criteria, with their items, filtered in SQL.
+func (store *PostgresOrderStore) SearchOrders(ctx context.Context, criteria StructureData.OrderSearchCriteria) ([]StructureData.Order, *StructureData.ErrorResponse) {
+	where := newWhereClause()
+	orderConditions(where, "o", criteria)
+	query := `SELECT o.id, o.customer_id, o.total_price, o.created_at, o.status FROM orders o` + where.String() + ` ORDER BY o.id`
+	rows, err := store.db.QueryContext(ctx, query, where.Args()...)
+	if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to search orders: %v", err)}
+	}
+	orders := []StructureData.Order{}
+	for rows.Next() {
+		var order StructureData.Order
+		if err := rows.Scan(&order.ID, &order.Customer.ID, &order.TotalPrice, &order.CreatedAt, &order.Status); err != nil {
+			rows.Close()
+			return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error scanning order: %v", err)}
 		}
-		return true
+		orders = append(orders, order)
 	}
-	return false
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to search orders: %v", err)}
+	}
+
+	if errResp := store.attachOrderItems(ctx, orders); errResp != nil {
+		return nil, errResp
+	}
+	log.Printf("Search returned %d orders", len(orders))
+	return orders, nil
 }
 
-// GetOrdersInTimeRange retrieves orders created within a specified time range.
-func (store *PostgresOrderStore) GetOrdersInTimeRange(start, end time.Time) ([]StructureData.Order, error) {
+// GetOrdersInTimeRange retrieves orders created within a specified time range,
+// with their items.
+func (store *PostgresOrderStore) GetOrdersInTimeRange(ctx context.Context, start, end time.Time) ([]StructureData.Order, error) {
 	orders := []StructureData.Order{}
 	query := `SELECT id, customer_id, total_price, created_at, status FROM orders WHERE created_at >= $1 AND created_at <= $2`
-	rows, err := store.db.Query(query, start, end)
+	rows, err := store.db.QueryContext(ctx, query, start, end)
 	if err != nil {
 		log.Printf("Error querying orders in time range: %v", err)
 		return orders, err
@@ -362,6 +389,376 @@ func (store *PostgresOrderStore) GetOrdersInTimeRange(start, end time.Time) ([]S
 		}
 		orders = append(orders, order)
 	}
+	if errResp := store.attachOrderItems(ctx, orders); errResp != nil {
+		return orders, errResp
+	}
 	log.Printf("Retrieved %d orders in the specified time range", len(orders))
 	return orders, nil
 }
+
+// PlaceOrder reserves stock for the order's items and inserts the order in a single
+// transaction. Each book's stock is decremented with a conditional UPDATE, so two
+// concurrent orders can never both take the last copy. Item prices and the order
+// total are taken from the locked book rows.
+//
+// If any item's book is missing or short of stock, nothing is written and the
+// returned error lists every rejected item. With allowPartial, those items are
+// left out instead and reported in the returned order's RejectedItems; the order
+// is still refused if no item remains.
+func (store *PostgresOrderStore) PlaceOrder(ctx context.Context, order StructureData.Order, allowPartial bool) (StructureData.Order, StructureData.StockLevels, *StructureData.ErrorResponse) {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return StructureData.Order{}, nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to begin transaction: %v", err)}
+	}
+	defer tx.Rollback()
+
+	levels := StructureData.StockLevels{}
+	items, rejected, errResp := reserveStock(ctx, tx, order.Items, levels)
+	if errResp != nil {
+		return StructureData.Order{}, nil, errResp
+	}
+	if errResp := StructureData.CheckRejections(items, rejected, allowPartial); errResp != nil {
+		return StructureData.Order{}, nil, errResp
+	}
+	order.Items = items
+	order.RejectedItems = rejected
+	order.TotalPrice = StructureData.ItemsTotal(items)
+
+	order, errResp = insertOrder(ctx, tx, order)
+	if errResp != nil {
+		return StructureData.Order{}, nil, errResp
+	}
+
+	if err := tx.Commit(); err != nil {
+		return StructureData.Order{}, nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to commit transaction: %v", err)}
+	}
+	log.Printf("Placed order ID %d with %d items", order.ID, len(order.Items))
+	return order, levels, nil
+}
+
+// ReplaceOrder returns the stock held by order id and reserves stock for the new
+// items in a single transaction, then overwrites the order. The same rules as
+// PlaceOrder apply to the new items. Only pending orders can be replaced.
+func (store *PostgresOrderStore) ReplaceOrder(ctx context.Context, id int, order StructureData.Order, allowPartial bool) (StructureData.Order, StructureData.StockLevels, *StructureData.ErrorResponse) {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return StructureData.Order{}, nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to begin transaction: %v", err)}
+	}
+	defer tx.Rollback()
+
+	if errResp := lockModifiableOrder(ctx, tx, id); errResp != nil {
+		return StructureData.Order{}, nil, errResp
+	}
+
+	// Restocking and reserving touch different sets of books; lock all of them up
+	// front, in ID order, so this cannot deadlock with a concurrent order.
+	if errResp := lockOrderBooks(ctx, tx, id, order.Items); errResp != nil {
+		return StructureData.Order{}, nil, errResp
+	}
+
+	levels := StructureData.StockLevels{}
+	if errResp := restoreStock(ctx, tx, id, levels); errResp != nil {
+		return StructureData.Order{}, nil, errResp
+	}
+	items, rejected, errResp := reserveStock(ctx, tx, order.Items, levels)
+	if errResp != nil {
+		return StructureData.Order{}, nil, errResp
+	}
+	if errResp := StructureData.CheckRejections(items, rejected, allowPartial); errResp != nil {
+		return StructureData.Order{}, nil, errResp
+	}
+	order.Items = items
+	order.RejectedItems = rejected
+	order.TotalPrice = StructureData.ItemsTotal(items)
+
+	if errResp := updateOrderRows(ctx, tx, id, order); errResp != nil {
+		return StructureData.Order{}, nil, errResp
+	}
+
+	if err := tx.Commit(); err != nil {
+		return StructureData.Order{}, nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to commit transaction: %v", err)}
+	}
+	order.ID = id
+	log.Printf("Replaced order ID %d with %d items", id, len(order.Items))
+	return order, levels, nil
+}
+
+// RemoveOrder deletes order id and returns its items to stock in a single
+// transaction. Only pending orders can be removed.
+func (store *PostgresOrderStore) RemoveOrder(ctx context.Context, id int) (StructureData.StockLevels, *StructureData.ErrorResponse) {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to begin transaction: %v", err)}
+	}
+	defer tx.Rollback()
+
+	if errResp := lockModifiableOrder(ctx, tx, id); errResp != nil {
+		return nil, errResp
+	}
+
+	levels := StructureData.StockLevels{}
+	if errResp := restoreStock(ctx, tx, id, levels); errResp != nil {
+		return nil, errResp
+	}
+	// order_items rows go with the order through ON DELETE CASCADE.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM orders WHERE id=$1`, id); err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to delete order: %v", err)}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to commit transaction: %v", err)}
+	}
+	log.Printf("Removed order ID %d and restocked its items", id)
+	return levels, nil
+}
+
+// lockModifiableOrder locks order id for the rest of tx and checks that it is
+// still pending, the only status in which its items may change.
+func lockModifiableOrder(ctx context.Context, tx *sql.Tx, id int) *StructureData.ErrorResponse {
+	status, errResp := lockOrderStatus(ctx, tx, id)
+	if errResp != nil {
+		return errResp
+	}
+	if status != StructureData.OrderStatusPending {
+		return StructureData.ErrOrderLocked
+	}
+	return nil
+}
+
+// lockOrderStatus locks order id for the rest of tx and returns its status.
+func lockOrderStatus(ctx context.Context, tx *sql.Tx, id int) (string, *StructureData.ErrorResponse) {
+	var status string
+	err := tx.QueryRowContext(ctx, `SELECT status FROM orders WHERE id=$1 FOR UPDATE`, id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", StructureData.ErrOrderNotFound
+	} else if err != nil {
+		return "", &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching order: %v", err)}
+	}
+	return status, nil
+}
+
+// TransitionOrder moves order id to status to and records the change in the
+// order's status history, in a single transaction. Cancelling an order returns
+// its items to stock; the new stock levels are returned for those books. actorID
+// is the customer making the change, or 0 if it is not known.
+func (store *PostgresOrderStore) TransitionOrder(ctx context.Context, id int, to string, actorID int, note string) (StructureData.OrderStatusChange, StructureData.StockLevels, *StructureData.ErrorResponse) {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return StructureData.OrderStatusChange{}, nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to begin transaction: %v", err)}
+	}
+	defer tx.Rollback()
+
+	from, errResp := lockOrderStatus(ctx, tx, id)
+	if errResp != nil {
+		return StructureData.OrderStatusChange{}, nil, errResp
+	}
+	if !StructureData.CanTransitionOrder(from, to) {
+		return StructureData.OrderStatusChange{}, nil, StructureData.ErrInvalidTransition
+	}
+
+	levels := StructureData.StockLevels{}
+	if to == StructureData.OrderStatusCancelled {
+		if errResp := restoreStock(ctx, tx, id, levels); errResp != nil {
+			return StructureData.OrderStatusChange{}, nil, errResp
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE orders SET status=$2 WHERE id=$1`, id, to); err != nil {
+		return StructureData.OrderStatusChange{}, nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to update order status: %v", err)}
+	}
+
+	change := StructureData.OrderStatusChange{OrderID: id, FromStatus: from, ToStatus: to, Note: note}
+	changedBy := sql.NullInt64{Int64: int64(actorID), Valid: actorID != 0}
+	if changedBy.Valid {
+		change.ChangedBy = &actorID
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO order_status_history (order_id, from_status, to_status, changed_by, note)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, changed_at`,
+		id, from, to, changedBy, note,
+	).Scan(&change.ID, &change.ChangedAt)
+	if err != nil {
+		return StructureData.OrderStatusChange{}, nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to record status change: %v", err)}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return StructureData.OrderStatusChange{}, nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to commit transaction: %v", err)}
+	}
+	log.Printf("Moved order ID %d from %s to %s", id, from, to)
+	return change, levels, nil
+}
+
+// GetOrderHistory returns the status changes of order id, oldest first.
+func (store *PostgresOrderStore) GetOrderHistory(ctx context.Context, id int) ([]StructureData.OrderStatusChange, *StructureData.ErrorResponse) {
+	rows, err := store.db.QueryContext(ctx, `
+		SELECT id, order_id, from_status, to_status, changed_at, changed_by, note
+		FROM order_status_history
+		WHERE order_id = $1
+		ORDER BY changed_at, id`, id)
+	if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching order history: %v", err)}
+	}
+	defer rows.Close()
+
+	history := []StructureData.OrderStatusChange{}
+	for rows.Next() {
+		var change StructureData.OrderStatusChange
+		var changedBy sql.NullInt64
+		if err := rows.Scan(&change.ID, &change.OrderID, &change.FromStatus, &change.ToStatus, &change.ChangedAt, &changedBy, &change.Note); err != nil {
+			return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error scanning order history: %v", err)}
+		}
+		if changedBy.Valid {
+			actorID := int(changedBy.Int64)
+			change.ChangedBy = &actorID
+		}
+		history = append(history, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching order history: %v", err)}
+	}
+	return history, nil
+}
+
+// lockOrderBooks locks, in ID order, the books currently held by order orderID and
+// the books referenced by items.
+func lockOrderBooks(ctx context.Context, tx *sql.Tx, orderID int, items []StructureData.OrderItem) *StructureData.ErrorResponse {
+	bookIDs := make([]int, 0, len(items))
+	for _, item := range items {
+		bookIDs = append(bookIDs, item.Book.ID)
+	}
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id FROM books
+		WHERE id = ANY($1) OR id IN (SELECT book_id FROM order_items WHERE order_id = $2)
+		ORDER BY id
+		FOR UPDATE`, intArray(bookIDs), orderID)
+	if err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to lock books: %v", err)}
+	}
+	defer rows.Close()
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to lock books: %v", err)}
+	}
+	return nil
+}
+
+// reserveStock decrements stock for each item within tx and returns the items that
+// could be reserved, with the book's current title, price and author captured on
+// each item, and the items that could not. Books are updated in ID order so
+// concurrent transactions lock rows in the same order and cannot deadlock. New
+// stock levels are recorded in levels.
+func reserveStock(ctx context.Context, tx *sql.Tx, items []StructureData.OrderItem, levels StructureData.StockLevels) ([]StructureData.OrderItem, []StructureData.OrderItemRejection, *StructureData.ErrorResponse) {
+	byBook := make([]int, len(items))
+	for i := range byBook {
+		byBook[i] = i
+	}
+	sort.SliceStable(byBook, func(a, b int) bool { return items[byBook[a]].Book.ID < items[byBook[b]].Book.ID })
+
+	reserved := make([]bool, len(items))
+	rejections := make([]*StructureData.OrderItemRejection, len(items))
+	for _, i := range byBook {
+		item := items[i]
+		if item.Quantity < 1 {
+			rejections[i] = &StructureData.OrderItemRejection{BookID: item.Book.ID, Quantity: item.Quantity, Reason: StructureData.RejectionInvalidQuantity}
+			continue
+		}
+		var title, authorName string
+		var price float64
+		var stock int
+		err := tx.QueryRowContext(ctx, `
+			UPDATE books SET stock = stock - $2
+			WHERE id = $1 AND stock >= $2
+			RETURNING title, price, stock,
+				COALESCE((SELECT first_name || ' ' || last_name FROM authors WHERE authors.id = books.author_id), '')`,
+			item.Book.ID, item.Quantity).Scan(&title, &price, &stock, &authorName)
+		if err == sql.ErrNoRows {
+			rejection, errResp := stockRejection(ctx, tx, item)
+			if errResp != nil {
+				return nil, nil, errResp
+			}
+			rejections[i] = rejection
+			continue
+		} else if err != nil {
+			return nil, nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to reserve stock for book %d: %v", item.Book.ID, err)}
+		}
+		items[i].Book.Title = title
+		items[i].Book.Price = price
+		items[i].Book.Stock = stock
+		items[i].UnitPrice = price
+		items[i].BookTitle = title
+		items[i].AuthorName = authorName
+		levels[item.Book.ID] = stock
+		reserved[i] = true
+	}
+
+	var result []StructureData.OrderItem
+	var rejected []StructureData.OrderItemRejection
+	for i, item := range items {
+		if reserved[i] {
+			result = append(result, item)
+		} else if rejections[i] != nil {
+			rejected = append(rejected, *rejections[i])
+		}
+	}
+	return result, rejected, nil
+}
+
+// stockRejection explains why item's conditional stock update matched no row.
+func stockRejection(ctx context.Context, tx *sql.Tx, item StructureData.OrderItem) (*StructureData.OrderItemRejection, *StructureData.ErrorResponse) {
+	rejection := &StructureData.OrderItemRejection{BookID: item.Book.ID, Quantity: item.Quantity}
+	var available int
+	err := tx.QueryRowContext(ctx, `SELECT stock FROM books WHERE id = $1`, item.Book.ID).Scan(&available)
+	if err == sql.ErrNoRows {
+		rejection.Reason = StructureData.RejectionNotFound
+		return rejection, nil
+	} else if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching book %d: %v", item.Book.ID, err)}
+	}
+	rejection.Reason = StructureData.RejectionInsufficientStock
+	rejection.Available = &available
+	return rejection, nil
+}
+
+// restoreStock adds the quantities held by order orderID back to their books
+// within tx, in book ID order, and records the new stock levels in levels.
+func restoreStock(ctx context.Context, tx *sql.Tx, orderID int, levels StructureData.StockLevels) *StructureData.ErrorResponse {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT book_id, SUM(quantity)
+		FROM order_items
+		WHERE order_id = $1
+		GROUP BY book_id
+		ORDER BY book_id`, orderID)
+	if err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching order items: %v", err)}
+	}
+	held := map[int]int{}
+	var bookIDs []int
+	for rows.Next() {
+		var bookID, quantity int
+		if err := rows.Scan(&bookID, &quantity); err != nil {
+			rows.Close()
+			return &StructureData.ErrorResponse{Message: fmt.Sprintf("Error scanning order item: %v", err)}
+		}
+		held[bookID] = quantity
+		bookIDs = append(bookIDs, bookID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching order items: %v", err)}
+	}
+
+	for _, bookID := range bookIDs {
+		var stock int
+		err := tx.QueryRowContext(ctx, `UPDATE books SET stock = stock + $2 WHERE id = $1 RETURNING stock`, bookID, held[bookID]).Scan(&stock)
+		if err == sql.ErrNoRows {
+			// The book was deleted; there is nothing to return the copies to.
+			continue
+		} else if err != nil {
+			return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to restock book %d: %v", bookID, err)}
+		}
+		levels[bookID] = stock
+	}
+	return nil
+}
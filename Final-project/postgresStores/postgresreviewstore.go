@@ -1,12 +1,14 @@
 package postgresStores
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"finalProject/StructureData"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // PostgresReviewStore implements the review storage using PostgreSQL.
@@ -19,47 +21,57 @@ var postgresReviewStoreInstance *PostgresReviewStore
 // GetPostgresReviewStoreInstance returns a singleton instance of PostgresReviewStore.
 func GetPostgresReviewStoreInstance() *PostgresReviewStore {
 	if postgresReviewStoreInstance == nil {
-		connStr := "user=postgres password=root dbname=booklibrary sslmode=disable"
-		db, err := sql.Open("postgres", connStr)
-		if err != nil {
-			panic(fmt.Sprintf("Failed to connect to Postgres for reviews: %v", err))
-		}
-		if err := db.Ping(); err != nil {
-			panic(fmt.Sprintf("Failed to ping Postgres for reviews: %v", err))
-		}
+		db := getDB()
 		postgresReviewStoreInstance = &PostgresReviewStore{db: db}
-		log.Println("Connected to Postgres for reviews.")
 	}
 	return postgresReviewStoreInstance
 }
 
-// Close gracefully closes the database connection.
-func (store *PostgresReviewStore) Close() error {
-	return store.db.Close()
-}
-
-// CreateReview inserts a new review into the reviews table.
-func (store *PostgresReviewStore) CreateReview(review StructureData.Review) (StructureData.Review, *StructureData.ErrorResponse) {
+// CreateReview inserts a new review into the reviews table. A review of a book that
+// does not exist is a validation error on book_id.
+func (store *PostgresReviewStore) CreateReview(ctx context.Context, review StructureData.Review) (StructureData.Review, *StructureData.ErrorResponse) {
 	query := `
 		INSERT INTO reviews (book_id, customer_id, rating, review_text, created_at)
 		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id`
-	err := store.db.QueryRow(query, review.BookID, review.CustomerID, review.Rating, review.ReviewText, review.CreatedAt).Scan(&review.ID)
+	err := store.db.QueryRowContext(ctx, query, review.BookID, review.CustomerID, review.Rating, review.ReviewText, review.CreatedAt).Scan(&review.ID)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "foreign_key_violation" && pqErr.Constraint == "reviews_book_id_fkey" {
+		return StructureData.Review{}, StructureData.FieldValidationError("book_id", "Book %d not found", review.BookID)
+	}
 	if err != nil {
 		return StructureData.Review{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to create review: %v", err)}
 	}
-	go updateBookReviewStats(review.BookID)
+	// Runs after the response, so it must not inherit the request deadline.
+	go updateBookReviewStats(context.WithoutCancel(ctx), review.BookID)
     return review, nil
 }
 
+// GetReview retrieves a single review by its ID.
+func (store *PostgresReviewStore) GetReview(ctx context.Context, id int) (StructureData.Review, *StructureData.ErrorResponse) {
+	query := `
+		SELECT id, book_id, COALESCE(customer_id, 0), rating, review_text, created_at
+		FROM reviews
+		WHERE id = $1`
+	var r StructureData.Review
+	err := store.db.QueryRowContext(ctx, query, id).Scan(&r.ID, &r.BookID, &r.CustomerID, &r.Rating, &r.ReviewText, &r.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return StructureData.Review{}, StructureData.NotFoundError("Review not found")
+		}
+		return StructureData.Review{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to fetch review: %v", err)}
+	}
+	return r, nil
+}
+
 // GetReviewsByBookID retrieves all reviews for a given book, ordered by creation time (most recent first).
-func (store *PostgresReviewStore) GetReviewsByBookID(bookID int) ([]StructureData.Review, *StructureData.ErrorResponse) {
+func (store *PostgresReviewStore) GetReviewsByBookID(ctx context.Context, bookID int) ([]StructureData.Review, *StructureData.ErrorResponse) {
 	query := `
 		SELECT id, book_id, customer_id, rating, review_text, created_at
 		FROM reviews
 		WHERE book_id = $1
 		ORDER BY created_at DESC`
-	rows, err := store.db.Query(query, bookID)
+	rows, err := store.db.QueryContext(ctx, query, bookID)
 	if err != nil {
 		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to fetch reviews: %v", err)}
 	}
@@ -78,42 +90,42 @@ func (store *PostgresReviewStore) GetReviewsByBookID(bookID int) ([]StructureDat
 	return reviews, nil
 }
 
-func updateBookReviewStats(bookID int) {
-    stats, err := GetPostgresReviewStoreInstance().GetBookReviewStats(bookID)
+func updateBookReviewStats(ctx context.Context, bookID int) {
+    stats, err := GetPostgresReviewStoreInstance().GetBookReviewStats(ctx, bookID)
     if err != nil {
         log.Printf("Failed to update review stats for book %d: %v", bookID, err)
         return
     }
 
     bookStore := GetPostgresBookStoreInstance()
-    book, errResp := bookStore.GetBook(bookID)
+    book, errResp := bookStore.GetBook(ctx, bookID)
     if errResp != nil {
         log.Printf("Book %d not found for stats update: %v", bookID, errResp)
         return
     }
 
     book.ReviewStats = &stats
-    _, errResp = bookStore.UpdateBook(bookID, book)
+    _, errResp = bookStore.UpdateBook(ctx, bookID, book)
     if errResp != nil {
         log.Printf("Failed to update book %d review stats: %v", bookID, errResp)
     }
 }
 
 // DeleteReview removes a review from the database.
-func (store *PostgresReviewStore) DeleteReview(id int) *StructureData.ErrorResponse {
+func (store *PostgresReviewStore) DeleteReview(ctx context.Context, id int) *StructureData.ErrorResponse {
 	query := `DELETE FROM reviews WHERE id = $1`
-	res, err := store.db.Exec(query, id)
+	res, err := store.db.ExecContext(ctx, query, id)
 	if err != nil {
 		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to delete review: %v", err)}
 	}
 	rowsAffected, _ := res.RowsAffected()
 	if rowsAffected == 0 {
-		return &StructureData.ErrorResponse{Message: "Review not found"}
+		return StructureData.NotFoundError("Review not found")
 	}
 	return nil
 }
 // Add to postgresStores/reviewStore.go
-func (store *PostgresReviewStore) GetBookReviewStats(bookID int) (StructureData.BookReviewAggregate, error) {
+func (store *PostgresReviewStore) GetBookReviewStats(ctx context.Context, bookID int) (StructureData.BookReviewAggregate, error) {
     query := `
         SELECT 
             COALESCE(AVG(rating), 0), 
@@ -122,7 +134,7 @@ func (store *PostgresReviewStore) GetBookReviewStats(bookID int) (StructureData.
         WHERE book_id = $1`
     
     var stats StructureData.BookReviewAggregate
-    err := store.db.QueryRow(query, bookID).Scan(
+    err := store.db.QueryRowContext(ctx, query, bookID).Scan(
         &stats.AverageRating,
         &stats.ReviewCount,
     )
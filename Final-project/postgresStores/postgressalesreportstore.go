@@ -1,7 +1,9 @@
 package postgresStores
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
 
@@ -20,44 +22,37 @@ var postgresSalesReportStoreInstance *PostgresSalesReportStore
 // GetPostgresSalesReportStoreInstance returns a singleton instance.
 func GetPostgresSalesReportStoreInstance() *PostgresSalesReportStore {
 	if postgresSalesReportStoreInstance == nil {
-		connStr := "user=postgres password=root dbname=booklibrary sslmode=disable"
-		db, err := sql.Open("postgres", connStr)
-		if err != nil {
-			panic(fmt.Sprintf("Failed to connect to Postgres for sales reports: %v", err))
-		}
-		if err := db.Ping(); err != nil {
-			panic(fmt.Sprintf("Failed to ping Postgres for sales reports: %v", err))
-		}
+		db := getDB()
 		postgresSalesReportStoreInstance = &PostgresSalesReportStore{db: db}
-		log.Println("Connected to Postgres for sales reports.")
 	}
 	return postgresSalesReportStoreInstance
 }
 
-// Close gracefully closes the underlying DB connection.
-func (store *PostgresSalesReportStore) Close() error {
-	return store.db.Close()
-}
-
 // SaveSalesReport inserts a new sales report and its top selling books into PostgreSQL.
-func (store *PostgresSalesReportStore) SaveSalesReport(report StructureData.SalesReport) (*StructureData.SalesReport, *StructureData.ErrorResponse) {
-	tx, err := store.db.Begin()
+func (store *PostgresSalesReportStore) SaveSalesReport(ctx context.Context, report StructureData.SalesReport) (*StructureData.SalesReport, *StructureData.ErrorResponse) {
+	tx, err := store.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to begin transaction: %v", err)}
 	}
 	defer tx.Rollback()
 
+	statusCounts, err := json.Marshal(report.StatusCounts)
+	if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to encode status counts: %v", err)}
+	}
+
 	// Insert the sales report header.
 	reportQuery := `
-		INSERT INTO sales_reports (timestamp, total_revenue, total_orders, successful_orders, pending_orders)
-		VALUES ($1, $2, $3, $4, $5) RETURNING id`
+		INSERT INTO sales_reports (timestamp, total_revenue, total_orders, successful_orders, pending_orders, status_counts)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
 	var reportID int
-	err = tx.QueryRow(reportQuery,
+	err = tx.QueryRowContext(ctx, reportQuery,
 		report.Timestamp,
 		report.TotalRevenue,
 		report.TotalOrders,
 		report.SuccessfulOrders,
 		report.PendingOrders,
+		statusCounts,
 	).Scan(&reportID)
 	if err != nil {
 		log.Printf("Error inserting sales report: %v", err)
@@ -72,7 +67,7 @@ func (store *PostgresSalesReportStore) SaveSalesReport(report StructureData.Sale
 			(sales_report_id, book_id, quantity_sold, total_revenue, book_title, book_price)
 		VALUES ($1, $2, $3, $4, $5, $6)`
 	for _, tsb := range report.TopSellingBooks {
-		_, err = tx.Exec(bookQuery,
+		_, err = tx.ExecContext(ctx, bookQuery,
 			reportID,
 			tsb.Book.ID,
 			tsb.QuantitySold,
@@ -93,68 +88,73 @@ func (store *PostgresSalesReportStore) SaveSalesReport(report StructureData.Sale
 	return &report, nil
 }
 
-// GetAllSalesReports retrieves all sales reports and their top selling books from PostgreSQL.
-func (store *PostgresSalesReportStore) GetAllSalesReports() ([]StructureData.SalesReport, *StructureData.ErrorResponse) {
+// GetAllSalesReports retrieves all sales reports, oldest first, and their top
+// selling books, with one query for each.
+func (store *PostgresSalesReportStore) GetAllSalesReports(ctx context.Context) ([]StructureData.SalesReport, *StructureData.ErrorResponse) {
 	const mainQuery = `
-		SELECT id, timestamp, total_revenue, total_orders, successful_orders, pending_orders 
-		FROM sales_reports`
-	rows, err := store.db.Query(mainQuery)
+		SELECT id, timestamp, total_revenue, total_orders, successful_orders, pending_orders, status_counts
+		FROM sales_reports
+		ORDER BY id`
+	rows, err := store.db.QueryContext(ctx, mainQuery)
 	if err != nil {
 		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to fetch sales reports: %v", err)}
 	}
-	defer rows.Close()
 
 	var reports []StructureData.SalesReport
+	var reportIDs []int
 	for rows.Next() {
 		var report StructureData.SalesReport
 		var reportID int
-		err := rows.Scan(&reportID, &report.Timestamp, &report.TotalRevenue, &report.TotalOrders, &report.SuccessfulOrders, &report.PendingOrders)
+		var statusCounts []byte
+		err := rows.Scan(&reportID, &report.Timestamp, &report.TotalRevenue, &report.TotalOrders, &report.SuccessfulOrders, &report.PendingOrders, &statusCounts)
 		if err != nil {
 			log.Printf("Error scanning sales report: %v", err)
 			continue
 		}
+		if err := json.Unmarshal(statusCounts, &report.StatusCounts); err != nil {
+			log.Printf("Error decoding status counts for report ID %d: %v", reportID, err)
+		}
+		reports = append(reports, report)
+		reportIDs = append(reportIDs, reportID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to fetch sales reports: %v", err)}
+	}
+	if len(reports) == 0 {
+		return reports, nil
+	}
+
+	// Query the top selling books of every report at once.
+	const tsbQuery = `
+		SELECT sales_report_id, book_id, quantity_sold, total_revenue, book_title, book_price
+		FROM top_selling_books
+		WHERE sales_report_id = ANY($1)
+		ORDER BY sales_report_id, total_revenue DESC`
+	tsbRows, err := store.db.QueryContext(ctx, tsbQuery, intArray(reportIDs))
+	if err != nil {
+		log.Printf("Error fetching top selling books: %v", err)
+		return reports, nil
+	}
+	defer tsbRows.Close()
 
-		// Query the top selling books for this report.
-		tsbQuery := `
-			SELECT book_id, quantity_sold, total_revenue, book_title, book_price
-			FROM top_selling_books
-			WHERE sales_report_id = $1
-			ORDER BY total_revenue DESC`
-		tsbRows, err := store.db.Query(tsbQuery, reportID)
+	index := make(map[int]int, len(reportIDs))
+	for i, id := range reportIDs {
+		index[id] = i
+	}
+	for tsbRows.Next() {
+		var reportID int
+		var tsb StructureData.TopSellingBook
+		err := tsbRows.Scan(&reportID, &tsb.Book.ID, &tsb.QuantitySold, &tsb.TotalRevenue, &tsb.Book.Title, &tsb.Book.Price)
 		if err != nil {
-			log.Printf("Error fetching top selling books for report ID %d: %v", reportID, err)
-			reports = append(reports, report)
+			log.Printf("Error scanning top selling book for report ID %d: %v", reportID, err)
 			continue
 		}
-		var topSellingBooks []StructureData.TopSellingBook
-		for tsbRows.Next() {
-			var tsb StructureData.TopSellingBook
-			var bookID int
-			var quantitySold int
-			var totalRevenue float64
-			var bookTitle string
-			var bookPrice float64
-
-			err = tsbRows.Scan(&bookID, &quantitySold, &totalRevenue, &bookTitle, &bookPrice)
-			if err != nil {
-				log.Printf("Error scanning top selling book for report ID %d: %v", reportID, err)
-				continue
-			}
-
-			tsb.QuantitySold = quantitySold
-			tsb.TotalRevenue = totalRevenue
-			tsb.Book = StructureData.Book{
-				ID:    bookID,
-				Title: bookTitle,
-				Price: bookPrice,
-			}
-			topSellingBooks = append(topSellingBooks, tsb)
-		}
-		tsbRows.Close()
-
-		report.TopSellingBooks = topSellingBooks
-		reports = append(reports, report)
+		i := index[reportID]
+		reports[i].TopSellingBooks = append(reports[i].TopSellingBooks, tsb)
+	}
+	if err := tsbRows.Err(); err != nil {
+		log.Printf("Error fetching top selling books: %v", err)
 	}
-
 	return reports, nil
 }
@@ -0,0 +1,287 @@
+package postgresStores
+
+import (
+	"fmt"
+	"strings"
+
+	"finalProject/StructureData"
+
+	"github.com/lib/pq"
+)
+
+// whereClause collects the conditions of a parameterised WHERE clause. The
+// conditions are ANDed together. Clauses made with nested share their parent's
+// arguments, so a subquery's placeholders number on from the outer query's.
+type whereClause struct {
+	conds []string
+	args  *[]interface{}
+}
+
+func newWhereClause() *whereClause {
+	return &whereClause{args: &[]interface{}{}}
+}
+
+// nested returns an empty clause for a subquery of w.
+func (w *whereClause) nested() *whereClause {
+	return &whereClause{args: w.args}
+}
+
+// bind adds value to the arguments and returns its placeholder.
+func (w *whereClause) bind(value interface{}) string {
+	*w.args = append(*w.args, value)
+	return fmt.Sprintf("$%d", len(*w.args))
+}
+
+// add appends a condition. Each %s in format is replaced by the placeholder of
+// the matching value.
+func (w *whereClause) add(format string, values ...interface{}) {
+	placeholders := make([]interface{}, len(values))
+	for i, value := range values {
+		placeholders[i] = w.bind(value)
+	}
+	w.conds = append(w.conds, fmt.Sprintf(format, placeholders...))
+}
+
+// addExists appends an EXISTS condition over from, restricted by join and the
+// conditions of sub. Nothing is added when sub has no conditions.
+func (w *whereClause) addExists(from, join string, sub *whereClause) {
+	if len(sub.conds) == 0 {
+		return
+	}
+	w.conds = append(w.conds, fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE %s AND %s)", from, join, sub.joined()))
+}
+
+// orderByScore returns an ORDER BY clause for the best matches first when score,
+// as returned by authorConditions or customerConditions, is set, and for id alone
+// otherwise.
+func orderByScore(score, id string) string {
+	if score == "" {
+		return " ORDER BY " + id
+	}
+	return " ORDER BY " + score + " DESC, " + id
+}
+
+func (w *whereClause) joined() string {
+	return strings.Join(w.conds, " AND ")
+}
+
+// String returns the clause with its leading WHERE, or "" without conditions.
+func (w *whereClause) String() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + w.joined()
+}
+
+// Args returns the arguments for the clause's placeholders.
+func (w *whereClause) Args() []interface{} {
+	return *w.args
+}
+
+func intArray(ids []int) interface{} {
+	values := make([]int64, len(ids))
+	for i, id := range ids {
+		values[i] = int64(id)
+	}
+	return pq.Array(values)
+}
+
+// containsPattern returns an ILIKE pattern matching text anywhere, with LIKE
+// wildcards in text escaped.
+func containsPattern(text string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(text)
+	return "%" + escaped + "%"
+}
+
+// fuzzyConditions adds a condition that one of terms is similar to a word run of
+// one of columns, as the pg_trgm %> operator decides. It returns an expression
+// for the best word_similarity found, to order the results by.
+func fuzzyConditions(w *whereClause, terms []string, columns ...string) string {
+	var matches, scores []string
+	for _, term := range terms {
+		p := w.bind(term)
+		for _, column := range columns {
+			matches = append(matches, fmt.Sprintf("%s %%> %s", column, p))
+			scores = append(scores, fmt.Sprintf("word_similarity(%s, %s)", p, column))
+		}
+	}
+	w.conds = append(w.conds, "("+strings.Join(matches, " OR ")+")")
+	return "GREATEST(" + strings.Join(scores, ", ") + ")"
+}
+
+// authorConditions adds the conditions of criteria on the authors row aliased a.
+// For fuzzy criteria it returns an expression scoring the similarity of a match,
+// and "" otherwise.
+func authorConditions(w *whereClause, a string, criteria StructureData.AuthorSearchCriteria) string {
+	if len(criteria.IDs) > 0 {
+		w.add(a+".id = ANY(%s)", intArray(criteria.IDs))
+	}
+	w.addFilter(criteria.Filter, StructureData.AuthorFilterFields, authorFilterColumns, a)
+	if criteria.Fuzzy {
+		var scores []string
+		if len(criteria.FirstNames) > 0 {
+			scores = append(scores, fuzzyConditions(w, criteria.FirstNames, a+".first_name"))
+		}
+		if len(criteria.LastNames) > 0 {
+			scores = append(scores, fuzzyConditions(w, criteria.LastNames, a+".last_name"))
+		}
+		if len(criteria.Keywords) > 0 {
+			scores = append(scores, fuzzyConditions(w, criteria.Keywords, a+".first_name", a+".last_name", a+".bio"))
+		}
+		return strings.Join(scores, " + ")
+	}
+	if len(criteria.FirstNames) > 0 {
+		w.add(a+".first_name = ANY(%s)", pq.Array(criteria.FirstNames))
+	}
+	if len(criteria.LastNames) > 0 {
+		w.add(a+".last_name = ANY(%s)", pq.Array(criteria.LastNames))
+	}
+	if len(criteria.Keywords) > 0 {
+		// Any keyword may match any of the name and bio columns.
+		var alternatives []string
+		for _, keyword := range criteria.Keywords {
+			p := w.bind(containsPattern(keyword))
+			alternatives = append(alternatives, fmt.Sprintf("%[1]s.first_name ILIKE %[2]s OR %[1]s.last_name ILIKE %[2]s OR %[1]s.bio ILIKE %[2]s", a, p))
+		}
+		w.conds = append(w.conds, "("+strings.Join(alternatives, " OR ")+")")
+	}
+	return ""
+}
+
+// bookConditions adds the conditions of criteria on the books row aliased b.
+func bookConditions(w *whereClause, b string, criteria StructureData.BookSearchCriteria) {
+	if len(criteria.IDs) > 0 {
+		w.add(b+".id = ANY(%s)", intArray(criteria.IDs))
+	}
+	w.addFilter(criteria.Filter, StructureData.BookFilterFields, bookFilterColumns, b)
+	if len(criteria.Titles) > 0 {
+		w.add(b+".title = ANY(%s)", pq.Array(criteria.Titles))
+	}
+	if len(criteria.Genres) > 0 {
+		w.add(b+".genres && %s", pq.Array(criteria.Genres))
+	}
+	if !criteria.MinPublishedAt.IsZero() {
+		w.add(b+".published_at >= %s", criteria.MinPublishedAt)
+	}
+	if !criteria.MaxPublishedAt.IsZero() {
+		w.add(b+".published_at <= %s", criteria.MaxPublishedAt)
+	}
+	if criteria.MinPrice > 0 {
+		w.add(b+".price >= %s", criteria.MinPrice)
+	}
+	if criteria.MaxPrice > 0 {
+		w.add(b+".price <= %s", criteria.MaxPrice)
+	}
+	if criteria.MinStock > 0 {
+		w.add(b+".stock >= %s", criteria.MinStock)
+	}
+	if criteria.MaxStock > 0 {
+		w.add(b+".stock <= %s", criteria.MaxStock)
+	}
+
+	author := w.nested()
+	authorConditions(author, "a", criteria.AuthorCriteria)
+	w.addExists("authors a", "a.id = "+b+".author_id", author)
+
+	// Books without reviews count as rated 0, as in the in-memory store.
+	averageRating := "(SELECT COALESCE(AVG(r.rating), 0) FROM reviews r WHERE r.book_id = " + b + ".id)"
+	reviewCount := "(SELECT COUNT(*) FROM reviews r WHERE r.book_id = " + b + ".id)"
+	if criteria.MinAverageRating > 0 {
+		w.add(averageRating+" >= %s", criteria.MinAverageRating)
+	}
+	if criteria.MaxAverageRating > 0 {
+		w.add(averageRating+" <= %s", criteria.MaxAverageRating)
+	}
+	if criteria.MinReviewCount > 0 {
+		w.add(reviewCount+" >= %s", criteria.MinReviewCount)
+	}
+	if criteria.MaxReviewCount > 0 {
+		w.add(reviewCount+" <= %s", criteria.MaxReviewCount)
+	}
+}
+
+// addressConditions adds the conditions of criteria on the address columns of the
+// customers row aliased c.
+func addressConditions(w *whereClause, c string, criteria StructureData.AddressSearchCriteria) {
+	if len(criteria.Streets) > 0 {
+		w.add(c+".street = ANY(%s)", pq.Array(criteria.Streets))
+	}
+	if len(criteria.Cities) > 0 {
+		w.add(c+".city = ANY(%s)", pq.Array(criteria.Cities))
+	}
+	if len(criteria.States) > 0 {
+		w.add(c+".state = ANY(%s)", pq.Array(criteria.States))
+	}
+	if len(criteria.PostalCodes) > 0 {
+		w.add(c+".postal_code = ANY(%s)", pq.Array(criteria.PostalCodes))
+	}
+	if len(criteria.Countries) > 0 {
+		w.add(c+".country = ANY(%s)", pq.Array(criteria.Countries))
+	}
+}
+
+// customerConditions adds the conditions of criteria on the customers row aliased
+// c. For fuzzy criteria it returns an expression scoring the name similarity of a
+// match, and "" otherwise.
+func customerConditions(w *whereClause, c string, criteria StructureData.CustomerSearchCriteria) string {
+	var score string
+	if len(criteria.IDs) > 0 {
+		w.add(c+".id = ANY(%s)", intArray(criteria.IDs))
+	}
+	w.addFilter(criteria.Filter, StructureData.CustomerFilterFields, customerFilterColumns, c)
+	if len(criteria.Names) > 0 {
+		if criteria.Fuzzy {
+			score = fuzzyConditions(w, criteria.Names, c+".name")
+		} else {
+			w.add(c+".name = ANY(%s)", pq.Array(criteria.Names))
+		}
+	}
+	if len(criteria.Emails) > 0 {
+		w.add(c+".email = ANY(%s)", pq.Array(criteria.Emails))
+	}
+	if !criteria.MinCreatedAt.IsZero() {
+		w.add(c+".created_at >= %s", criteria.MinCreatedAt)
+	}
+	if !criteria.MaxCreatedAt.IsZero() {
+		w.add(c+".created_at <= %s", criteria.MaxCreatedAt)
+	}
+	addressConditions(w, c, criteria.AddressCriteria)
+	return score
+}
+
+// orderConditions adds the conditions of criteria on the orders row aliased o. An
+// order matches the item criteria when at least one of its items does.
+func orderConditions(w *whereClause, o string, criteria StructureData.OrderSearchCriteria) {
+	if len(criteria.IDs) > 0 {
+		w.add(o+".id = ANY(%s)", intArray(criteria.IDs))
+	}
+	w.addFilter(criteria.Filter, StructureData.OrderFilterFields, orderFilterColumns, o)
+	if len(criteria.CustomerIDs) > 0 {
+		w.add(o+".customer_id = ANY(%s)", intArray(criteria.CustomerIDs))
+	}
+	if criteria.MinTotalPrice > 0 {
+		w.add(o+".total_price >= %s", criteria.MinTotalPrice)
+	}
+	if criteria.MaxTotalPrice > 0 {
+		w.add(o+".total_price <= %s", criteria.MaxTotalPrice)
+	}
+	if !criteria.MinCreatedAt.IsZero() {
+		w.add(o+".created_at >= %s", criteria.MinCreatedAt)
+	}
+	if !criteria.MaxCreatedAt.IsZero() {
+		w.add(o+".created_at <= %s", criteria.MaxCreatedAt)
+	}
+	if criteria.Status != "" {
+		w.add(o+".status = %s", criteria.Status)
+	}
+
+	items := w.nested()
+	if criteria.ItemCriteria.MinQuantity > 0 {
+		items.add("oi.quantity >= %s", criteria.ItemCriteria.MinQuantity)
+	}
+	if criteria.ItemCriteria.MaxQuantity > 0 {
+		items.add("oi.quantity <= %s", criteria.ItemCriteria.MaxQuantity)
+	}
+	bookConditions(items, "ib", criteria.ItemCriteria.BookCriteria)
+	w.addExists("order_items oi LEFT JOIN books ib ON ib.id = oi.book_id", "oi.order_id = "+o+".id", items)
+}
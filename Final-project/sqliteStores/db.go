@@ -0,0 +1,55 @@
+// Package sqliteStores keeps the API's data in a single SQLite file, through the
+// pure-Go modernc.org/sqlite driver, so that it runs without a database server.
+// The stores answer every Interfaces store method with the same results as the
+// PostgreSQL stores; where SQLite lacks a PostgreSQL feature, such as pg_trgm or
+// text arrays, the package registers SQL functions or stores JSON instead.
+package sqliteStores
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// memoryPath opens a database that lives only as long as the process.
+const memoryPath = ":memory:"
+
+// Open opens the SQLite database at path, creating the file if needed, and checks
+// that it can be read. Foreign keys are enforced, writers wait for each other for
+// up to five seconds, and every transaction takes the write lock when it begins,
+// which is what the stores rely on instead of SELECT ... FOR UPDATE.
+func Open(ctx context.Context, path string) (*sql.DB, error) {
+	if path == "" {
+		return nil, fmt.Errorf("no SQLite database path given")
+	}
+	if strings.ContainsAny(path, "?#") {
+		return nil, fmt.Errorf("SQLite database path %q cannot contain ? or #", path)
+	}
+
+	params := []string{
+		"_pragma=foreign_keys(1)",
+		"_pragma=busy_timeout(5000)",
+		"_pragma=journal_mode(WAL)",
+		"_txlock=immediate",
+		// Times are written as text that parses back into time.Time.
+		"_time_format=sqlite",
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?"+strings.Join(params, "&"))
+	if err != nil {
+		return nil, fmt.Errorf("opening SQLite database %s: %w", path, err)
+	}
+	if path == memoryPath {
+		// Every connection to :memory: gets a database of its own.
+		db.SetMaxOpenConns(1)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening SQLite database %s: %w", path, err)
+	}
+	log.Printf("Opened SQLite database %s", path)
+	return db, nil
+}
@@ -0,0 +1,53 @@
+package sqliteStores
+
+import (
+	"database/sql/driver"
+	"fmt"
+
+	"finalProject/utils"
+
+	"modernc.org/sqlite"
+)
+
+// SQL functions standing in for the PostgreSQL features the searches use. They
+// call the same code the in-memory stores match with.
+func init() {
+	// contains_fold(text, term) is text ILIKE '%term%', without LIKE wildcards.
+	sqlite.MustRegisterDeterministicScalarFunction("contains_fold", 2,
+		func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			text, term, ok := stringArgs(args)
+			if !ok {
+				return nil, nil
+			}
+			return utils.ContainsIgnoreCase(text, term), nil
+		})
+	// word_similarity(term, text) is pg_trgm's function of the same name, as
+	// approximated by utils.WordSimilarity.
+	sqlite.MustRegisterDeterministicScalarFunction("word_similarity", 2,
+		func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			term, text, ok := stringArgs(args)
+			if !ok {
+				return nil, nil
+			}
+			return utils.WordSimilarity(term, text), nil
+		})
+}
+
+// stringArgs returns the two text arguments of a function, or false if either is
+// NULL, in which case the function returns NULL as well.
+func stringArgs(args []driver.Value) (string, string, bool) {
+	if args[0] == nil || args[1] == nil {
+		return "", "", false
+	}
+	return asText(args[0]), asText(args[1]), true
+}
+
+func asText(v driver.Value) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	}
+	return fmt.Sprint(v)
+}
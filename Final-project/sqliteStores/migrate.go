@@ -0,0 +1,34 @@
+package sqliteStores
+
+import (
+	"database/sql"
+	"embed"
+	"io/fs"
+
+	"finalProject/migrate"
+)
+
+// migrationFiles holds the schema's migrations, compiled into the binary.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations returns the schema's migrations, ordered by version.
+func Migrations() ([]migrate.Migration, error) {
+	files, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.Load(files)
+}
+
+// NewMigrator returns a Migrator for db, opened with Open. It needs no lock of
+// its own, as the transaction of each migration holds SQLite's write lock, and
+// SQLite takes the default ? placeholders.
+func NewMigrator(db *sql.DB) (*migrate.Migrator, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(db, migrations, migrate.Dialect{}), nil
+}
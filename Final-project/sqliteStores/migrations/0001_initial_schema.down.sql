@@ -0,0 +1,17 @@
+DROP TRIGGER IF EXISTS authors_search_update;
+DROP TRIGGER IF EXISTS books_search_delete;
+DROP TRIGGER IF EXISTS books_search_update;
+DROP TRIGGER IF EXISTS books_search_insert;
+DROP TABLE IF EXISTS books_search;
+
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS top_selling_books;
+DROP TABLE IF EXISTS sales_reports;
+DROP TABLE IF EXISTS reviews;
+DROP TABLE IF EXISTS order_status_history;
+DROP TABLE IF EXISTS order_items;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS customers;
+DROP TABLE IF EXISTS books;
+DROP TABLE IF EXISTS authors;
@@ -0,0 +1,180 @@
+-- The whole schema of the PostgreSQL migrations, in SQLite terms: genres are a
+-- JSON array, timestamps are UTC text that sorts in time order, and the
+-- full-text search vector of books is the books_search FTS5 table.
+
+CREATE TABLE authors (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    first_name TEXT NOT NULL,
+    last_name  TEXT NOT NULL,
+    bio        TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_authors_first_name ON authors (first_name);
+CREATE INDEX idx_authors_last_name ON authors (last_name);
+
+CREATE TABLE books (
+    id           INTEGER   PRIMARY KEY AUTOINCREMENT,
+    title        TEXT      NOT NULL,
+    author_id    INTEGER   NOT NULL REFERENCES authors (id) ON DELETE RESTRICT,
+    genres       TEXT      NOT NULL DEFAULT '[]' CHECK (json_valid(genres)),
+    published_at TIMESTAMP NOT NULL,
+    price        NUMERIC   NOT NULL,
+    stock        INTEGER   NOT NULL CHECK (stock >= 0)
+);
+
+CREATE INDEX idx_books_author_id ON books (author_id);
+CREATE INDEX idx_books_title ON books (title);
+CREATE INDEX idx_books_published_at ON books (published_at);
+CREATE INDEX idx_books_price ON books (price);
+
+CREATE TABLE customers (
+    id          INTEGER   PRIMARY KEY AUTOINCREMENT,
+    name        TEXT      NOT NULL,
+    email       TEXT      NOT NULL UNIQUE,
+    username    TEXT      NOT NULL DEFAULT '',
+    password    TEXT      NOT NULL DEFAULT '',
+    street      TEXT      NOT NULL DEFAULT '',
+    city        TEXT      NOT NULL DEFAULT '',
+    state       TEXT      NOT NULL DEFAULT '',
+    postal_code TEXT      NOT NULL DEFAULT '',
+    country     TEXT      NOT NULL DEFAULT '',
+    role        TEXT      NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin')),
+    created_at  TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_customers_name ON customers (name);
+CREATE INDEX idx_customers_created_at ON customers (created_at);
+CREATE INDEX idx_customers_address ON customers (country, state, city, postal_code);
+
+CREATE TABLE orders (
+    id          INTEGER   PRIMARY KEY AUTOINCREMENT,
+    customer_id INTEGER   NOT NULL REFERENCES customers (id) ON DELETE RESTRICT,
+    total_price NUMERIC   NOT NULL,
+    created_at  TIMESTAMP NOT NULL,
+    status      TEXT      NOT NULL CHECK (status IN
+        ('pending', 'paid', 'success', 'shipped', 'delivered', 'cancelled', 'refunded'))
+);
+
+CREATE INDEX idx_orders_customer_id ON orders (customer_id);
+CREATE INDEX idx_orders_status ON orders (status);
+CREATE INDEX idx_orders_created_at ON orders (created_at);
+
+CREATE TABLE order_items (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id    INTEGER NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    book_id     INTEGER NOT NULL REFERENCES books (id) ON DELETE RESTRICT,
+    quantity    INTEGER NOT NULL,
+    unit_price  NUMERIC NOT NULL,
+    book_title  TEXT    NOT NULL,
+    author_name TEXT    NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_order_items_order_id ON order_items (order_id);
+CREATE INDEX idx_order_items_book_id ON order_items (book_id);
+
+-- One row per status transition; changed_by is the customer who made it.
+CREATE TABLE order_status_history (
+    id          INTEGER   PRIMARY KEY AUTOINCREMENT,
+    order_id    INTEGER   NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    from_status TEXT      NOT NULL,
+    to_status   TEXT      NOT NULL,
+    changed_at  TIMESTAMP NOT NULL,
+    changed_by  INTEGER   REFERENCES customers (id) ON DELETE SET NULL,
+    note        TEXT      NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_order_status_history_order_id ON order_status_history (order_id);
+
+CREATE TABLE reviews (
+    id          INTEGER   PRIMARY KEY AUTOINCREMENT,
+    book_id     INTEGER   NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    customer_id INTEGER   REFERENCES customers (id) ON DELETE CASCADE,
+    rating      INTEGER   NOT NULL CHECK (rating >= 1 AND rating <= 5),
+    review_text TEXT      NOT NULL,
+    created_at  TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_reviews_book_id ON reviews (book_id);
+
+CREATE TABLE sales_reports (
+    id                INTEGER   PRIMARY KEY AUTOINCREMENT,
+    timestamp         TIMESTAMP NOT NULL,
+    total_revenue     NUMERIC   NOT NULL,
+    total_orders      INTEGER   NOT NULL,
+    successful_orders INTEGER   NOT NULL,
+    pending_orders    INTEGER   NOT NULL,
+    status_counts     TEXT      NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX idx_sales_reports_timestamp ON sales_reports (timestamp);
+
+CREATE TABLE top_selling_books (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    sales_report_id INTEGER NOT NULL REFERENCES sales_reports (id) ON DELETE CASCADE,
+    book_id         INTEGER NOT NULL REFERENCES books (id),
+    quantity_sold   INTEGER NOT NULL,
+    total_revenue   NUMERIC NOT NULL DEFAULT 0,
+    book_title      TEXT    NOT NULL,
+    book_price      NUMERIC NOT NULL
+);
+
+CREATE INDEX idx_top_selling_books_sales_report_id ON top_selling_books (sales_report_id);
+
+-- Opaque refresh tokens are stored by SHA-256 hash only and rotated on every use.
+CREATE TABLE refresh_tokens (
+    id          INTEGER   PRIMARY KEY AUTOINCREMENT,
+    customer_id INTEGER   NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+    token_hash  TEXT      NOT NULL UNIQUE,
+    access_jti  TEXT,
+    expires_at  TIMESTAMP NOT NULL,
+    created_at  TIMESTAMP NOT NULL,
+    revoked_at  TIMESTAMP,
+    replaced_by INTEGER   REFERENCES refresh_tokens (id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_refresh_tokens_customer_id ON refresh_tokens (customer_id);
+
+-- Access token IDs (jti) rejected by the auth middleware until they would have expired.
+CREATE TABLE revoked_tokens (
+    jti        TEXT      PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP NOT NULL
+);
+
+-- Full-text search over books, keyed by book ID: the title, the author's name,
+-- the genres and the author's bio, stemmed as English like the Postgres search
+-- vector. The triggers keep it in step with books and authors.
+CREATE VIRTUAL TABLE books_search USING fts5 (
+    title, author_name, genres, bio,
+    tokenize = 'porter unicode61'
+);
+
+CREATE TRIGGER books_search_insert AFTER INSERT ON books BEGIN
+    INSERT INTO books_search (rowid, title, author_name, genres, bio) VALUES (
+        NEW.id,
+        NEW.title,
+        COALESCE((SELECT first_name || ' ' || last_name FROM authors WHERE id = NEW.author_id), ''),
+        COALESCE((SELECT group_concat(value, ' ') FROM json_each(NEW.genres)), ''),
+        COALESCE((SELECT bio FROM authors WHERE id = NEW.author_id), ''));
+END;
+
+CREATE TRIGGER books_search_update AFTER UPDATE OF title, author_id, genres ON books BEGIN
+    DELETE FROM books_search WHERE rowid = OLD.id;
+    INSERT INTO books_search (rowid, title, author_name, genres, bio) VALUES (
+        NEW.id,
+        NEW.title,
+        COALESCE((SELECT first_name || ' ' || last_name FROM authors WHERE id = NEW.author_id), ''),
+        COALESCE((SELECT group_concat(value, ' ') FROM json_each(NEW.genres)), ''),
+        COALESCE((SELECT bio FROM authors WHERE id = NEW.author_id), ''));
+END;
+
+CREATE TRIGGER books_search_delete AFTER DELETE ON books BEGIN
+    DELETE FROM books_search WHERE rowid = OLD.id;
+END;
+
+-- Re-index an author's books when the name or bio changes.
+CREATE TRIGGER authors_search_update AFTER UPDATE OF first_name, last_name, bio ON authors BEGIN
+    UPDATE books_search
+    SET author_name = NEW.first_name || ' ' || NEW.last_name, bio = NEW.bio
+    WHERE rowid IN (SELECT id FROM books WHERE author_id = NEW.id);
+END;
@@ -0,0 +1,192 @@
+package sqliteStores
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"finalProject/Interfaces"
+	"finalProject/StructureData"
+	"finalProject/utils"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type SQLiteAuthorStore struct {
+	db *sql.DB
+}
+
+var _ Interfaces.AuthorStore = (*SQLiteAuthorStore)(nil)
+
+// NewSQLiteAuthorStore returns an author store backed by db, opened with Open.
+func NewSQLiteAuthorStore(db *sql.DB) *SQLiteAuthorStore {
+	return &SQLiteAuthorStore{db: db}
+}
+
+// CreateAuthor inserts a new author into the database.
+func (store *SQLiteAuthorStore) CreateAuthor(ctx context.Context, author StructureData.Author) (StructureData.Author, *StructureData.ErrorResponse) {
+	var id interface{}
+	if author.ID != 0 {
+		id = author.ID
+	}
+	query := `INSERT INTO authors (id, first_name, last_name, bio) VALUES (?, ?, ?, ?) RETURNING id`
+	err := store.db.QueryRowContext(ctx, query, id, author.FirstName, author.LastName, author.Bio).Scan(&author.ID)
+	if err != nil {
+		return StructureData.Author{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to insert author: %v", err)}
+	}
+	return author, nil
+}
+
+// GetAuthor retrieves an author by its ID.
+func (store *SQLiteAuthorStore) GetAuthor(ctx context.Context, id int) (StructureData.Author, *StructureData.ErrorResponse) {
+	var author StructureData.Author
+	query := `SELECT id, first_name, last_name, bio FROM authors WHERE id = ?`
+	err := store.db.QueryRowContext(ctx, query, id).Scan(&author.ID, &author.FirstName, &author.LastName, &author.Bio)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return StructureData.Author{}, StructureData.NotFoundError("Author not found")
+		}
+		return StructureData.Author{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching author: %v", err)}
+	}
+	return author, nil
+}
+
+// GetAuthorByDetails retrieves the author with exactly the given name and bio.
+func (store *SQLiteAuthorStore) GetAuthorByDetails(ctx context.Context, firstName, lastName, bio string) (StructureData.Author, *StructureData.ErrorResponse) {
+	var author StructureData.Author
+	query := `SELECT id, first_name, last_name, bio FROM authors WHERE first_name = ? AND last_name = ? AND bio = ?`
+	err := store.db.QueryRowContext(ctx, query, firstName, lastName, bio).Scan(&author.ID, &author.FirstName, &author.LastName, &author.Bio)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return StructureData.Author{}, StructureData.NotFoundError("Author not found")
+		}
+		return StructureData.Author{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Database error: %v", err)}
+	}
+	return author, nil
+}
+
+// UpdateAuthor updates an existing author in the database.
+func (store *SQLiteAuthorStore) UpdateAuthor(ctx context.Context, id int, author StructureData.Author) (StructureData.Author, *StructureData.ErrorResponse) {
+	query := `UPDATE authors SET first_name = ?, last_name = ?, bio = ? WHERE id = ?`
+	res, err := store.db.ExecContext(ctx, query, author.FirstName, author.LastName, author.Bio, id)
+	if err != nil {
+		return StructureData.Author{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to update author: %v", err)}
+	}
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return StructureData.Author{}, StructureData.NotFoundError("Author not found")
+	}
+	author.ID = id
+	return author, nil
+}
+
+// DeleteAuthor removes an author and its books from the database in one
+// transaction, refusing with a conflict if an order item refers to any of the
+// books. The foreign key from order items to books catches an order placed after
+// the check.
+func (store *SQLiteAuthorStore) DeleteAuthor(ctx context.Context, id int) *StructureData.ErrorResponse {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to begin transaction: %v", err)}
+	}
+	defer tx.Rollback()
+
+	var ordered bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM order_items oi JOIN books b ON b.id = oi.book_id WHERE b.author_id = ?)`,
+		id).Scan(&ordered)
+	if err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to check the author's books: %v", err)}
+	}
+	if ordered {
+		return StructureData.ConflictError("Author has books linked to orders")
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM books WHERE author_id = ?`, id)
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY {
+		return StructureData.ConflictError("Author has books linked to orders")
+	}
+	if err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to delete the author's books: %v", err)}
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM authors WHERE id = ?`, id)
+	if err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to delete author: %v", err)}
+	}
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return StructureData.NotFoundError("Author not found")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to commit transaction: %v", err)}
+	}
+	return nil
+}
+
+// GetAllAuthors retrieves all authors from the database.
+func (store *SQLiteAuthorStore) GetAllAuthors(ctx context.Context) []StructureData.Author {
+	authors, _ := store.queryAuthors(ctx, `SELECT id, first_name, last_name, bio FROM authors ORDER BY id`)
+	if authors == nil {
+		return []StructureData.Author{}
+	}
+	return authors
+}
+
+// authorSortColumns maps StructureData.AuthorSortFields to columns of authors.
+var authorSortColumns = map[string]string{
+	"id":         "id",
+	"first_name": "first_name",
+	"last_name":  "last_name",
+}
+
+// ListAuthors returns the page of authors selected by opts.
+func (store *SQLiteAuthorStore) ListAuthors(ctx context.Context, opts StructureData.ListOptions) ([]StructureData.Author, StructureData.ListMeta, *StructureData.ErrorResponse) {
+	var total int
+	if err := store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM authors`).Scan(&total); err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to count authors: %v", err)}
+	}
+
+	query, args := pageQuery(`SELECT id, first_name, last_name, bio FROM authors`, opts, authorSortColumns)
+	authors, err := store.queryAuthors(ctx, query, args...)
+	if err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to list authors: %v", err)}
+	}
+	authors, meta := utils.FinishPage(authors, opts, total)
+	return authors, meta, nil
+}
+
+// SearchAuthors returns the authors matching criteria, filtered in SQL. Results are
+// ordered by similarity for fuzzy criteria, and by ID otherwise.
+func (store *SQLiteAuthorStore) SearchAuthors(ctx context.Context, criteria StructureData.AuthorSearchCriteria) ([]StructureData.Author, *StructureData.ErrorResponse) {
+	where := newWhereClause()
+	score := authorConditions(where, "a", criteria)
+	query := `SELECT a.id, a.first_name, a.last_name, a.bio FROM authors a` + where.String() + orderByScore(score, "a.id")
+	authors, err := store.queryAuthors(ctx, query, where.Args()...)
+	if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to search authors: %v", err)}
+	}
+	return authors, nil
+}
+
+// queryAuthors runs a query selecting id, first_name, last_name and bio.
+func (store *SQLiteAuthorStore) queryAuthors(ctx context.Context, query string, args ...interface{}) ([]StructureData.Author, error) {
+	rows, err := store.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	authors := []StructureData.Author{}
+	for rows.Next() {
+		var author StructureData.Author
+		if err := rows.Scan(&author.ID, &author.FirstName, &author.LastName, &author.Bio); err != nil {
+			return nil, err
+		}
+		authors = append(authors, author)
+	}
+	return authors, rows.Err()
+}
@@ -0,0 +1,317 @@
+package sqliteStores
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"finalProject/Interfaces"
+	"finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// SQLiteBookStore implements the BookStore interface using SQLite.
+type SQLiteBookStore struct {
+	db *sql.DB
+}
+
+var _ Interfaces.BookStore = (*SQLiteBookStore)(nil)
+
+// NewSQLiteBookStore returns a book store backed by db, opened with Open.
+func NewSQLiteBookStore(db *sql.DB) *SQLiteBookStore {
+	return &SQLiteBookStore{db: db}
+}
+
+// bookColumns are the columns of books b, their authors a and their review
+// statistics, in the order scanBook reads them.
+const bookColumns = `b.id, b.title, b.genres, b.published_at, b.price, b.stock,
+	a.id, a.first_name, a.last_name, a.bio,
+	(SELECT COALESCE(AVG(r.rating), 0) FROM reviews r WHERE r.book_id = b.id),
+	(SELECT COUNT(*) FROM reviews r WHERE r.book_id = b.id)`
+
+// selectBooks selects bookColumns. Queries add their own WHERE and ORDER BY.
+const selectBooks = `SELECT ` + bookColumns + ` FROM books b JOIN authors a ON a.id = b.author_id`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanBook reads a row selected by selectBooks, followed by the columns of extra.
+func scanBook(row rowScanner, extra ...interface{}) (StructureData.Book, error) {
+	var book StructureData.Book
+	var genres string
+	var stats StructureData.BookReviewAggregate
+	dest := []interface{}{&book.ID, &book.Title, &genres, &book.PublishedAt, &book.Price, &book.Stock,
+		&book.Author.ID, &book.Author.FirstName, &book.Author.LastName, &book.Author.Bio,
+		&stats.AverageRating, &stats.ReviewCount}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return StructureData.Book{}, err
+	}
+	if err := json.Unmarshal([]byte(genres), &book.Genres); err != nil {
+		return StructureData.Book{}, fmt.Errorf("genres of book %d: %w", book.ID, err)
+	}
+	book.ReviewStats = &stats
+	return book, nil
+}
+
+// queryBooks runs a query built on selectBooks.
+func (store *SQLiteBookStore) queryBooks(ctx context.Context, query string, args ...interface{}) ([]StructureData.Book, error) {
+	rows, err := store.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	books := []StructureData.Book{}
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			return nil, err
+		}
+		books = append(books, book)
+	}
+	return books, rows.Err()
+}
+
+// CreateBook inserts a new book into the database.
+func (store *SQLiteBookStore) CreateBook(ctx context.Context, book StructureData.Book) (StructureData.Book, *StructureData.ErrorResponse) {
+	var id interface{}
+	if book.ID != 0 {
+		id = book.ID
+	}
+	query := `INSERT INTO books (id, title, author_id, genres, published_at, price, stock)
+		VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id`
+	err := store.db.QueryRowContext(ctx, query, id, book.Title, book.Author.ID, jsonArray(book.Genres),
+		book.PublishedAt.UTC(), book.Price, book.Stock).Scan(&book.ID)
+	if err != nil {
+		return StructureData.Book{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to insert book: %v", err)}
+	}
+	return book, nil
+}
+
+// GetBook retrieves a book by its ID, with its author and review statistics.
+func (store *SQLiteBookStore) GetBook(ctx context.Context, id int) (StructureData.Book, *StructureData.ErrorResponse) {
+	book, err := scanBook(store.db.QueryRowContext(ctx, selectBooks+` WHERE b.id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return StructureData.Book{}, StructureData.NotFoundError("Book not found")
+		}
+		return StructureData.Book{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching book: %v", err)}
+	}
+	return book, nil
+}
+
+// UpdateBook updates an existing book in the database.
+func (store *SQLiteBookStore) UpdateBook(ctx context.Context, id int, book StructureData.Book) (StructureData.Book, *StructureData.ErrorResponse) {
+	query := `UPDATE books SET title = ?, author_id = ?, genres = ?, published_at = ?, price = ?, stock = ? WHERE id = ?`
+	res, err := store.db.ExecContext(ctx, query, book.Title, book.Author.ID, jsonArray(book.Genres),
+		book.PublishedAt.UTC(), book.Price, book.Stock, id)
+	if err != nil {
+		return StructureData.Book{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to update book: %v", err)}
+	}
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return StructureData.Book{}, StructureData.NotFoundError("Book not found")
+	}
+	book.ID = id
+	return book, nil
+}
+
+// DeleteBook removes a book from the database.
+func (store *SQLiteBookStore) DeleteBook(ctx context.Context, id int) *StructureData.ErrorResponse {
+	res, err := store.db.ExecContext(ctx, `DELETE FROM books WHERE id = ?`, id)
+	if err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to delete book: %v", err)}
+	}
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return StructureData.NotFoundError("Book not found")
+	}
+	return nil
+}
+
+// GetAllBooks retrieves all books, with their authors and review statistics.
+func (store *SQLiteBookStore) GetAllBooks(ctx context.Context) []StructureData.Book {
+	books, _ := store.queryBooks(ctx, selectBooks+` ORDER BY b.id`)
+	if books == nil {
+		return []StructureData.Book{}
+	}
+	return books
+}
+
+// bookSortColumns maps StructureData.BookSortFields to columns of books b.
+var bookSortColumns = map[string]string{
+	"id":           "b.id",
+	"title":        "b.title",
+	"price":        "b.price",
+	"stock":        "b.stock",
+	"published_at": "b.published_at",
+}
+
+// ListBooks returns the page of books selected by opts. Author details and review
+// statistics are only returned when those fields are wanted.
+func (store *SQLiteBookStore) ListBooks(ctx context.Context, opts StructureData.ListOptions) ([]StructureData.Book, StructureData.ListMeta, *StructureData.ErrorResponse) {
+	var total int
+	if err := store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM books`).Scan(&total); err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to count books: %v", err)}
+	}
+
+	query, args := pageQuery(selectBooks, opts, bookSortColumns)
+	books, err := store.queryBooks(ctx, query, args...)
+	if err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to list books: %v", err)}
+	}
+	withAuthor, withStats := opts.Wants("author"), opts.Wants("review_stats")
+	for i := range books {
+		if !withAuthor {
+			books[i].Author = StructureData.Author{ID: books[i].Author.ID}
+		}
+		if !withStats {
+			books[i].ReviewStats = nil
+		}
+	}
+	books, meta := utils.FinishPage(books, opts, total)
+	return books, meta, nil
+}
+
+// SearchBooks returns the books matching criteria, filtered in SQL.
+func (store *SQLiteBookStore) SearchBooks(ctx context.Context, criteria StructureData.BookSearchCriteria) ([]StructureData.Book, *StructureData.ErrorResponse) {
+	where := newWhereClause()
+	bookConditions(where, "b", criteria)
+	books, err := store.queryBooks(ctx, selectBooks+where.String()+` ORDER BY b.id`, where.Args()...)
+	if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to search books: %v", err)}
+	}
+	return books, nil
+}
+
+// bookFacetQueries holds, for each facet, a query counting the books selected by
+// a WHERE clause on books b, substituted for %s. Each row holds the key, a label
+// and the count.
+var bookFacetQueries = map[string]string{
+	StructureData.FacetGenre: `SELECT g.value, '', COUNT(*) FROM books b, json_each(b.genres) g%s
+		GROUP BY g.value ORDER BY COUNT(*) DESC, g.value`,
+	StructureData.FacetAuthor: `SELECT CAST(b.author_id AS TEXT), COALESCE(MAX(TRIM(a.first_name || ' ' || a.last_name)), ''), COUNT(*)
+		FROM books b LEFT JOIN authors a ON a.id = b.author_id%s
+		GROUP BY b.author_id ORDER BY COUNT(*) DESC, CAST(b.author_id AS TEXT)`,
+	// The band number, the count of limits at or below the price, is turned into
+	// its key by SearchBookFacets.
+	StructureData.FacetPrice: `SELECT (SELECT COUNT(*) FROM json_each(%[2]s) l WHERE l.value <= b.price), '', COUNT(*) FROM books b%[1]s
+		GROUP BY 1 ORDER BY 1`,
+	StructureData.FacetRating: `SELECT MIN(CAST(COALESCE(r.average, 0) AS INTEGER), 5), '', COUNT(*)
+		FROM books b LEFT JOIN (SELECT book_id, AVG(rating) AS average FROM reviews GROUP BY book_id) r ON r.book_id = b.id%s
+		GROUP BY 1 ORDER BY 1`,
+}
+
+// SearchBookFacets counts the books matching criteria by each facet named in
+// criteria.Facets, with one GROUP BY query per facet.
+func (store *SQLiteBookStore) SearchBookFacets(ctx context.Context, criteria StructureData.BookSearchCriteria) (StructureData.BookFacets, *StructureData.ErrorResponse) {
+	var facets StructureData.BookFacets
+	for _, facet := range criteria.Facets {
+		where := newWhereClause()
+		bookConditions(where, "b", criteria)
+		var query string
+		if facet == StructureData.FacetPrice {
+			query = fmt.Sprintf(bookFacetQueries[facet], where.String(), where.bind(jsonArray(StructureData.PriceBandLimits)))
+		} else {
+			query = fmt.Sprintf(bookFacetQueries[facet], where.String())
+		}
+		counts, err := store.facetCounts(ctx, query, where.Args())
+		if err != nil {
+			return StructureData.BookFacets{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to count %s facet: %v", facet, err)}
+		}
+
+		switch facet {
+		case StructureData.FacetGenre:
+			facets.Genres = counts
+		case StructureData.FacetAuthor:
+			facets.Authors = counts
+		case StructureData.FacetPrice:
+			for i := range counts {
+				band, _ := strconv.Atoi(counts[i].Key)
+				counts[i].Key = StructureData.PriceBand(band)
+			}
+			facets.PriceBands = counts
+		case StructureData.FacetRating:
+			facets.Ratings = counts
+		}
+	}
+	return facets, nil
+}
+
+func (store *SQLiteBookStore) facetCounts(ctx context.Context, query string, args []interface{}) ([]StructureData.FacetCount, error) {
+	rows, err := store.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []StructureData.FacetCount{}
+	for rows.Next() {
+		var count StructureData.FacetCount
+		if err := rows.Scan(&count.Key, &count.Label, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+// prefixMatchQuery turns search terms into an FTS5 MATCH expression that requires
+// every term as a word prefix. SearchTerms only yields letters and digits, so the
+// quoted terms need no escaping.
+func prefixMatchQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = `"` + term + `"*`
+	}
+	return strings.Join(parts, " ")
+}
+
+// TextSearchBooks ranks the books whose books_search row matches every word of
+// query.Query as a prefix, highlighting the matches in the title and author bio.
+// bm25 weighs the title, author, genres and bio columns as the Postgres search
+// vector weighs its labels.
+func (store *SQLiteBookStore) TextSearchBooks(ctx context.Context, query StructureData.BookTextQuery) ([]StructureData.BookSearchHit, StructureData.ListMeta, *StructureData.ErrorResponse) {
+	match := prefixMatchQuery(utils.SearchTerms(query.Query))
+	meta := StructureData.ListMeta{Limit: query.Limit, Offset: query.Offset}
+
+	err := store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM books_search WHERE books_search MATCH ?`, match).Scan(&meta.Total)
+	if err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to search books: %v", err)}
+	}
+
+	rows, err := store.db.QueryContext(ctx, `
+		SELECT `+bookColumns+`,
+		       -bm25(books_search, 1.0, 0.4, 0.4, 0.2) AS score,
+		       highlight(books_search, 0, ?2, ?3),
+		       snippet(books_search, 3, ?2, ?3, '', 30)
+		FROM books_search
+		JOIN books b ON b.id = books_search.rowid
+		JOIN authors a ON a.id = b.author_id
+		WHERE books_search MATCH ?1
+		ORDER BY score DESC, b.id
+		LIMIT ?4 OFFSET ?5`,
+		match, StructureData.HighlightStart, StructureData.HighlightStop, query.Limit, query.Offset)
+	if err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to search books: %v", err)}
+	}
+	defer rows.Close()
+
+	hits := []StructureData.BookSearchHit{}
+	for rows.Next() {
+		var hit StructureData.BookSearchHit
+		hit.Book, err = scanBook(rows, &hit.Score, &hit.Highlights.Title, &hit.Highlights.Snippet)
+		if err != nil {
+			return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error scanning book: %v", err)}
+		}
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to search books: %v", err)}
+	}
+	return hits, meta, nil
+}
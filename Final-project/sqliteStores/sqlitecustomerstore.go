@@ -0,0 +1,174 @@
+package sqliteStores
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"finalProject/Interfaces"
+	"finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// SQLiteCustomerStore implements the customer store using SQLite.
+type SQLiteCustomerStore struct {
+	db *sql.DB
+}
+
+var _ Interfaces.CustomerStore = (*SQLiteCustomerStore)(nil)
+
+// NewSQLiteCustomerStore returns a customer store backed by db, opened with Open.
+func NewSQLiteCustomerStore(db *sql.DB) *SQLiteCustomerStore {
+	return &SQLiteCustomerStore{db: db}
+}
+
+// selectCustomers selects the columns read by scanCustomers from customers c.
+const selectCustomers = `SELECT c.id, c.name, c.username, c.email, c.street, c.city, c.state, c.postal_code, c.country, c.role, c.created_at FROM customers c`
+
+// CreateCustomer inserts a new customer. If customer.ID is nonzero, it will be
+// inserted explicitly.
+func (store *SQLiteCustomerStore) CreateCustomer(ctx context.Context, customer StructureData.Customer) (StructureData.Customer, *StructureData.ErrorResponse) {
+	if customer.Role == "" {
+		customer.Role = StructureData.RoleUser
+	}
+	var id interface{}
+	if customer.ID != 0 {
+		id = customer.ID
+	}
+	query := `INSERT INTO customers (id, name, username, email, password, street, city, state, postal_code, country, role, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`
+	address := customer.Address
+	err := store.db.QueryRowContext(ctx, query, id, customer.Name, customer.Username, customer.Email, customer.Password,
+		address.Street, address.City, address.State, address.PostalCode, address.Country,
+		customer.Role, customer.CreatedAt.UTC()).Scan(&customer.ID)
+	if err != nil {
+		return StructureData.Customer{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to insert customer: %v", err)}
+	}
+	return customer, nil
+}
+
+// GetCustomer retrieves a customer by its ID.
+func (store *SQLiteCustomerStore) GetCustomer(ctx context.Context, id int) (StructureData.Customer, *StructureData.ErrorResponse) {
+	customers, err := store.queryCustomers(ctx, selectCustomers+` WHERE c.id = ?`, id)
+	if err != nil {
+		return StructureData.Customer{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching customer: %v", err)}
+	}
+	if len(customers) == 0 {
+		return StructureData.Customer{}, StructureData.NotFoundError("Customer not found")
+	}
+	return customers[0], nil
+}
+
+// GetCustomerByEmail retrieves the customer with the given email, including the
+// password hash, for checking their credentials.
+func (store *SQLiteCustomerStore) GetCustomerByEmail(ctx context.Context, email string) (StructureData.Customer, *StructureData.ErrorResponse) {
+	var customer StructureData.Customer
+	query := `SELECT id, name, username, email, password, role, created_at FROM customers WHERE email = ?`
+	err := store.db.QueryRowContext(ctx, query, email).Scan(
+		&customer.ID, &customer.Name, &customer.Username, &customer.Email, &customer.Password, &customer.Role, &customer.CreatedAt)
+	if err == sql.ErrNoRows {
+		return StructureData.Customer{}, StructureData.NotFoundError("Customer not found")
+	} else if err != nil {
+		return StructureData.Customer{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching customer: %v", err)}
+	}
+	return customer, nil
+}
+
+// GetAllCustomers retrieves all customers from the database.
+func (store *SQLiteCustomerStore) GetAllCustomers(ctx context.Context) []StructureData.Customer {
+	customers, _ := store.queryCustomers(ctx, selectCustomers+` ORDER BY c.id`)
+	if customers == nil {
+		return []StructureData.Customer{}
+	}
+	return customers
+}
+
+// customerSortColumns maps StructureData.CustomerSortFields to columns of customers c.
+var customerSortColumns = map[string]string{
+	"id":         "c.id",
+	"name":       "c.name",
+	"email":      "c.email",
+	"created_at": "c.created_at",
+}
+
+// ListCustomers returns the page of customers selected by opts.
+func (store *SQLiteCustomerStore) ListCustomers(ctx context.Context, opts StructureData.ListOptions) ([]StructureData.Customer, StructureData.ListMeta, *StructureData.ErrorResponse) {
+	var total int
+	if err := store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM customers`).Scan(&total); err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to count customers: %v", err)}
+	}
+
+	query, args := pageQuery(selectCustomers, opts, customerSortColumns)
+	customers, err := store.queryCustomers(ctx, query, args...)
+	if err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to list customers: %v", err)}
+	}
+	customers, meta := utils.FinishPage(customers, opts, total)
+	return customers, meta, nil
+}
+
+// UpdateCustomer updates an existing customer in the database.
+// The role is never changed here; the stored role is returned on the updated customer.
+func (store *SQLiteCustomerStore) UpdateCustomer(ctx context.Context, id int, customer StructureData.Customer) (StructureData.Customer, *StructureData.ErrorResponse) {
+	query := `UPDATE customers SET name = ?, username = ?, email = ?, street = ?, city = ?, state = ?, postal_code = ?, country = ?
+		WHERE id = ? RETURNING role`
+	address := customer.Address
+	err := store.db.QueryRowContext(ctx, query, customer.Name, customer.Username, customer.Email,
+		address.Street, address.City, address.State, address.PostalCode, address.Country, id).Scan(&customer.Role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return StructureData.Customer{}, StructureData.NotFoundError("Customer not found")
+		}
+		return StructureData.Customer{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to update customer: %v", err)}
+	}
+	customer.ID = id
+	return customer, nil
+}
+
+// DeleteCustomer removes a customer from the database.
+func (store *SQLiteCustomerStore) DeleteCustomer(ctx context.Context, id int) *StructureData.ErrorResponse {
+	res, err := store.db.ExecContext(ctx, `DELETE FROM customers WHERE id = ?`, id)
+	if err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to delete customer: %v", err)}
+	}
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return StructureData.NotFoundError("Customer not found")
+	}
+	return nil
+}
+
+// SearchCustomers returns the customers matching criteria, filtered in SQL. Results
+// are ordered by name similarity for fuzzy criteria, and by ID otherwise.
+func (store *SQLiteCustomerStore) SearchCustomers(ctx context.Context, criteria StructureData.CustomerSearchCriteria) ([]StructureData.Customer, *StructureData.ErrorResponse) {
+	where := newWhereClause()
+	score := customerConditions(where, "c", criteria)
+	customers, err := store.queryCustomers(ctx, selectCustomers+where.String()+orderByScore(score, "c.id"), where.Args()...)
+	if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to search customers: %v", err)}
+	}
+	return customers, nil
+}
+
+// queryCustomers runs a query built on selectCustomers.
+func (store *SQLiteCustomerStore) queryCustomers(ctx context.Context, query string, args ...interface{}) ([]StructureData.Customer, error) {
+	rows, err := store.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	customers := []StructureData.Customer{}
+	for rows.Next() {
+		var customer StructureData.Customer
+		address := &customer.Address
+		err := rows.Scan(&customer.ID, &customer.Name, &customer.Username, &customer.Email,
+			&address.Street, &address.City, &address.State, &address.PostalCode, &address.Country,
+			&customer.Role, &customer.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		customers = append(customers, customer)
+	}
+	return customers, rows.Err()
+}
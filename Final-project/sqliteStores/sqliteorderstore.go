@@ -0,0 +1,616 @@
+package sqliteStores
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"finalProject/Interfaces"
+	"finalProject/StructureData"
+	"finalProject/utils"
+)
+
+// SQLiteOrderStore implements the OrderStore and OrderProcessor interfaces using
+// SQLite. Every transaction holds the database's write lock from its start (see
+// Open), so orders and stock are never changed by two transactions at once and
+// no row locks are needed.
+type SQLiteOrderStore struct {
+	db *sql.DB
+}
+
+var (
+	_ Interfaces.OrderStore     = (*SQLiteOrderStore)(nil)
+	_ Interfaces.OrderProcessor = (*SQLiteOrderStore)(nil)
+)
+
+// NewSQLiteOrderStore returns an order store backed by db, opened with Open.
+func NewSQLiteOrderStore(db *sql.DB) *SQLiteOrderStore {
+	return &SQLiteOrderStore{db: db}
+}
+
+// selectOrders selects the order headers read by queryOrders from orders o.
+const selectOrders = `SELECT o.id, o.customer_id, o.total_price, o.created_at, o.status FROM orders o`
+
+// CreateOrder inserts a new order and its items into the database.
+func (store *SQLiteOrderStore) CreateOrder(ctx context.Context, order StructureData.Order) (StructureData.Order, *StructureData.ErrorResponse) {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return StructureData.Order{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to begin transaction: %v", err)}
+	}
+	defer tx.Rollback()
+
+	order, errResp := insertOrder(ctx, tx, order)
+	if errResp != nil {
+		return StructureData.Order{}, errResp
+	}
+
+	if err = tx.Commit(); err != nil {
+		return StructureData.Order{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to commit transaction: %v", err)}
+	}
+	log.Printf("Order ID %d committed successfully", order.ID)
+	return order, nil
+}
+
+// insertOrder inserts the order header and its items within tx.
+func insertOrder(ctx context.Context, tx *sql.Tx, order StructureData.Order) (StructureData.Order, *StructureData.ErrorResponse) {
+	var id interface{}
+	if order.ID != 0 {
+		id = order.ID
+	}
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO orders (id, customer_id, total_price, created_at, status)
+		VALUES (?, ?, ?, ?, ?) RETURNING id`,
+		id, order.Customer.ID, order.TotalPrice, order.CreatedAt.UTC(), order.Status).Scan(&order.ID)
+	if err != nil {
+		return StructureData.Order{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to insert order: %v", err)}
+	}
+	if errResp := insertOrderItems(ctx, tx, order.ID, order.Items); errResp != nil {
+		return StructureData.Order{}, errResp
+	}
+	return order, nil
+}
+
+// insertOrderItems inserts the line items of order orderID within tx.
+func insertOrderItems(ctx context.Context, tx *sql.Tx, orderID int, items []StructureData.OrderItem) *StructureData.ErrorResponse {
+	for _, item := range items {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO order_items (order_id, book_id, quantity, unit_price, book_title, author_name)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			orderID, item.Book.ID, item.Quantity, item.UnitPrice, item.BookTitle, item.AuthorName)
+		if err != nil {
+			return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to insert order item: %v", err)}
+		}
+	}
+	return nil
+}
+
+// GetOrder retrieves an order (including its items) by ID.
+func (store *SQLiteOrderStore) GetOrder(ctx context.Context, id int) (StructureData.Order, *StructureData.ErrorResponse) {
+	orders, err := store.queryOrders(ctx, selectOrders+` WHERE o.id = ?`, id)
+	if err != nil {
+		return StructureData.Order{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching order: %v", err)}
+	}
+	if len(orders) == 0 {
+		return StructureData.Order{}, StructureData.NotFoundError("Order not found")
+	}
+	order := orders[0]
+
+	rows, err := store.db.QueryContext(ctx, `
+		SELECT book_id, quantity, unit_price, book_title, author_name
+		FROM order_items WHERE order_id = ? ORDER BY id`, id)
+	if err != nil {
+		return StructureData.Order{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching order items: %v", err)}
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var item StructureData.OrderItem
+		if err := rows.Scan(&item.Book.ID, &item.Quantity, &item.UnitPrice, &item.BookTitle, &item.AuthorName); err != nil {
+			return StructureData.Order{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error scanning order item: %v", err)}
+		}
+		order.Items = append(order.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return StructureData.Order{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching order items: %v", err)}
+	}
+	return order, nil
+}
+
+// UpdateOrder updates an existing order and its items.
+func (store *SQLiteOrderStore) UpdateOrder(ctx context.Context, id int, order StructureData.Order) (StructureData.Order, *StructureData.ErrorResponse) {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return StructureData.Order{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to begin transaction: %v", err)}
+	}
+	defer tx.Rollback()
+
+	if errResp := updateOrderRows(ctx, tx, id, order); errResp != nil {
+		return StructureData.Order{}, errResp
+	}
+
+	if err = tx.Commit(); err != nil {
+		return StructureData.Order{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to commit transaction: %v", err)}
+	}
+	order.ID = id
+	log.Printf("Order ID %d updated successfully", id)
+	return order, nil
+}
+
+// updateOrderRows overwrites the header and line items of order id within tx.
+func updateOrderRows(ctx context.Context, tx *sql.Tx, id int, order StructureData.Order) *StructureData.ErrorResponse {
+	_, err := tx.ExecContext(ctx, `UPDATE orders SET customer_id = ?, total_price = ?, created_at = ?, status = ? WHERE id = ?`,
+		order.Customer.ID, order.TotalPrice, order.CreatedAt.UTC(), order.Status, id)
+	if err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to update order: %v", err)}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM order_items WHERE order_id = ?`, id); err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to delete old order items: %v", err)}
+	}
+	return insertOrderItems(ctx, tx, id, order.Items)
+}
+
+// DeleteOrder removes an order and, through ON DELETE CASCADE, its items.
+func (store *SQLiteOrderStore) DeleteOrder(ctx context.Context, id int) *StructureData.ErrorResponse {
+	res, err := store.db.ExecContext(ctx, `DELETE FROM orders WHERE id = ?`, id)
+	if err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to delete order: %v", err)}
+	}
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return StructureData.NotFoundError("Order not found")
+	}
+	log.Printf("Deleted order ID %d and its items", id)
+	return nil
+}
+
+// GetAllOrders retrieves all orders, with their items, from the database.
+func (store *SQLiteOrderStore) GetAllOrders(ctx context.Context) []StructureData.Order {
+	orders, err := store.queryOrders(ctx, selectOrders+` ORDER BY o.id`)
+	if err != nil {
+		log.Printf("Error querying orders: %v", err)
+		return []StructureData.Order{}
+	}
+	if errResp := store.attachOrderItems(ctx, orders); errResp != nil {
+		log.Printf("Error querying order items: %v", errResp.Message)
+	}
+	return orders
+}
+
+// orderSortColumns maps StructureData.OrderSortFields to columns of orders o.
+var orderSortColumns = map[string]string{
+	"id":          "o.id",
+	"created_at":  "o.created_at",
+	"total_price": "o.total_price",
+	"status":      "o.status",
+}
+
+// ListOrders returns the page of orders selected by opts, with their items.
+func (store *SQLiteOrderStore) ListOrders(ctx context.Context, opts StructureData.ListOptions) ([]StructureData.Order, StructureData.ListMeta, *StructureData.ErrorResponse) {
+	var total int
+	if err := store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM orders`).Scan(&total); err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to count orders: %v", err)}
+	}
+
+	query, args := pageQuery(selectOrders, opts, orderSortColumns)
+	orders, err := store.queryOrders(ctx, query, args...)
+	if err != nil {
+		return nil, StructureData.ListMeta{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to list orders: %v", err)}
+	}
+	orders, meta := utils.FinishPage(orders, opts, total)
+
+	if opts.Wants("items") {
+		if errResp := store.attachOrderItems(ctx, orders); errResp != nil {
+			return nil, StructureData.ListMeta{}, errResp
+		}
+	}
+	return orders, meta, nil
+}
+
+// SearchOrders returns the orders matching criteria, with their items, filtered in SQL.
+func (store *SQLiteOrderStore) SearchOrders(ctx context.Context, criteria StructureData.OrderSearchCriteria) ([]StructureData.Order, *StructureData.ErrorResponse) {
+	where := newWhereClause()
+	orderConditions(where, "o", criteria)
+	orders, err := store.queryOrders(ctx, selectOrders+where.String()+` ORDER BY o.id`, where.Args()...)
+	if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to search orders: %v", err)}
+	}
+	if errResp := store.attachOrderItems(ctx, orders); errResp != nil {
+		return nil, errResp
+	}
+	return orders, nil
+}
+
+// GetOrdersInTimeRange retrieves orders created within a specified time range,
+// with their items.
+func (store *SQLiteOrderStore) GetOrdersInTimeRange(ctx context.Context, start, end time.Time) ([]StructureData.Order, error) {
+	orders, err := store.queryOrders(ctx, selectOrders+` WHERE o.created_at >= ? AND o.created_at <= ? ORDER BY o.id`, start.UTC(), end.UTC())
+	if err != nil {
+		return []StructureData.Order{}, err
+	}
+	if errResp := store.attachOrderItems(ctx, orders); errResp != nil {
+		return orders, errResp
+	}
+	return orders, nil
+}
+
+// queryOrders runs a query built on selectOrders, leaving the items out.
+func (store *SQLiteOrderStore) queryOrders(ctx context.Context, query string, args ...interface{}) ([]StructureData.Order, error) {
+	rows, err := store.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []StructureData.Order{}
+	for rows.Next() {
+		var order StructureData.Order
+		if err := rows.Scan(&order.ID, &order.Customer.ID, &order.TotalPrice, &order.CreatedAt, &order.Status); err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+// attachOrderItems loads the items of orders, with the current details of their
+// books, in one query.
+func (store *SQLiteOrderStore) attachOrderItems(ctx context.Context, orders []StructureData.Order) *StructureData.ErrorResponse {
+	if len(orders) == 0 {
+		return nil
+	}
+	ids := make([]int, len(orders))
+	for i, order := range orders {
+		ids[i] = order.ID
+	}
+	rows, err := store.db.QueryContext(ctx, `
+		SELECT oi.order_id, oi.book_id, oi.quantity, oi.unit_price, oi.book_title, oi.author_name,
+		       COALESCE(b.title, oi.book_title), COALESCE(b.price, oi.unit_price), COALESCE(b.stock, 0)
+		FROM order_items oi
+		LEFT JOIN books b ON oi.book_id = b.id
+		WHERE oi.order_id`+fmt.Sprintf(inJSON, "?1")+`
+		ORDER BY oi.order_id, oi.id`, jsonArray(ids))
+	if err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching order items: %v", err)}
+	}
+	defer rows.Close()
+
+	items := map[int][]StructureData.OrderItem{}
+	for rows.Next() {
+		var orderID int
+		var item StructureData.OrderItem
+		err := rows.Scan(&orderID, &item.Book.ID, &item.Quantity, &item.UnitPrice, &item.BookTitle, &item.AuthorName,
+			&item.Book.Title, &item.Book.Price, &item.Book.Stock)
+		if err != nil {
+			return &StructureData.ErrorResponse{Message: fmt.Sprintf("Error scanning order item: %v", err)}
+		}
+		items[orderID] = append(items[orderID], item)
+	}
+	if err := rows.Err(); err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching order items: %v", err)}
+	}
+	for i := range orders {
+		orders[i].Items = items[orders[i].ID]
+	}
+	return nil
+}
+
+// PlaceOrder reserves stock for the order's items and inserts the order in a single
+// transaction. Item prices and the order total are taken from the book rows as
+// they are reserved.
+//
+// If any item's book is missing or short of stock, nothing is written and the
+// returned error lists every rejected item. With allowPartial, those items are
+// left out instead and reported in the returned order's RejectedItems; the order
+// is still refused if no item remains.
+func (store *SQLiteOrderStore) PlaceOrder(ctx context.Context, order StructureData.Order, allowPartial bool) (StructureData.Order, StructureData.StockLevels, *StructureData.ErrorResponse) {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return StructureData.Order{}, nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to begin transaction: %v", err)}
+	}
+	defer tx.Rollback()
+
+	levels := StructureData.StockLevels{}
+	items, rejected, errResp := reserveStock(ctx, tx, order.Items, levels)
+	if errResp != nil {
+		return StructureData.Order{}, nil, errResp
+	}
+	if errResp := StructureData.CheckRejections(items, rejected, allowPartial); errResp != nil {
+		return StructureData.Order{}, nil, errResp
+	}
+	order.Items = items
+	order.RejectedItems = rejected
+	order.TotalPrice = StructureData.ItemsTotal(items)
+
+	order, errResp = insertOrder(ctx, tx, order)
+	if errResp != nil {
+		return StructureData.Order{}, nil, errResp
+	}
+
+	if err := tx.Commit(); err != nil {
+		return StructureData.Order{}, nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to commit transaction: %v", err)}
+	}
+	log.Printf("Placed order ID %d with %d items", order.ID, len(order.Items))
+	return order, levels, nil
+}
+
+// ReplaceOrder returns the stock held by order id and reserves stock for the new
+// items in a single transaction, then overwrites the order. The same rules as
+// PlaceOrder apply to the new items. Only pending orders can be replaced.
+func (store *SQLiteOrderStore) ReplaceOrder(ctx context.Context, id int, order StructureData.Order, allowPartial bool) (StructureData.Order, StructureData.StockLevels, *StructureData.ErrorResponse) {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return StructureData.Order{}, nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to begin transaction: %v", err)}
+	}
+	defer tx.Rollback()
+
+	if errResp := checkModifiableOrder(ctx, tx, id); errResp != nil {
+		return StructureData.Order{}, nil, errResp
+	}
+
+	levels := StructureData.StockLevels{}
+	if errResp := restoreStock(ctx, tx, id, levels); errResp != nil {
+		return StructureData.Order{}, nil, errResp
+	}
+	items, rejected, errResp := reserveStock(ctx, tx, order.Items, levels)
+	if errResp != nil {
+		return StructureData.Order{}, nil, errResp
+	}
+	if errResp := StructureData.CheckRejections(items, rejected, allowPartial); errResp != nil {
+		return StructureData.Order{}, nil, errResp
+	}
+	order.Items = items
+	order.RejectedItems = rejected
+	order.TotalPrice = StructureData.ItemsTotal(items)
+
+	if errResp := updateOrderRows(ctx, tx, id, order); errResp != nil {
+		return StructureData.Order{}, nil, errResp
+	}
+
+	if err := tx.Commit(); err != nil {
+		return StructureData.Order{}, nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to commit transaction: %v", err)}
+	}
+	order.ID = id
+	log.Printf("Replaced order ID %d with %d items", id, len(order.Items))
+	return order, levels, nil
+}
+
+// RemoveOrder deletes order id and returns its items to stock in a single
+// transaction. Only pending orders can be removed.
+func (store *SQLiteOrderStore) RemoveOrder(ctx context.Context, id int) (StructureData.StockLevels, *StructureData.ErrorResponse) {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to begin transaction: %v", err)}
+	}
+	defer tx.Rollback()
+
+	if errResp := checkModifiableOrder(ctx, tx, id); errResp != nil {
+		return nil, errResp
+	}
+
+	levels := StructureData.StockLevels{}
+	if errResp := restoreStock(ctx, tx, id, levels); errResp != nil {
+		return nil, errResp
+	}
+	// order_items rows go with the order through ON DELETE CASCADE.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM orders WHERE id = ?`, id); err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to delete order: %v", err)}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to commit transaction: %v", err)}
+	}
+	log.Printf("Removed order ID %d and restocked its items", id)
+	return levels, nil
+}
+
+// checkModifiableOrder checks that order id is still pending, the only status in
+// which its items may change.
+func checkModifiableOrder(ctx context.Context, tx *sql.Tx, id int) *StructureData.ErrorResponse {
+	status, errResp := orderStatus(ctx, tx, id)
+	if errResp != nil {
+		return errResp
+	}
+	if status != StructureData.OrderStatusPending {
+		return StructureData.ErrOrderLocked
+	}
+	return nil
+}
+
+// orderStatus returns the status of order id.
+func orderStatus(ctx context.Context, tx *sql.Tx, id int) (string, *StructureData.ErrorResponse) {
+	var status string
+	err := tx.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = ?`, id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", StructureData.ErrOrderNotFound
+	} else if err != nil {
+		return "", &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching order: %v", err)}
+	}
+	return status, nil
+}
+
+// TransitionOrder moves order id to status to and records the change in the
+// order's status history, in a single transaction. Cancelling an order returns
+// its items to stock; the new stock levels are returned for those books. actorID
+// is the customer making the change, or 0 if it is not known.
+func (store *SQLiteOrderStore) TransitionOrder(ctx context.Context, id int, to string, actorID int, note string) (StructureData.OrderStatusChange, StructureData.StockLevels, *StructureData.ErrorResponse) {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return StructureData.OrderStatusChange{}, nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to begin transaction: %v", err)}
+	}
+	defer tx.Rollback()
+
+	from, errResp := orderStatus(ctx, tx, id)
+	if errResp != nil {
+		return StructureData.OrderStatusChange{}, nil, errResp
+	}
+	if !StructureData.CanTransitionOrder(from, to) {
+		return StructureData.OrderStatusChange{}, nil, StructureData.ErrInvalidTransition
+	}
+
+	levels := StructureData.StockLevels{}
+	if to == StructureData.OrderStatusCancelled {
+		if errResp := restoreStock(ctx, tx, id, levels); errResp != nil {
+			return StructureData.OrderStatusChange{}, nil, errResp
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE orders SET status = ? WHERE id = ?`, to, id); err != nil {
+		return StructureData.OrderStatusChange{}, nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to update order status: %v", err)}
+	}
+
+	change := StructureData.OrderStatusChange{OrderID: id, FromStatus: from, ToStatus: to, Note: note}
+	changedBy := sql.NullInt64{Int64: int64(actorID), Valid: actorID != 0}
+	if changedBy.Valid {
+		change.ChangedBy = &actorID
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO order_status_history (order_id, from_status, to_status, changed_at, changed_by, note)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id, changed_at`,
+		id, from, to, time.Now().UTC(), changedBy, note,
+	).Scan(&change.ID, &change.ChangedAt)
+	if err != nil {
+		return StructureData.OrderStatusChange{}, nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to record status change: %v", err)}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return StructureData.OrderStatusChange{}, nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to commit transaction: %v", err)}
+	}
+	log.Printf("Moved order ID %d from %s to %s", id, from, to)
+	return change, levels, nil
+}
+
+// GetOrderHistory returns the status changes of order id, oldest first.
+func (store *SQLiteOrderStore) GetOrderHistory(ctx context.Context, id int) ([]StructureData.OrderStatusChange, *StructureData.ErrorResponse) {
+	rows, err := store.db.QueryContext(ctx, `
+		SELECT id, order_id, from_status, to_status, changed_at, changed_by, note
+		FROM order_status_history
+		WHERE order_id = ?
+		ORDER BY changed_at, id`, id)
+	if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching order history: %v", err)}
+	}
+	defer rows.Close()
+
+	history := []StructureData.OrderStatusChange{}
+	for rows.Next() {
+		var change StructureData.OrderStatusChange
+		var changedBy sql.NullInt64
+		if err := rows.Scan(&change.ID, &change.OrderID, &change.FromStatus, &change.ToStatus, &change.ChangedAt, &changedBy, &change.Note); err != nil {
+			return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error scanning order history: %v", err)}
+		}
+		if changedBy.Valid {
+			actorID := int(changedBy.Int64)
+			change.ChangedBy = &actorID
+		}
+		history = append(history, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching order history: %v", err)}
+	}
+	return history, nil
+}
+
+// reserveStock decrements stock for each item within tx and returns the items that
+// could be reserved, with the book's current title, price and author captured on
+// each item, and the items that could not. New stock levels are recorded in
+// levels. Books are taken in ID order, as the Postgres store does, so that the
+// rejections come out the same.
+func reserveStock(ctx context.Context, tx *sql.Tx, items []StructureData.OrderItem, levels StructureData.StockLevels) ([]StructureData.OrderItem, []StructureData.OrderItemRejection, *StructureData.ErrorResponse) {
+	byBook := make([]int, len(items))
+	for i := range byBook {
+		byBook[i] = i
+	}
+	sort.SliceStable(byBook, func(a, b int) bool { return items[byBook[a]].Book.ID < items[byBook[b]].Book.ID })
+
+	reserved := make([]bool, len(items))
+	rejections := make([]*StructureData.OrderItemRejection, len(items))
+	for _, i := range byBook {
+		item := items[i]
+		if item.Quantity < 1 {
+			rejections[i] = &StructureData.OrderItemRejection{BookID: item.Book.ID, Quantity: item.Quantity, Reason: StructureData.RejectionInvalidQuantity}
+			continue
+		}
+		var title, authorName string
+		var price float64
+		var stock int
+		err := tx.QueryRowContext(ctx, `
+			UPDATE books SET stock = stock - ?2
+			WHERE id = ?1 AND stock >= ?2
+			RETURNING title, price, stock,
+				COALESCE((SELECT first_name || ' ' || last_name FROM authors WHERE authors.id = books.author_id), '')`,
+			item.Book.ID, item.Quantity).Scan(&title, &price, &stock, &authorName)
+		if err == sql.ErrNoRows {
+			rejection, errResp := stockRejection(ctx, tx, item)
+			if errResp != nil {
+				return nil, nil, errResp
+			}
+			rejections[i] = rejection
+			continue
+		} else if err != nil {
+			return nil, nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to reserve stock for book %d: %v", item.Book.ID, err)}
+		}
+		items[i].Book.Title = title
+		items[i].Book.Price = price
+		items[i].Book.Stock = stock
+		items[i].UnitPrice = price
+		items[i].BookTitle = title
+		items[i].AuthorName = authorName
+		levels[item.Book.ID] = stock
+		reserved[i] = true
+	}
+
+	var result []StructureData.OrderItem
+	var rejected []StructureData.OrderItemRejection
+	for i, item := range items {
+		if reserved[i] {
+			result = append(result, item)
+		} else if rejections[i] != nil {
+			rejected = append(rejected, *rejections[i])
+		}
+	}
+	return result, rejected, nil
+}
+
+// stockRejection explains why item's conditional stock update matched no row.
+func stockRejection(ctx context.Context, tx *sql.Tx, item StructureData.OrderItem) (*StructureData.OrderItemRejection, *StructureData.ErrorResponse) {
+	rejection := &StructureData.OrderItemRejection{BookID: item.Book.ID, Quantity: item.Quantity}
+	var available int
+	err := tx.QueryRowContext(ctx, `SELECT stock FROM books WHERE id = ?`, item.Book.ID).Scan(&available)
+	if err == sql.ErrNoRows {
+		rejection.Reason = StructureData.RejectionNotFound
+		return rejection, nil
+	} else if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching book %d: %v", item.Book.ID, err)}
+	}
+	rejection.Reason = StructureData.RejectionInsufficientStock
+	rejection.Available = &available
+	return rejection, nil
+}
+
+// restoreStock adds the quantities held by order orderID back to their books
+// within tx and records the new stock levels in levels.
+func restoreStock(ctx context.Context, tx *sql.Tx, orderID int, levels StructureData.StockLevels) *StructureData.ErrorResponse {
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE books SET stock = stock + held.quantity
+		FROM (SELECT book_id, SUM(quantity) AS quantity FROM order_items WHERE order_id = ? GROUP BY book_id) AS held
+		WHERE books.id = held.book_id
+		RETURNING books.id, books.stock`, orderID)
+	if err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to restock order %d: %v", orderID, err)}
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bookID, stock int
+		if err := rows.Scan(&bookID, &stock); err != nil {
+			return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to restock order %d: %v", orderID, err)}
+		}
+		levels[bookID] = stock
+	}
+	if err := rows.Err(); err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to restock order %d: %v", orderID, err)}
+	}
+	return nil
+}
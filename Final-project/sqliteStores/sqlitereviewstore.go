@@ -0,0 +1,108 @@
+package sqliteStores
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"finalProject/Interfaces"
+	"finalProject/StructureData"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLiteReviewStore implements the review storage using SQLite. Review statistics
+// are computed from the reviews whenever books are read, so nothing is kept up to
+// date on the books themselves.
+type SQLiteReviewStore struct {
+	db *sql.DB
+}
+
+var _ Interfaces.ReviewStore = (*SQLiteReviewStore)(nil)
+
+// NewSQLiteReviewStore returns a review store backed by db, opened with Open.
+func NewSQLiteReviewStore(db *sql.DB) *SQLiteReviewStore {
+	return &SQLiteReviewStore{db: db}
+}
+
+// CreateReview inserts a new review into the reviews table. A CustomerID of 0 is
+// stored as no customer. A review of a book that does not exist is a validation
+// error on book_id.
+func (store *SQLiteReviewStore) CreateReview(ctx context.Context, review StructureData.Review) (StructureData.Review, *StructureData.ErrorResponse) {
+	customerID := sql.NullInt64{Int64: int64(review.CustomerID), Valid: review.CustomerID != 0}
+	err := store.db.QueryRowContext(ctx, `
+		INSERT INTO reviews (book_id, customer_id, rating, review_text, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id`,
+		review.BookID, customerID, review.Rating, review.ReviewText, review.CreatedAt.UTC()).Scan(&review.ID)
+	// SQLite does not say which foreign key failed; the customer comes from the
+	// caller's token, so it is the book when the book is missing.
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY {
+		var bookExists bool
+		if store.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM books WHERE id = ?)", review.BookID).Scan(&bookExists) == nil && !bookExists {
+			return StructureData.Review{}, StructureData.FieldValidationError("book_id", "Book %d not found", review.BookID)
+		}
+	}
+	if err != nil {
+		return StructureData.Review{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to create review: %v", err)}
+	}
+	return review, nil
+}
+
+// GetReview retrieves a single review by its ID.
+func (store *SQLiteReviewStore) GetReview(ctx context.Context, id int) (StructureData.Review, *StructureData.ErrorResponse) {
+	var r StructureData.Review
+	err := store.db.QueryRowContext(ctx, `
+		SELECT id, book_id, COALESCE(customer_id, 0), rating, review_text, created_at
+		FROM reviews
+		WHERE id = ?`, id).Scan(&r.ID, &r.BookID, &r.CustomerID, &r.Rating, &r.ReviewText, &r.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return StructureData.Review{}, StructureData.NotFoundError("Review not found")
+		}
+		return StructureData.Review{}, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to fetch review: %v", err)}
+	}
+	return r, nil
+}
+
+// GetReviewsByBookID retrieves all reviews for a given book, most recent first.
+func (store *SQLiteReviewStore) GetReviewsByBookID(ctx context.Context, bookID int) ([]StructureData.Review, *StructureData.ErrorResponse) {
+	rows, err := store.db.QueryContext(ctx, `
+		SELECT id, book_id, COALESCE(customer_id, 0), rating, review_text, created_at
+		FROM reviews
+		WHERE book_id = ?
+		ORDER BY created_at DESC, id DESC`, bookID)
+	if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to fetch reviews: %v", err)}
+	}
+	defer rows.Close()
+
+	var reviews []StructureData.Review
+	for rows.Next() {
+		var r StructureData.Review
+		if err := rows.Scan(&r.ID, &r.BookID, &r.CustomerID, &r.Rating, &r.ReviewText, &r.CreatedAt); err != nil {
+			return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error scanning review: %v", err)}
+		}
+		reviews = append(reviews, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to fetch reviews: %v", err)}
+	}
+	return reviews, nil
+}
+
+// DeleteReview removes a review from the database.
+func (store *SQLiteReviewStore) DeleteReview(ctx context.Context, id int) *StructureData.ErrorResponse {
+	res, err := store.db.ExecContext(ctx, `DELETE FROM reviews WHERE id = ?`, id)
+	if err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to delete review: %v", err)}
+	}
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return StructureData.NotFoundError("Review not found")
+	}
+	return nil
+}
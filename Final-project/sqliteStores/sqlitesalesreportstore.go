@@ -0,0 +1,121 @@
+package sqliteStores
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"finalProject/Interfaces"
+	"finalProject/StructureData"
+)
+
+// SQLiteSalesReportStore implements persistence for sales reports in SQLite.
+type SQLiteSalesReportStore struct {
+	db *sql.DB
+}
+
+var _ Interfaces.SalesReportStore = (*SQLiteSalesReportStore)(nil)
+
+// NewSQLiteSalesReportStore returns a sales report store backed by db, opened with Open.
+func NewSQLiteSalesReportStore(db *sql.DB) *SQLiteSalesReportStore {
+	return &SQLiteSalesReportStore{db: db}
+}
+
+// SaveSalesReport inserts a new sales report and its top selling books.
+func (store *SQLiteSalesReportStore) SaveSalesReport(ctx context.Context, report StructureData.SalesReport) (*StructureData.SalesReport, *StructureData.ErrorResponse) {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to begin transaction: %v", err)}
+	}
+	defer tx.Rollback()
+
+	statusCounts, err := json.Marshal(report.StatusCounts)
+	if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to encode status counts: %v", err)}
+	}
+
+	var reportID int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO sales_reports (timestamp, total_revenue, total_orders, successful_orders, pending_orders, status_counts)
+		VALUES (?, ?, ?, ?, ?, ?) RETURNING id`,
+		report.Timestamp.UTC(), report.TotalRevenue, report.TotalOrders, report.SuccessfulOrders, report.PendingOrders, string(statusCounts),
+	).Scan(&reportID)
+	if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to insert sales report: %v", err)}
+	}
+
+	for _, tsb := range report.TopSellingBooks {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO top_selling_books (sales_report_id, book_id, quantity_sold, total_revenue, book_title, book_price)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			reportID, tsb.Book.ID, tsb.QuantitySold, tsb.TotalRevenue, tsb.Book.Title, tsb.Book.Price)
+		if err != nil {
+			return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to insert top selling book: %v", err)}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to commit transaction: %v", err)}
+	}
+	log.Printf("Inserted sales report with generated ID %d", reportID)
+	return &report, nil
+}
+
+// GetAllSalesReports retrieves all sales reports, oldest first, and their top
+// selling books, with one query for each.
+func (store *SQLiteSalesReportStore) GetAllSalesReports(ctx context.Context) ([]StructureData.SalesReport, *StructureData.ErrorResponse) {
+	rows, err := store.db.QueryContext(ctx, `
+		SELECT id, timestamp, total_revenue, total_orders, successful_orders, pending_orders, status_counts
+		FROM sales_reports
+		ORDER BY id`)
+	if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to fetch sales reports: %v", err)}
+	}
+	var reports []StructureData.SalesReport
+	index := map[int]int{}
+	for rows.Next() {
+		var report StructureData.SalesReport
+		var reportID int
+		var statusCounts string
+		err := rows.Scan(&reportID, &report.Timestamp, &report.TotalRevenue, &report.TotalOrders, &report.SuccessfulOrders, &report.PendingOrders, &statusCounts)
+		if err != nil {
+			rows.Close()
+			return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error scanning sales report: %v", err)}
+		}
+		if err := json.Unmarshal([]byte(statusCounts), &report.StatusCounts); err != nil {
+			log.Printf("Error decoding status counts for report ID %d: %v", reportID, err)
+		}
+		index[reportID] = len(reports)
+		reports = append(reports, report)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to fetch sales reports: %v", err)}
+	}
+
+	rows, err = store.db.QueryContext(ctx, `
+		SELECT sales_report_id, book_id, quantity_sold, total_revenue, book_title, book_price
+		FROM top_selling_books
+		ORDER BY sales_report_id, total_revenue DESC`)
+	if err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to fetch top selling books: %v", err)}
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var reportID int
+		var tsb StructureData.TopSellingBook
+		err := rows.Scan(&reportID, &tsb.Book.ID, &tsb.QuantitySold, &tsb.TotalRevenue, &tsb.Book.Title, &tsb.Book.Price)
+		if err != nil {
+			return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error scanning top selling book: %v", err)}
+		}
+		if i, ok := index[reportID]; ok {
+			reports[i].TopSellingBooks = append(reports[i].TopSellingBooks, tsb)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to fetch top selling books: %v", err)}
+	}
+	return reports, nil
+}
@@ -0,0 +1,174 @@
+package sqliteStores
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"finalProject/Interfaces"
+	"finalProject/StructureData"
+)
+
+// SQLiteTokenStore persists refresh tokens and revoked access token IDs (jti) in SQLite.
+type SQLiteTokenStore struct {
+	db *sql.DB
+}
+
+var _ Interfaces.TokenStore = (*SQLiteTokenStore)(nil)
+
+// NewSQLiteTokenStore returns a token store backed by db, opened with Open.
+func NewSQLiteTokenStore(db *sql.DB) *SQLiteTokenStore {
+	return &SQLiteTokenStore{db: db}
+}
+
+// SaveRefreshToken records a newly issued refresh token (by hash) for a customer,
+// along with the jti of the access token issued alongside it.
+func (store *SQLiteTokenStore) SaveRefreshToken(ctx context.Context, customerID int, tokenHash string, accessJTI string, expiresAt time.Time) *StructureData.ErrorResponse {
+	if _, err := insertRefreshToken(ctx, store.db, customerID, tokenHash, accessJTI, expiresAt); err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to save refresh token: %v", err)}
+	}
+	return nil
+}
+
+// RefreshTokenOwner returns the ID of the customer a refresh token was issued to.
+func (store *SQLiteTokenStore) RefreshTokenOwner(ctx context.Context, tokenHash string) (int, *StructureData.ErrorResponse) {
+	var customerID int
+	err := store.db.QueryRowContext(ctx, `SELECT customer_id FROM refresh_tokens WHERE token_hash = ?`, tokenHash).Scan(&customerID)
+	if err == sql.ErrNoRows {
+		return 0, StructureData.UnauthorizedError("Invalid refresh token")
+	} else if err != nil {
+		return 0, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching refresh token: %v", err)}
+	}
+	return customerID, nil
+}
+
+// RotateRefreshToken atomically revokes the refresh token identified by oldHash and
+// stores its replacement. The access token issued with the old refresh token is put
+// on the revocation list until revokeAccessUntil. It returns the customer the token
+// belonged to.
+//
+// Presenting a refresh token that was already rotated or revoked is treated as
+// token theft: every outstanding refresh token of that customer is revoked.
+func (store *SQLiteTokenStore) RotateRefreshToken(ctx context.Context, oldHash string, newHash string, newAccessJTI string, newExpiresAt time.Time, revokeAccessUntil time.Time) (int, *StructureData.ErrorResponse) {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to begin transaction: %v", err)}
+	}
+	defer tx.Rollback()
+
+	var id, customerID int
+	var accessJTI sql.NullString
+	var expiresAt time.Time
+	var revokedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, customer_id, access_jti, expires_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = ?`, oldHash).Scan(&id, &customerID, &accessJTI, &expiresAt, &revokedAt)
+	if err == sql.ErrNoRows {
+		return 0, StructureData.UnauthorizedError("Invalid refresh token")
+	} else if err != nil {
+		return 0, &StructureData.ErrorResponse{Message: fmt.Sprintf("Error fetching refresh token: %v", err)}
+	}
+
+	now := time.Now().UTC()
+	if revokedAt.Valid {
+		if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = ? WHERE customer_id = ? AND revoked_at IS NULL`, now, customerID); err != nil {
+			return 0, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to revoke refresh tokens: %v", err)}
+		}
+		if err := tx.Commit(); err != nil {
+			return 0, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to commit transaction: %v", err)}
+		}
+		log.Printf("Refresh token reuse detected for customer %d; all refresh tokens revoked", customerID)
+		return 0, StructureData.UnauthorizedError("Refresh token has been revoked")
+	}
+	if now.After(expiresAt) {
+		return 0, StructureData.UnauthorizedError("Refresh token expired")
+	}
+
+	newID, err := insertRefreshToken(ctx, tx, customerID, newHash, newAccessJTI, newExpiresAt)
+	if err != nil {
+		return 0, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to save refresh token: %v", err)}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = ?, replaced_by = ? WHERE id = ?`, now, newID, id); err != nil {
+		return 0, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to revoke refresh token: %v", err)}
+	}
+	if accessJTI.Valid && accessJTI.String != "" {
+		if err := revokeJTI(ctx, tx, accessJTI.String, revokeAccessUntil); err != nil {
+			return 0, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to revoke access token: %v", err)}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to commit transaction: %v", err)}
+	}
+	return customerID, nil
+}
+
+// RevokeRefreshToken revokes the refresh token identified by tokenHash if it belongs
+// to customerID.
+func (store *SQLiteTokenStore) RevokeRefreshToken(ctx context.Context, customerID int, tokenHash string) *StructureData.ErrorResponse {
+	res, err := store.db.ExecContext(ctx, `
+		UPDATE refresh_tokens SET revoked_at = ?
+		WHERE token_hash = ? AND customer_id = ? AND revoked_at IS NULL`, time.Now().UTC(), tokenHash, customerID)
+	if err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to revoke refresh token: %v", err)}
+	}
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return StructureData.FieldValidationError("refresh_token", "Refresh token not found")
+	}
+	return nil
+}
+
+// RevokeAccessToken adds an access token's jti to the revocation list until expiresAt,
+// after which the token would be rejected anyway.
+func (store *SQLiteTokenStore) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) *StructureData.ErrorResponse {
+	if err := revokeJTI(ctx, store.db, jti, expiresAt); err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to revoke access token: %v", err)}
+	}
+	return nil
+}
+
+// IsAccessTokenRevoked reports whether the access token with the given jti was revoked.
+func (store *SQLiteTokenStore) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	err := store.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = ?)`, jti).Scan(&revoked)
+	return revoked, err
+}
+
+// PurgeExpiredTokens deletes refresh tokens and revocation entries that have expired.
+func (store *SQLiteTokenStore) PurgeExpiredTokens(ctx context.Context) *StructureData.ErrorResponse {
+	now := time.Now().UTC()
+	if _, err := store.db.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at < ?`, now); err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to purge revoked tokens: %v", err)}
+	}
+	if _, err := store.db.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE expires_at < ?`, now); err != nil {
+		return &StructureData.ErrorResponse{Message: fmt.Sprintf("Failed to purge refresh tokens: %v", err)}
+	}
+	return nil
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// insertRefreshToken stores a refresh token issued now and returns its ID.
+func insertRefreshToken(ctx context.Context, db queryer, customerID int, tokenHash, accessJTI string, expiresAt time.Time) (int, error) {
+	var id int
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO refresh_tokens (customer_id, token_hash, access_jti, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?) RETURNING id`,
+		customerID, tokenHash, accessJTI, expiresAt.UTC(), time.Now().UTC()).Scan(&id)
+	return id, err
+}
+
+func revokeJTI(ctx context.Context, db queryer, jti string, expiresAt time.Time) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO revoked_tokens (jti, expires_at, revoked_at) VALUES (?, ?, ?)
+		ON CONFLICT (jti) DO NOTHING`, jti, expiresAt.UTC(), time.Now().UTC())
+	return err
+}
@@ -0,0 +1,28 @@
+package sqliteStores
+
+import "database/sql"
+
+// Stores are the SQLite stores that serve the whole API from one database. The
+// order store is also the API's order processor.
+type Stores struct {
+	Books     *SQLiteBookStore
+	Authors   *SQLiteAuthorStore
+	Customers *SQLiteCustomerStore
+	Orders    *SQLiteOrderStore
+	Reviews   *SQLiteReviewStore
+	Reports   *SQLiteSalesReportStore
+	Tokens    *SQLiteTokenStore
+}
+
+// NewStores returns the stores of db, opened with Open and migrated.
+func NewStores(db *sql.DB) Stores {
+	return Stores{
+		Books:     NewSQLiteBookStore(db),
+		Authors:   NewSQLiteAuthorStore(db),
+		Customers: NewSQLiteCustomerStore(db),
+		Orders:    NewSQLiteOrderStore(db),
+		Reviews:   NewSQLiteReviewStore(db),
+		Reports:   NewSQLiteSalesReportStore(db),
+		Tokens:    NewSQLiteTokenStore(db),
+	}
+}
@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	data "finalProject/StructureData"
+)
+
+// ParseFilter validates filter against fields, which maps each field the filter
+// may test to its type, and converts the values it compares with to the field's
+// Go type: string, float64 or time.Time. A nil filter is valid.
+func ParseFilter(filter *data.Filter, fields map[string]string) *data.ErrorResponse {
+	if filter == nil {
+		return nil
+	}
+	nodes := 0
+	return parseFilterNode(filter, fields, "filter", &nodes)
+}
+
+func parseFilterNode(f *data.Filter, fields map[string]string, path string, nodes *int) *data.ErrorResponse {
+	*nodes++
+	if *nodes > data.MaxFilterNodes {
+		return data.FieldValidationError("filter", "filter has more than %d nodes", data.MaxFilterNodes)
+	}
+
+	kinds := 0
+	for _, set := range []bool{len(f.And) > 0, len(f.Or) > 0, f.Not != nil, f.Field != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return data.FieldValidationError(path, "%s: a filter node needs exactly one of a non-empty and, a non-empty or, not, or field", path)
+	}
+
+	switch {
+	case len(f.And) > 0:
+		for i := range f.And {
+			if errResp := parseFilterNode(&f.And[i], fields, fmt.Sprintf("%s.and[%d]", path, i), nodes); errResp != nil {
+				return errResp
+			}
+		}
+		return nil
+	case len(f.Or) > 0:
+		for i := range f.Or {
+			if errResp := parseFilterNode(&f.Or[i], fields, fmt.Sprintf("%s.or[%d]", path, i), nodes); errResp != nil {
+				return errResp
+			}
+		}
+		return nil
+	case f.Not != nil:
+		return parseFilterNode(f.Not, fields, path+".not", nodes)
+	}
+
+	fieldType, ok := fields[f.Field]
+	if !ok {
+		return data.FieldValidationError(path, "%s: unknown field %q", path, f.Field)
+	}
+	if !ContainsString(data.FilterOps[fieldType], f.Op) {
+		return data.FieldValidationError(path, "%s: field %q supports %s, not %q", path, f.Field, strings.Join(data.FilterOps[fieldType], ", "), f.Op)
+	}
+
+	var err error
+	switch f.Op {
+	case data.FilterOpEq, data.FilterOpContains:
+		if f.Value == nil {
+			return data.FieldValidationError(path, "%s: %s needs a value", path, f.Op)
+		}
+		f.Value, err = filterValue(f.Value, fieldType)
+	case data.FilterOpIn:
+		if len(f.Values) == 0 {
+			return data.FieldValidationError(path, "%s: in needs a non-empty values list", path)
+		}
+		for i := range f.Values {
+			if f.Values[i], err = filterValue(f.Values[i], fieldType); err != nil {
+				break
+			}
+		}
+	case data.FilterOpRange:
+		if f.Min == nil && f.Max == nil {
+			return data.FieldValidationError(path, "%s: range needs a min, a max or both", path)
+		}
+		if f.Min != nil {
+			f.Min, err = filterValue(f.Min, fieldType)
+		}
+		if err == nil && f.Max != nil {
+			f.Max, err = filterValue(f.Max, fieldType)
+		}
+	}
+	if err != nil {
+		return data.FieldValidationError(path, "%s: field %q: %v", path, f.Field, err)
+	}
+	return nil
+}
+
+// filterValue converts a value decoded from JSON for comparison with a field of
+// type fieldType.
+func filterValue(value interface{}, fieldType string) (interface{}, error) {
+	switch fieldType {
+	case data.FilterNumber:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		}
+		return nil, fmt.Errorf("%v is not a number", value)
+	case data.FilterTime:
+		switch v := value.(type) {
+		case time.Time:
+			return v, nil
+		case string:
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not an RFC 3339 time", v)
+			}
+			return t, nil
+		}
+		return nil, fmt.Errorf("%v is not an RFC 3339 time", value)
+	}
+	v, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("%v is not a string", value)
+	}
+	return v, nil
+}
+
+// MatchFilter reports whether record satisfies filter, which must have been
+// parsed with ParseFilter. A nil filter matches every record.
+func MatchFilter(filter *data.Filter, record data.Filterable) bool {
+	if filter == nil {
+		return true
+	}
+	switch {
+	case len(filter.And) > 0:
+		for i := range filter.And {
+			if !MatchFilter(&filter.And[i], record) {
+				return false
+			}
+		}
+		return true
+	case len(filter.Or) > 0:
+		for i := range filter.Or {
+			if MatchFilter(&filter.Or[i], record) {
+				return true
+			}
+		}
+		return false
+	case filter.Not != nil:
+		return !MatchFilter(filter.Not, record)
+	}
+
+	value := record.FilterValue(filter.Field)
+	if list, ok := value.([]string); ok {
+		for _, element := range list {
+			if matchPredicate(filter, element) {
+				return true
+			}
+		}
+		return false
+	}
+	return matchPredicate(filter, value)
+}
+
+// matchPredicate tests a single value against the predicate of filter.
+func matchPredicate(filter *data.Filter, value interface{}) bool {
+	switch filter.Op {
+	case data.FilterOpEq:
+		return CompareValues(value, filter.Value) == 0
+	case data.FilterOpIn:
+		for _, v := range filter.Values {
+			if CompareValues(value, v) == 0 {
+				return true
+			}
+		}
+		return false
+	case data.FilterOpContains:
+		s, _ := value.(string)
+		substr, _ := filter.Value.(string)
+		return ContainsIgnoreCase(s, substr)
+	case data.FilterOpRange:
+		return (filter.Min == nil || CompareValues(value, filter.Min) >= 0) &&
+			(filter.Max == nil || CompareValues(value, filter.Max) <= 0)
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"strings"
+)
+
+// FuzzyThreshold is the lowest WordSimilarity at which a fuzzy search term
+// matches. It equals the default pg_trgm.word_similarity_threshold used by the
+// Postgres stores.
+const FuzzyThreshold = 0.6
+
+// Similarity scores how alike a and b are, ignoring case, from 0 (nothing in
+// common) to 1 (equal). It is one minus their edit distance relative to the
+// longer string; swapping two adjacent letters counts as one edit.
+func Similarity(a, b string) float64 {
+	x, y := []rune(strings.ToLower(a)), []rune(strings.ToLower(b))
+	longest := max(len(x), len(y))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(editDistance(x, y))/float64(longest)
+}
+
+// editDistance returns the optimal string alignment distance between x and y.
+func editDistance(x, y []rune) int {
+	// d[i][j] is the distance between x[:i] and y[:j].
+	d := make([][]int, len(x)+1)
+	for i := range d {
+		d[i] = make([]int, len(y)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(x); i++ {
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && x[i-1] == y[j-2] && x[i-2] == y[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(x)][len(y)]
+}
+
+// WordSimilarity returns the best Similarity between term and any run of as
+// many consecutive words of text as term has, so "Tolkein" scores high against
+// "J.R.R. Tolkien".
+func WordSimilarity(term, text string) float64 {
+	termWords := SearchTerms(term)
+	textWords := SearchTerms(text)
+	if len(termWords) == 0 {
+		return 0
+	}
+	joined := strings.Join(termWords, " ")
+	n := min(len(termWords), len(textWords))
+	best := Similarity(joined, strings.Join(textWords, " "))
+	for i := 0; i+n <= len(textWords); i++ {
+		best = max(best, Similarity(joined, strings.Join(textWords[i:i+n], " ")))
+	}
+	return best
+}
+
+// FuzzyScore returns the best WordSimilarity of any of terms to any of texts,
+// and whether it reaches FuzzyThreshold.
+func FuzzyScore(terms []string, texts ...string) (float64, bool) {
+	best := 0.0
+	for _, term := range terms {
+		for _, text := range texts {
+			best = max(best, WordSimilarity(term, text))
+		}
+	}
+	return best, best >= FuzzyThreshold
+}
@@ -0,0 +1,292 @@
+package utils
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	data "finalProject/StructureData"
+)
+
+// Sortable is implemented by records that list endpoints can sort.
+type Sortable interface {
+	SortValue(field string) interface{}
+}
+
+// cursor is the decoded form of the opaque next_cursor handed to clients. It
+// remembers the sort order it was issued for, so it cannot be replayed against
+// a different one.
+type cursor struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+// ParseListOptions reads the limit, offset, page, cursor, sort and fields query
+// parameters of a list request. sortable lists the fields the resource can be
+// sorted by.
+func ParseListOptions(query url.Values, sortable []string) (data.ListOptions, *data.ErrorResponse) {
+	opts := data.ListOptions{Limit: data.DefaultListLimit}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > data.MaxListLimit {
+			return data.ListOptions{}, data.FieldValidationError("limit", "limit must be between 1 and %d", data.MaxListLimit)
+		}
+		opts.Limit = limit
+	}
+
+	cursorParam, offsetParam, pageParam := query.Get("cursor"), query.Get("offset"), query.Get("page")
+	if cursorParam != "" && (offsetParam != "" || pageParam != "") {
+		return data.ListOptions{}, data.FieldValidationError("cursor", "cursor cannot be combined with offset or page")
+	}
+	if offsetParam != "" && pageParam != "" {
+		return data.ListOptions{}, data.FieldValidationError("page", "offset and page cannot be combined")
+	}
+	if offsetParam != "" {
+		offset, err := strconv.Atoi(offsetParam)
+		if err != nil || offset < 0 {
+			return data.ListOptions{}, data.FieldValidationError("offset", "offset must be a non-negative integer")
+		}
+		opts.Offset = offset
+	}
+	if pageParam != "" {
+		page, err := strconv.Atoi(pageParam)
+		if err != nil || page < 1 {
+			return data.ListOptions{}, data.FieldValidationError("page", "page must be a positive integer")
+		}
+		opts.Offset = (page - 1) * opts.Limit
+	}
+
+	fields, errResp := parseSort(query.Get("sort"), sortable)
+	if errResp != nil {
+		return data.ListOptions{}, errResp
+	}
+	opts.Sort = fields
+
+	if cursorParam != "" {
+		values, errResp := decodeCursor(cursorParam, opts.Sort)
+		if errResp != nil {
+			return data.ListOptions{}, errResp
+		}
+		opts.After = values
+	}
+
+	if v := query.Get("fields"); v != "" {
+		for _, field := range strings.Split(v, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				opts.Fields = append(opts.Fields, field)
+			}
+		}
+	}
+	return opts, nil
+}
+
+// parseSort parses a sort parameter such as "title,-price" and appends "id" as
+// the final tie-breaker unless it is already present.
+func parseSort(param string, sortable []string) ([]data.SortField, *data.ErrorResponse) {
+	var fields []data.SortField
+	seen := map[string]bool{}
+	for _, part := range strings.Split(param, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field := data.SortField{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if !ContainsString(sortable, field.Field) {
+			return nil, data.FieldValidationError("sort", "Cannot sort by %q; sortable fields are %s", field.Field, strings.Join(sortable, ", "))
+		}
+		if seen[field.Field] {
+			return nil, data.FieldValidationError("sort", "Sort field %q is given more than once", field.Field)
+		}
+		seen[field.Field] = true
+		fields = append(fields, field)
+	}
+	if !seen["id"] {
+		fields = append(fields, data.SortField{Field: "id"})
+	}
+	return fields, nil
+}
+
+// FormatSort renders fields in the form accepted by the sort query parameter.
+func FormatSort(fields []data.SortField) string {
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		if field.Desc {
+			parts[i] = "-" + field.Field
+		} else {
+			parts[i] = field.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// EncodeCursor returns a cursor that continues a list sorted by fields after the
+// row with the given sort values.
+func EncodeCursor(fields []data.SortField, values []interface{}) string {
+	raw, _ := json.Marshal(cursor{Sort: FormatSort(fields), Values: values})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string, fields []data.SortField) ([]interface{}, *data.ErrorResponse) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, data.FieldValidationError("cursor", "Invalid cursor")
+	}
+	var c cursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, data.FieldValidationError("cursor", "Invalid cursor")
+	}
+	if c.Sort != FormatSort(fields) || len(c.Values) != len(fields) {
+		return nil, data.FieldValidationError("cursor", "Cursor does not match the requested sort order")
+	}
+	return c.Values, nil
+}
+
+// SortValues returns the values of item for each of fields.
+func SortValues(item Sortable, fields []data.SortField) []interface{} {
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		values[i] = item.SortValue(field.Field)
+	}
+	return values
+}
+
+// FinishPage builds the metadata for a page fetched with one row more than
+// opts.Limit, and drops that extra row. The extra row only tells whether a next
+// page exists.
+func FinishPage[T Sortable](items []T, opts data.ListOptions, total int) ([]T, data.ListMeta) {
+	meta := data.ListMeta{Total: total, Limit: opts.Limit, Offset: opts.Offset}
+	if len(items) > opts.Limit {
+		items = items[:opts.Limit]
+		meta.NextCursor = EncodeCursor(opts.Sort, SortValues(items[len(items)-1], opts.Sort))
+	}
+	return items, meta
+}
+
+// Paginate sorts items and returns the page selected by opts. It is the in-memory
+// counterpart of the queries the Postgres stores run.
+func Paginate[T Sortable](items []T, opts data.ListOptions) ([]T, data.ListMeta) {
+	sorted := append([]T{}, items...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compareBySort(SortValues(sorted[i], opts.Sort), SortValues(sorted[j], opts.Sort), opts.Sort) < 0
+	})
+
+	start := min(opts.Offset, len(sorted))
+	if opts.After != nil {
+		start = sort.Search(len(sorted), func(i int) bool {
+			return compareBySort(SortValues(sorted[i], opts.Sort), opts.After, opts.Sort) > 0
+		})
+	}
+	end := min(start+opts.Limit+1, len(sorted))
+	return FinishPage(sorted[start:end], opts, len(items))
+}
+
+// compareBySort compares two rows, given by their sort values, in the order
+// described by fields.
+func compareBySort(a, b []interface{}, fields []data.SortField) int {
+	for i, field := range fields {
+		c := CompareValues(a[i], b[i])
+		if field.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// CompareValues orders two sort values. a is taken from a record; b is either
+// taken from a record too or decoded from a cursor, where numbers arrive as
+// float64 and times as RFC 3339 strings.
+func CompareValues(a, b interface{}) int {
+	switch x := a.(type) {
+	case time.Time:
+		switch y := b.(type) {
+		case time.Time:
+			return x.Compare(y)
+		case string:
+			if t, err := time.Parse(time.RFC3339Nano, y); err == nil {
+				return x.Compare(t)
+			}
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y)
+		}
+	case int:
+		return cmp.Compare(float64(x), toFloat(b))
+	case float64:
+		return cmp.Compare(x, toFloat(b))
+	}
+	return 0
+}
+
+func toFloat(v interface{}) float64 {
+	switch x := v.(type) {
+	case int:
+		return float64(x)
+	case float64:
+		return x
+	}
+	return 0
+}
+
+// SelectFields reduces each element of items, a slice of structs, to the given
+// JSON fields. Without fields items is returned unchanged.
+func SelectFields(items interface{}, fields []string) (interface{}, *data.ErrorResponse) {
+	if len(fields) == 0 {
+		return items, nil
+	}
+	known := jsonFieldNames(reflect.TypeOf(items).Elem())
+	for _, field := range fields {
+		if !known[field] {
+			return nil, data.FieldValidationError("fields", "Unknown field %q", field)
+		}
+	}
+
+	raw, err := json.Marshal(items)
+	if err != nil {
+		return nil, data.InternalError("Failed to encode response: %v", err)
+	}
+	var objects []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &objects); err != nil {
+		return nil, data.InternalError("Failed to encode response: %v", err)
+	}
+	selected := make([]map[string]json.RawMessage, len(objects))
+	for i, object := range objects {
+		selected[i] = make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if value, ok := object[field]; ok {
+				selected[i][field] = value
+			}
+		}
+	}
+	return selected, nil
+}
+
+// jsonFieldNames returns the JSON names of the fields of struct type t.
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = true
+	}
+	return names
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	data "finalProject/StructureData"
+)
+
+// ProblemContentType is the media type of error responses.
+const ProblemContentType = "application/problem+json"
+
+// problemKinds gives the status and title of the problems of each error code.
+var problemKinds = map[data.ErrorCode]struct {
+	status int
+	title  string
+}{
+	data.CodeValidation:        {http.StatusBadRequest, "Validation failed"},
+	data.CodeUnauthorized:      {http.StatusUnauthorized, "Unauthorized"},
+	data.CodeForbidden:         {http.StatusForbidden, "Forbidden"},
+	data.CodeNotFound:          {http.StatusNotFound, "Not found"},
+	data.CodeConflict:          {http.StatusConflict, "Conflict"},
+	data.CodeInsufficientStock: {http.StatusConflict, "Insufficient stock"},
+	data.CodeInternal:          {http.StatusInternalServerError, "Internal server error"},
+}
+
+// ErrorStatus returns the HTTP status of responses to errResp.
+func ErrorStatus(errResp *data.ErrorResponse) int {
+	if kind, ok := problemKinds[errResp.Kind()]; ok {
+		return kind.status
+	}
+	return http.StatusInternalServerError
+}
+
+// internalDetail is the detail of internal problems, whose messages may carry
+// database errors and are only logged.
+const internalDetail = "Internal server error"
+
+// NewProblem returns the problem document describing errResp, which happened
+// while serving r. Its type is a relative URI naming the error code, such as
+// /problems/not_found.
+func NewProblem(r *http.Request, errResp *data.ErrorResponse) data.Problem {
+	code := errResp.Kind()
+	kind, ok := problemKinds[code]
+	if !ok {
+		code = data.CodeInternal
+		kind = problemKinds[code]
+	}
+	detail := errResp.Message
+	if code == data.CodeInternal {
+		detail = internalDetail
+	}
+	return data.Problem{
+		Type:          "/problems/" + string(code),
+		Title:         kind.title,
+		Status:        kind.status,
+		Detail:        detail,
+		Instance:      requestPath(r),
+		Code:          code,
+		Errors:        errResp.Fields,
+		RejectedItems: errResp.RejectedItems,
+	}
+}
+
+// WriteError answers r with the problem document describing errResp. Every
+// handler and middleware reports its errors through it. The messages of internal
+// errors are logged rather than sent.
+func WriteError(w http.ResponseWriter, r *http.Request, errResp *data.ErrorResponse) {
+	problem := NewProblem(r, errResp)
+	if problem.Code == data.CodeInternal {
+		log.Printf("%s %s: %s", r.Method, problem.Instance, errResp.Message)
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// requestPath returns the path r was sent to. Routes rewrite r.URL.Path for the
+// handlers, so it is taken from the request line when there is one.
+func requestPath(r *http.Request) string {
+	if r.RequestURI == "" {
+		return r.URL.Path
+	}
+	path, _, _ := strings.Cut(r.RequestURI, "?")
+	return path
+}
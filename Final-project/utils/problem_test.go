@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	data "finalProject/StructureData"
+)
+
+func TestNewProblemDetail(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/customers/7", nil)
+	tests := []struct {
+		errResp *data.ErrorResponse
+		want    string
+	}{
+		{data.NotFoundError("Customer not found"), "Customer not found"},
+		{data.InternalError("Failed to update customer: pq: connection refused"), internalDetail},
+		{&data.ErrorResponse{Message: "pq: deadlock detected"}, internalDetail},
+	}
+	for _, tt := range tests {
+		if got := NewProblem(r, tt.errResp).Detail; got != tt.want {
+			t.Errorf("NewProblem(%q).Detail = %q, want %q", tt.errResp.Message, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	data "finalProject/StructureData"
+)
+
+// ParseTextQuery reads the q, limit and offset query parameters of a full-text
+// search request.
+func ParseTextQuery(query url.Values) (data.BookTextQuery, *data.ErrorResponse) {
+	q := data.BookTextQuery{Query: strings.TrimSpace(query.Get("q")), Limit: data.DefaultListLimit}
+	if len(SearchTerms(q.Query)) == 0 {
+		return data.BookTextQuery{}, data.FieldValidationError("q", "q must contain at least one word")
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > data.MaxListLimit {
+			return data.BookTextQuery{}, data.FieldValidationError("limit", "limit must be between 1 and %d", data.MaxListLimit)
+		}
+		q.Limit = limit
+	}
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return data.BookTextQuery{}, data.FieldValidationError("offset", "offset must be a non-negative integer")
+		}
+		q.Offset = offset
+	}
+	return q, nil
+}
+
+// SearchTerms splits a search query into lower-case words. Anything that is not a
+// letter or digit separates words.
+func SearchTerms(q string) []string {
+	return strings.FieldsFunc(strings.ToLower(q), isNotWordRune)
+}
+
+func isNotWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// wordSpans returns the start and end byte offsets of the words of text.
+func wordSpans(text string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range text {
+		if isNotWordRune(r) {
+			if start >= 0 {
+				spans = append(spans, [2]int{start, i})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(text)})
+	}
+	return spans
+}
+
+// matchesAnyTerm reports whether word starts with one of terms, ignoring case.
+func matchesAnyTerm(word string, terms []string) bool {
+	word = strings.ToLower(word)
+	for _, term := range terms {
+		if strings.HasPrefix(word, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// CountMatches returns how many words of text start with one of terms.
+func CountMatches(text string, terms []string) int {
+	count := 0
+	for _, span := range wordSpans(text) {
+		if matchesAnyTerm(text[span[0]:span[1]], terms) {
+			count++
+		}
+	}
+	return count
+}
+
+// HighlightTerms wraps the words of text that start with one of terms in
+// highlight tags.
+func HighlightTerms(text string, terms []string) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range wordSpans(text) {
+		if !matchesAnyTerm(text[span[0]:span[1]], terms) {
+			continue
+		}
+		b.WriteString(text[last:span[0]])
+		b.WriteString(data.HighlightStart + text[span[0]:span[1]] + data.HighlightStop)
+		last = span[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// Snippet returns at most maxWords words of text, starting a few words before the
+// first match, with the matches highlighted. Without a match the snippet is the
+// start of text.
+func Snippet(text string, terms []string, maxWords int) string {
+	spans := wordSpans(text)
+	if len(spans) == 0 {
+		return ""
+	}
+	first := 0
+	for i, span := range spans {
+		if matchesAnyTerm(text[span[0]:span[1]], terms) {
+			first = max(i-3, 0)
+			break
+		}
+	}
+	last := min(first+maxWords, len(spans)) - 1
+	return HighlightTerms(text[spans[first][0]:spans[last][1]], terms)
+}
@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"Tolk", []string{"tolk"}},
+		{"tolk HOB", []string{"tolk", "hob"}},
+		{"J.R.R. Tolkien", []string{"j", "r", "r", "tolkien"}},
+		{"sci-fi, 1984!", []string{"sci", "fi", "1984"}},
+		{"Émile Zola", []string{"émile", "zola"}},
+	}
+	for _, tt := range tests {
+		got := SearchTerms(tt.query)
+		if len(got) != len(tt.want) || len(got) > 0 && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SearchTerms(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestCountMatches(t *testing.T) {
+	tests := []struct {
+		text  string
+		terms []string
+		want  int
+	}{
+		{"The Hobbit", []string{"hob"}, 1},
+		{"The Hobbit", []string{"bit"}, 0},
+		{"The Hobbit", []string{"the", "hobbit"}, 2},
+		{"Hobbits and hobbit-holes", []string{"hobbit"}, 2},
+		{"The Hobbit", []string{"hobbits"}, 0},
+		{"", []string{"a"}, 0},
+	}
+	for _, tt := range tests {
+		if got := CountMatches(tt.text, tt.terms); got != tt.want {
+			t.Errorf("CountMatches(%q, %q) = %d, want %d", tt.text, tt.terms, got, tt.want)
+		}
+	}
+}
+
+func TestHighlightTerms(t *testing.T) {
+	tests := []struct {
+		text  string
+		terms []string
+		want  string
+	}{
+		{"The Hobbit", []string{"hob"}, "The <mark>Hobbit</mark>"},
+		{"The Hobbit", []string{"ring"}, "The Hobbit"},
+		{"Dune, Dune Messiah", []string{"dune"}, "<mark>Dune</mark>, <mark>Dune</mark> Messiah"},
+		{"The Lord of the Rings", []string{"the", "ring"}, "<mark>The</mark> Lord of <mark>the</mark> <mark>Rings</mark>"},
+	}
+	for _, tt := range tests {
+		if got := HighlightTerms(tt.text, tt.terms); got != tt.want {
+			t.Errorf("HighlightTerms(%q, %q) = %q, want %q", tt.text, tt.terms, got, tt.want)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	bio := "one two three four five six seven eight nine ten"
+	tests := []struct {
+		name     string
+		terms    []string
+		maxWords int
+		want     string
+	}{
+		{"starts three words before the match", []string{"seven"}, 5, "four five six <mark>seven</mark> eight"},
+		{"match near the start", []string{"two"}, 3, "one <mark>two</mark> three"},
+		{"no match is the start", []string{"zebra"}, 3, "one two three"},
+		{"stops at the end", []string{"ten"}, 10, "seven eight nine <mark>ten</mark>"},
+	}
+	for _, tt := range tests {
+		if got := Snippet(bio, tt.terms, tt.maxWords); got != tt.want {
+			t.Errorf("%s: Snippet(%q, %d) = %q, want %q", tt.name, tt.terms, tt.maxWords, got, tt.want)
+		}
+	}
+	if got := Snippet("", []string{"a"}, 5); got != "" {
+		t.Errorf("Snippet of empty text = %q, want empty", got)
+	}
+}
+
+func TestParseTextQuery(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantField string
+		limit     int
+		offset    int
+	}{
+		{"q=tolk", "", 50, 0},
+		{"q=tolk&limit=5&offset=10", "", 5, 10},
+		{"q=", "q", 0, 0},
+		{"q=...", "q", 0, 0},
+		{"q=tolk&limit=0", "limit", 0, 0},
+		{"q=tolk&limit=501", "limit", 0, 0},
+		{"q=tolk&offset=-1", "offset", 0, 0},
+	}
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		q, errResp := ParseTextQuery(values)
+		if tt.wantField != "" {
+			if errResp == nil || len(errResp.Fields) != 1 || errResp.Fields[0].Field != tt.wantField {
+				t.Errorf("ParseTextQuery(%q) = %+v, want an error on %s", tt.query, errResp, tt.wantField)
+			}
+			continue
+		}
+		if errResp != nil {
+			t.Errorf("ParseTextQuery(%q) failed: %s", tt.query, errResp.Message)
+			continue
+		}
+		if q.Limit != tt.limit || q.Offset != tt.offset {
+			t.Errorf("ParseTextQuery(%q) = limit %d offset %d, want %d %d", tt.query, q.Limit, q.Offset, tt.limit, tt.offset)
+		}
+	}
+}
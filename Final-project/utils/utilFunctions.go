@@ -37,24 +37,55 @@ func ContainsAnyString(slice []string, target []string) bool {
     return false
 }
 func MatchAuthorCriteria(author data.Author, criteria data.AuthorSearchCriteria) bool {
+    _, ok := AuthorScore(author, criteria)
+    return ok
+}
+
+// AuthorScore reports whether author matches criteria and, for fuzzy criteria,
+// how closely: the sum of the best similarity found for each name field given.
+func AuthorScore(author data.Author, criteria data.AuthorSearchCriteria) (float64, bool) {
     if len(criteria.IDs) > 0 && !ContainsInt(criteria.IDs, author.ID) {
-        return false
+        return 0, false
+    }
+    if !MatchFilter(criteria.Filter, author) {
+        return 0, false
+    }
+    if criteria.Fuzzy {
+        score := 0.0
+        for _, field := range []struct {
+            terms []string
+            texts []string
+        }{
+            {criteria.FirstNames, []string{author.FirstName}},
+            {criteria.LastNames, []string{author.LastName}},
+            {criteria.Keywords, []string{author.FirstName, author.LastName, author.Bio}},
+        } {
+            if len(field.terms) == 0 {
+                continue
+            }
+            similarity, ok := FuzzyScore(field.terms, field.texts...)
+            if !ok {
+                return 0, false
+            }
+            score += similarity
+        }
+        return score, true
     }
     if len(criteria.FirstNames) > 0 && !ContainsString(criteria.FirstNames, author.FirstName) {
-        return false
+        return 0, false
     }
     if len(criteria.LastNames) > 0 && !ContainsString(criteria.LastNames, author.LastName) {
-        return false
+        return 0, false
     }
     if len(criteria.Keywords) > 0 {
         for _, keyword := range criteria.Keywords {
             if ContainsIgnoreCase(author.FirstName, keyword) ||
                 ContainsIgnoreCase(author.LastName, keyword) ||
                 ContainsIgnoreCase(author.Bio, keyword) {
-                return true
+                return 0, true
             }
         }
-        return false
+        return 0, false
     }
-    return true
+    return 0, true
 }
\ No newline at end of file